@@ -0,0 +1,176 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/alerts"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/data"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/validator"
+	"github.com/google/uuid"
+)
+
+// alertInput is the request body for creating and replacing alerts. An alert
+// emails the user new posts in their followed feeds with the phrase in their
+// title or description.
+type alertInput struct {
+	Phrase  string `json:"phrase" validate:"required,max=200"`
+	Enabled *bool  `json:"enabled"`
+}
+
+// readAlertInput decodes and validates an alert, sending the error response
+// itself when the alert is invalid.
+func (app *application) readAlertInput(w http.ResponseWriter, r *http.Request) (alertInput, bool) {
+	var input alertInput
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return input, false
+	}
+
+	input.Phrase = alerts.NormalizePhrase(input.Phrase)
+
+	v := validator.New()
+	if v.ValidateStruct(input); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return input, false
+	}
+
+	if input.Enabled == nil {
+		enabled := true
+		input.Enabled = &enabled
+	}
+
+	return input, true
+}
+
+func (app *application) HandlerAlertsCreate(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	input, ok := app.readAlertInput(w, r)
+	if !ok {
+		return
+	}
+
+	alert, err := app.db.CreateAlert(r.Context(), database.CreateAlertParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		UserID:    user.ID,
+		Phrase:    input.Phrase,
+		Enabled:   *input.Enabled,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"Alert": data.DatabaseAlertToAlert(alert)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) HandlerAlertsGet(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	userAlerts, err := app.db.GetAlerts(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"Alerts": data.DatabaseAlertsToAlerts(userAlerts)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) HandlerAlertsUpdate(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	alertID, err := app.readIDParam(r, "alertID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	input, ok := app.readAlertInput(w, r)
+	if !ok {
+		return
+	}
+
+	alert, err := app.db.UpdateAlert(r.Context(), database.UpdateAlertParams{
+		ID:        alertID,
+		UserID:    user.ID,
+		Phrase:    input.Phrase,
+		Enabled:   *input.Enabled,
+		UpdatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"Alert": data.DatabaseAlertToAlert(alert)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) HandlerAlertsDelete(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	alertID, err := app.readIDParam(r, "alertID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	deleted, err := app.db.DeleteAlert(r.Context(), database.DeleteAlertParams{
+		ID:     alertID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if deleted == 0 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "alert deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/data"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/digests"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/validator"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// digestInput is the request body for creating and replacing digests. A
+// digest is sent at hour o'clock in its timezone, every day or on weekday
+// each week, and lists unread posts from its feeds and the feeds in its
+// folders, or from every followed feed when it has neither.
+type digestInput struct {
+	Schedule  string      `json:"schedule" validate:"required"`
+	Hour      *int        `json:"hour"`
+	Weekday   string      `json:"weekday"`
+	Timezone  string      `json:"timezone"`
+	FeedIDs   []uuid.UUID `json:"feed_ids" validate:"max=100"`
+	FolderIDs []uuid.UUID `json:"folder_ids" validate:"max=100"`
+	Enabled   *bool       `json:"enabled"`
+
+	weekday  time.Weekday
+	location *time.Location
+}
+
+// readDigestInput decodes and validates a digest, sending the error response
+// itself when the digest is invalid.
+func (app *application) readDigestInput(w http.ResponseWriter, r *http.Request, userID uuid.UUID) (digestInput, bool) {
+	var input digestInput
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return input, false
+	}
+
+	if input.Hour == nil {
+		hour := 8
+		input.Hour = &hour
+	}
+	if input.Weekday == "" {
+		input.Weekday = "monday"
+	}
+	if input.Timezone == "" {
+		input.Timezone = "UTC"
+	}
+	if input.FeedIDs == nil {
+		input.FeedIDs = []uuid.UUID{}
+	}
+	if input.FolderIDs == nil {
+		input.FolderIDs = []uuid.UUID{}
+	}
+
+	v := validator.New()
+	v.ValidateStruct(input)
+	v.Check(validator.PermittedValue(input.Schedule, digests.Schedules...), "Schedule", "must be either daily or weekly")
+	v.Check(*input.Hour >= 0 && *input.Hour <= 23, "Hour", "must be between 0 and 23")
+
+	weekday, ok := parseWeekday(input.Weekday)
+	v.Check(ok, "Weekday", "must be a day of the week, such as monday")
+	input.weekday = weekday
+
+	input.location, err = time.LoadLocation(input.Timezone)
+	v.Check(err == nil && input.Timezone != "Local", "Timezone", "must be an IANA timezone, such as Europe/Rome")
+
+	if v.Valid() {
+		for _, folderID := range input.FolderIDs {
+			_, err = app.db.GetFolder(r.Context(), database.GetFolderParams{
+				ID:     folderID,
+				UserID: userID,
+			})
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				v.AddError("FolderIDs", "folder not found")
+			case err != nil:
+				app.serverErrorResponse(w, r, err)
+				return input, false
+			}
+		}
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return input, false
+	}
+
+	if input.Enabled == nil {
+		enabled := true
+		input.Enabled = &enabled
+	}
+
+	return input, true
+}
+
+func parseWeekday(name string) (time.Weekday, bool) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(name, day.String()) {
+			return day, true
+		}
+	}
+	return time.Sunday, false
+}
+
+// nextSendAt returns when a digest with the input's schedule is next due.
+func (input digestInput) nextSendAt() time.Time {
+	return digests.Next(input.Schedule, *input.Hour, input.weekday, input.location, time.Now().UTC())
+}
+
+func (app *application) HandlerDigestsCreate(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	input, ok := app.readDigestInput(w, r, user.ID)
+	if !ok {
+		return
+	}
+
+	token, err := digests.NewUnsubscribeToken()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	digest, err := app.db.CreateDigest(r.Context(), database.CreateDigestParams{
+		ID:               uuid.New(),
+		CreatedAt:        time.Now().UTC(),
+		UpdatedAt:        time.Now().UTC(),
+		UserID:           user.ID,
+		Schedule:         input.Schedule,
+		Hour:             int32(*input.Hour),   //#nosec G115
+		Weekday:          int32(input.weekday), //#nosec G115
+		Timezone:         input.Timezone,
+		FeedIds:          input.FeedIDs,
+		FolderIds:        input.FolderIDs,
+		Enabled:          *input.Enabled,
+		UnsubscribeToken: token,
+		NextSendAt:       input.nextSendAt(),
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"Digest": data.DatabaseDigestToDigest(digest)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) HandlerDigestsGet(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	userDigests, err := app.db.GetDigests(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"Digests": data.DatabaseDigestsToDigests(userDigests)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) HandlerDigestsUpdate(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	digestID, err := app.readIDParam(r, "digestID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	input, ok := app.readDigestInput(w, r, user.ID)
+	if !ok {
+		return
+	}
+
+	digest, err := app.db.UpdateDigest(r.Context(), database.UpdateDigestParams{
+		ID:         digestID,
+		UserID:     user.ID,
+		Schedule:   input.Schedule,
+		Hour:       int32(*input.Hour),   //#nosec G115
+		Weekday:    int32(input.weekday), //#nosec G115
+		Timezone:   input.Timezone,
+		FeedIds:    input.FeedIDs,
+		FolderIds:  input.FolderIDs,
+		Enabled:    *input.Enabled,
+		NextSendAt: input.nextSendAt(),
+		UpdatedAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"Digest": data.DatabaseDigestToDigest(digest)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) HandlerDigestsDelete(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	digestID, err := app.readIDParam(r, "digestID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	deleted, err := app.db.DeleteDigest(r.Context(), database.DeleteDigestParams{
+		ID:     digestID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if deleted == 0 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "digest deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+// unsubscribePage is shown by the unsubscribe routes. Unsubscribe links are
+// opened in browsers rather than by API clients, so they get HTML.
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!doctype html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width">
+  <title>Unsubscribe</title>
+</head>
+<body>
+{{if .Unsubscribed}}
+  <p>You have been unsubscribed from your {{.Schedule}} digest.</p>
+{{else}}
+  <p>Stop receiving your {{.Schedule}} digest?</p>
+  <form method="post">
+    <button type="submit">Unsubscribe</button>
+  </form>
+{{end}}
+</body>
+</html>
+`))
+
+// writeUnsubscribePage responds with the unsubscribe page for a digest.
+func (app *application) writeUnsubscribePage(w http.ResponseWriter, r *http.Request, digest database.Digest, unsubscribed bool) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+
+	err := unsubscribePage.Execute(w, struct {
+		Schedule     string
+		Unsubscribed bool
+	}{digest.Schedule, unsubscribed})
+	if err != nil {
+		app.logError(r, err)
+	}
+}
+
+// HandlerUnsubscribeConfirm asks whether to disable the digest whose
+// unsubscribe link was opened. Opening the link changes nothing, as mail
+// scanners and link previews open links without anyone clicking them.
+func (app *application) HandlerUnsubscribeConfirm(w http.ResponseWriter, r *http.Request) {
+	token := httprouter.ParamsFromContext(r.Context()).ByName("token")
+
+	digest, err := app.db.GetDigestByUnsubscribeToken(r.Context(), token)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.writeUnsubscribePage(w, r, digest, !digest.Enabled)
+}
+
+// HandlerUnsubscribe disables the digest whose unsubscribe link was
+// confirmed, or was posted to by a mail client's one-click unsubscribe. The
+// token in the link is all that's needed, so it works without logging in.
+func (app *application) HandlerUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	token := httprouter.ParamsFromContext(r.Context()).ByName("token")
+
+	digest, err := app.db.GetDigestByUnsubscribeToken(r.Context(), token)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	unsubscribed, err := app.db.UnsubscribeDigest(r.Context(), database.UnsubscribeDigestParams{
+		UnsubscribeToken: token,
+		UpdatedAt:        time.Now().UTC(),
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if unsubscribed == 0 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	app.writeUnsubscribePage(w, r, digest, true)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/data"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/validator"
+)
+
+func (app *application) HandlerFeedFetchesGet(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.Filters
+	}
+
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	feedID, err := app.readIDParam(r, "feedID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	feed, err := app.db.GetFeedByID(r.Context(), feedID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	allowed, err := app.canManageFeed(r, user, feed)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !allowed {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+
+	input.Filters.Sort = app.readString(qs, "sort", "-started_at")
+	input.Filters.SortSafelist = []string{"started_at", "-started_at"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	fetches, err := app.db.GetFeedFetches(r.Context(), database.GetFeedFetchesParams{
+		FeedID: feed.ID,
+		Sort:   input.Filters.Sort,
+		Lim:    int32(input.Filters.Limit()),  //#nosec G115
+		Off:    int32(input.Filters.Offset()), //#nosec G115
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	totalRecords := 0
+	if len(fetches) > 0 {
+		totalRecords = int(fetches[0].Count)
+	}
+
+	metadata := data.CalculateMetadata(totalRecords, input.Filters.Page, input.Filters.PageSize)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{
+		"Metadata": metadata,
+		"Fetches":  data.DatabaseFeedFetchesToFeedFetches(fetches),
+	}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
@@ -1,11 +1,15 @@
 package main
 
 import (
+	"database/sql"
+	"errors"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/data"
 	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/validator"
 	"github.com/google/uuid"
 )
 
@@ -91,3 +95,158 @@ func (app *application) HandlerFeedFollowsGet(w http.ResponseWriter, r *http.Req
 		return
 	}
 }
+
+// HandlerFeedFollowCountsGet returns total and unread post counts for each
+// followed feed, folder and saved search. Clients poll it, so responses carry
+// an ETag and unchanged counts are answered with 304 Not Modified.
+func (app *application) HandlerFeedFollowCountsGet(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	counts, err := app.db.GetFeedFollowCounts(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	folderCounts, err := app.db.GetFolderCounts(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	searchCounts, err := app.db.GetSavedSearchCounts(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	response := envelope{
+		"Counts":        data.DatabaseFeedCountsToFeedCounts(counts),
+		"Folders":       data.DatabaseFolderCountsToFolderCounts(folderCounts),
+		"SavedSearches": data.DatabaseSavedSearchCountsToSavedSearchCounts(searchCounts),
+	}
+
+	etag, err := app.etag(response)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", etag)
+	headers.Set("Cache-Control", "private, no-cache")
+
+	if r.Header.Get("If-None-Match") == etag {
+		for key, value := range headers {
+			w.Header()[key] = value
+		}
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+// HandlerFeedFollowsUpdate changes a follow's display title, priority, and
+// whether it's muted or contributes to notifications. Only fields present in
+// the request are changed; an empty title restores the feed's own name.
+func (app *application) HandlerFeedFollowsUpdate(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	feedFollowID, err := app.readFeedFollowIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Title    *string `json:"title" validate:"omitempty,max=100"`
+		Priority *int32  `json:"priority" validate:"omitempty,min=-100,max=100"`
+		Muted    *bool   `json:"muted"`
+		Notify   *bool   `json:"notify"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.ValidateStruct(input)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	feedFollow, err := app.db.GetFeedFollowForUser(r.Context(), database.GetFeedFollowForUserParams{
+		ID:     feedFollowID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if input.Title != nil {
+		title := strings.TrimSpace(*input.Title)
+		feedFollow.Title = sql.NullString{String: title, Valid: title != ""}
+	}
+	if input.Priority != nil {
+		feedFollow.Priority = *input.Priority
+	}
+	if input.Muted != nil {
+		feedFollow.Muted = *input.Muted
+	}
+	if input.Notify != nil {
+		feedFollow.Notify = *input.Notify
+	}
+
+	feedFollow, err = app.db.UpdateFeedFollow(r.Context(), database.UpdateFeedFollowParams{
+		ID:        feedFollow.ID,
+		UserID:    user.ID,
+		Title:     feedFollow.Title,
+		Priority:  feedFollow.Priority,
+		Muted:     feedFollow.Muted,
+		Notify:    feedFollow.Notify,
+		UpdatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	folderIDs, err := app.db.GetFeedFollowFolderIDs(r.Context(), feedFollow.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	response := data.DatabaseFeedFollowToFeedFollow(feedFollow)
+	if folderIDs != nil {
+		response.FolderIDs = folderIDs
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"FeedFollow": response}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
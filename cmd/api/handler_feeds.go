@@ -1,11 +1,14 @@
 package main
 
 import (
+	"database/sql"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/data"
 	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/urlnorm"
 	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/validator"
 	"github.com/google/uuid"
 )
@@ -36,16 +39,29 @@ func (app *application) HandlerFeedsCreate(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	feedURL, err := urlnorm.Canonical(input.URL)
+	if err != nil {
+		v.AddError("URL", "must be an absolute http or https URL")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
 	feed, err := app.db.CreateFeed(r.Context(), database.CreateFeedParams{
 		ID:        uuid.New(),
 		CreatedAt: time.Now().UTC(),
 		UpdatedAt: time.Now().UTC(),
 		Name:      input.Name,
-		Url:       input.URL,
+		Url:       feedURL,
 		UserID:    user.ID,
 	})
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		switch {
+		case data.IsUniqueViolation(err):
+			v.AddError("URL", "a feed with this url already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
@@ -88,3 +104,81 @@ func (app *application) HandlerFeedsGet(w http.ResponseWriter, r *http.Request)
 		return
 	}
 }
+
+// HandlerFeedRetentionUpdate replaces a feed's retention override. A null
+// limit falls back to the server-wide default and 0 keeps posts forever.
+func (app *application) HandlerFeedRetentionUpdate(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	feedID, err := app.readIDParam(r, "feedID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		RetentionDays     *int32 `json:"retention_days" validate:"omitempty,min=0,max=36500"`
+		RetentionMaxPosts *int32 `json:"retention_max_posts" validate:"omitempty,min=0,max=1000000"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.ValidateStruct(input)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	feed, err := app.db.GetFeedByID(r.Context(), feedID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	allowed, err := app.canManageFeed(r, user, feed)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !allowed {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	params := database.UpdateFeedRetentionParams{
+		ID:        feed.ID,
+		UpdatedAt: time.Now().UTC(),
+	}
+	if input.RetentionDays != nil {
+		params.RetentionDays = sql.NullInt32{Int32: *input.RetentionDays, Valid: true}
+	}
+	if input.RetentionMaxPosts != nil {
+		params.RetentionMaxPosts = sql.NullInt32{Int32: *input.RetentionMaxPosts, Valid: true}
+	}
+
+	feed, err = app.db.UpdateFeedRetention(r.Context(), params)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"Feed": data.DatabaseFeedToFeed(feed)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
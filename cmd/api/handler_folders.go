@@ -0,0 +1,335 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/data"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/validator"
+	"github.com/google/uuid"
+)
+
+func (app *application) HandlerFoldersCreate(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Name string `json:"name" validate:"required,min=1,max=100"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.ValidateStruct(input)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	folder, err := app.db.CreateFolder(r.Context(), database.CreateFolderParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		UserID:    user.ID,
+		Name:      input.Name,
+	})
+	if err != nil {
+		switch {
+		case data.IsUniqueViolation(err):
+			v.AddError("Name", "a folder with this name already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"Folder": data.DatabaseFolderToFolder(folder)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) HandlerFoldersGet(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	folders, err := app.db.GetFolders(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"Folders": data.DatabaseFoldersToFolders(folders)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) HandlerFoldersUpdate(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	folderID, err := app.readIDParam(r, "folderID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Name string `json:"name" validate:"required,min=1,max=100"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.ValidateStruct(input)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	folder, err := app.db.UpdateFolder(r.Context(), database.UpdateFolderParams{
+		ID:        folderID,
+		UserID:    user.ID,
+		Name:      input.Name,
+		UpdatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			app.notFoundResponse(w, r)
+		case data.IsUniqueViolation(err):
+			v.AddError("Name", "a folder with this name already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"Folder": data.DatabaseFolderToFolder(folder)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) HandlerFoldersDelete(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	folderID, err := app.readIDParam(r, "folderID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	deleted, err := app.db.DeleteFolder(r.Context(), database.DeleteFolderParams{
+		ID:     folderID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if deleted == 0 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "folder deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+// HandlerFoldersReorder sets the order folders are listed in. The request
+// must list every one of the user's folders exactly once.
+func (app *application) HandlerFoldersReorder(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	var input struct {
+		FolderIDs []uuid.UUID `json:"folder_ids" validate:"required"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.ValidateStruct(input)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	folders, err := app.db.GetFolders(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	owned := make(map[uuid.UUID]bool, len(folders))
+	for _, folder := range folders {
+		owned[folder.ID] = true
+	}
+	seen := make(map[uuid.UUID]bool, len(input.FolderIDs))
+	for _, id := range input.FolderIDs {
+		v.Check(owned[id] && !seen[id], "FolderIDs", "must list each of your folders exactly once")
+		seen[id] = true
+	}
+	v.Check(len(seen) == len(owned), "FolderIDs", "must list each of your folders exactly once")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	_, err = app.db.ReorderFolders(r.Context(), database.ReorderFoldersParams{
+		UpdatedAt: time.Now().UTC(),
+		FolderIds: input.FolderIDs,
+		UserID:    user.ID,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	folders, err = app.db.GetFolders(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"Folders": data.DatabaseFoldersToFolders(folders)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+// HandlerFeedFollowFoldersUpdate replaces the folders a followed feed is
+// filed under.
+func (app *application) HandlerFeedFollowFoldersUpdate(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	feedFollowID, err := app.readFeedFollowIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		FolderIDs []uuid.UUID `json:"folder_ids" validate:"required"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.ValidateStruct(input)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	feedFollow, err := app.db.GetFeedFollowForUser(r.Context(), database.GetFeedFollowForUserParams{
+		ID:     feedFollowID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	folders, err := app.db.GetFolders(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	owned := make(map[uuid.UUID]bool, len(folders))
+	for _, folder := range folders {
+		owned[folder.ID] = true
+	}
+	seen := make(map[uuid.UUID]bool, len(input.FolderIDs))
+	for _, id := range input.FolderIDs {
+		v.Check(owned[id] && !seen[id], "FolderIDs", "must only contain your folders, each once")
+		seen[id] = true
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.db.SetFeedFollowFolders(r.Context(), database.SetFeedFollowFoldersParams{
+		FeedFollowID: feedFollow.ID,
+		FolderIds:    input.FolderIDs,
+		UserID:       user.ID,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	folderIDs, err := app.db.GetFeedFollowFolderIDs(r.Context(), feedFollow.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	response := data.DatabaseFeedFollowToFeedFollow(feedFollow)
+	if folderIDs != nil {
+		response.FolderIDs = folderIDs
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"FeedFollow": response}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
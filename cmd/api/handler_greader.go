@@ -0,0 +1,1175 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/data"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/greader"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/opml"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/urlnorm"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	// greaderTokenTTL is how long a Google Reader client stays logged in.
+	// Clients log in again with the saved password once it's rejected.
+	greaderTokenTTL = 30 * 24 * time.Hour
+
+	// greaderMaxItems is the most items a stream request returns at a time.
+	greaderMaxItems = 1000
+)
+
+// writeText sends a plain text response, as the Google Reader API answers
+// logins and edits.
+func (app *application) writeText(w http.ResponseWriter, status int, text string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write([]byte(text)) //#nosec G104
+}
+
+// HandlerGReaderLogin logs a Google Reader client in with the user's email
+// and password, and returns the token it authenticates later requests with.
+func (app *application) HandlerGReaderLogin(w http.ResponseWriter, r *http.Request) {
+	email := r.FormValue("Email")
+	password := r.FormValue("Passwd")
+
+	dbUser, err := app.db.GetUserByEmail(r.Context(), email)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			app.writeText(w, http.StatusUnauthorized, "Error=BadAuthentication\n")
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user := data.DatabaseUserToUser(dbUser)
+
+	match, err := user.Password.Matches(password)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !match {
+		app.writeText(w, http.StatusUnauthorized, "Error=BadAuthentication\n")
+		return
+	}
+
+	token, err := data.NewToken(r.Context(), dbUser.ID, greaderTokenTTL, data.ScopeGReader, app.db)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeText(w, http.StatusOK, fmt.Sprintf("SID=%[1]s\nLSID=%[1]s\nAuth=%[1]s\n", token.Plaintext))
+}
+
+// HandlerGReaderToken returns the token clients send back with their edits.
+// Every request is authenticated by its Authorization header instead, so the
+// token only has to stay the same.
+func (app *application) HandlerGReaderToken(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	app.writeText(w, http.StatusOK, strings.ReplaceAll(user.ID.String(), "-", ""))
+}
+
+func (app *application) HandlerGReaderUserInfo(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{
+		"userId":        user.ID,
+		"userName":      user.Name,
+		"userProfileId": user.ID,
+		"userEmail":     user.Email,
+	}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+// greaderFollows is what the API needs to know about the user's follows: the
+// title, URL and folders of each followed feed, and the folders by name.
+type greaderFollows struct {
+	feeds       []database.GetFollowedFeedTitlesRow
+	feedFolders map[uuid.UUID][]database.Folder
+	folders     []database.Folder
+}
+
+func (app *application) getGReaderFollows(r *http.Request, userID uuid.UUID) (greaderFollows, error) {
+	var follows greaderFollows
+
+	folders, err := app.db.GetFolders(r.Context(), userID)
+	if err != nil {
+		return follows, err
+	}
+
+	feedFollows, err := app.db.GetFeedFollows(r.Context(), userID)
+	if err != nil {
+		return follows, err
+	}
+
+	feeds, err := app.db.GetFollowedFeedTitles(r.Context(), userID)
+	if err != nil {
+		return follows, err
+	}
+
+	// The same feed may be followed more than once, but is only listed once.
+	seen := map[uuid.UUID]bool{}
+	feeds = slices.DeleteFunc(feeds, func(feed database.GetFollowedFeedTitlesRow) bool {
+		duplicate := seen[feed.ID]
+		seen[feed.ID] = true
+		return duplicate
+	})
+	sort.Slice(feeds, func(i, j int) bool {
+		return strings.ToLower(feeds[i].Title) < strings.ToLower(feeds[j].Title)
+	})
+
+	feedFolders := map[uuid.UUID][]database.Folder{}
+	for _, folder := range folders {
+		for _, follow := range feedFollows {
+			feedID := follow.FeedFollow.FeedID
+			if slices.Contains(follow.FolderIds, folder.ID) && !slices.ContainsFunc(feedFolders[feedID], func(f database.Folder) bool { return f.ID == folder.ID }) {
+				feedFolders[feedID] = append(feedFolders[feedID], folder)
+			}
+		}
+	}
+
+	follows.feeds = feeds
+	follows.feedFolders = feedFolders
+	follows.folders = folders
+	return follows, nil
+}
+
+// folder returns the user's folder named label.
+func (follows greaderFollows) folder(label string) (database.Folder, bool) {
+	for _, folder := range follows.folders {
+		if folder.Name == label {
+			return folder, true
+		}
+	}
+	return database.Folder{}, false
+}
+
+func (app *application) HandlerGReaderSubscriptionsGet(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	follows, err := app.getGReaderFollows(r, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	subscriptions := make([]greader.Subscription, len(follows.feeds))
+	for i, feed := range follows.feeds {
+		categories := []greader.Category{}
+		for _, folder := range follows.feedFolders[feed.ID] {
+			categories = append(categories, greader.Category{ID: greader.LabelStreamID(folder.Name), Label: folder.Name})
+		}
+
+		subscriptions[i] = greader.Subscription{
+			ID:         greader.FeedStreamID(feed.ID),
+			Title:      feed.Title,
+			Categories: categories,
+			URL:        feed.Url,
+			HTMLURL:    greader.SiteURL(feed.Url),
+		}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"subscriptions": subscriptions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) HandlerGReaderTagsGet(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	folders, err := app.db.GetFolders(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	tags := []greader.Tag{{ID: greader.StateStarred}}
+	for _, folder := range folders {
+		tags = append(tags, greader.Tag{ID: greader.LabelStreamID(folder.Name), Type: "folder"})
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"tags": tags}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+// readGReaderLabel reads a label's name from the form field key, reporting
+// a bad request when it isn't a label's stream ID.
+func (app *application) readGReaderLabel(w http.ResponseWriter, r *http.Request, key string) (string, bool) {
+	id := r.Form.Get(key)
+	if id == "" {
+		return "", true
+	}
+
+	stream, err := greader.ParseStream(id)
+	if err != nil || stream.Kind != greader.StreamLabel {
+		app.badRequestResponse(w, r, fmt.Errorf("%s must be a label", key))
+		return "", false
+	}
+	return stream.Label, true
+}
+
+// getGReaderFeed returns the feed a stream names, by its ID or URL. URLs that
+// can't be feeds are treated as missing.
+func (app *application) getGReaderFeed(r *http.Request, stream greader.Stream) (database.Feed, error) {
+	if stream.FeedID != uuid.Nil {
+		return app.db.GetFeedByID(r.Context(), stream.FeedID)
+	}
+
+	feedURL, err := urlnorm.Canonical(stream.URL)
+	if err != nil {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	return app.db.GetFeedByURL(r.Context(), feedURL)
+}
+
+// HandlerGReaderSubscriptionsEdit subscribes to, unsubscribes from or edits
+// the feeds in the s fields, as ac says. Subscribing creates feeds that don't
+// exist yet. Subscriptions are titled t, added to the label a and removed
+// from the label r.
+func (app *application) HandlerGReaderSubscriptionsEdit(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	action := r.Form.Get("ac")
+	title := truncate(strings.TrimSpace(r.Form.Get("t")), 100)
+
+	add, ok := app.readGReaderLabel(w, r, "a")
+	if !ok {
+		return
+	}
+	remove, ok := app.readGReaderLabel(w, r, "r")
+	if !ok {
+		return
+	}
+
+	if !slices.Contains([]string{"subscribe", "unsubscribe", "edit"}, action) {
+		app.badRequestResponse(w, r, errors.New("ac must be subscribe, unsubscribe or edit"))
+		return
+	}
+
+	userFolders, err := app.db.GetFolders(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	folders := make(map[string]uuid.UUID, len(userFolders))
+	for _, folder := range userFolders {
+		folders[folder.Name] = folder.ID
+	}
+
+	feedFollows, err := app.db.GetFeedFollows(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	follows := make(map[uuid.UUID]database.GetFeedFollowsRow, len(feedFollows))
+	for _, follow := range feedFollows {
+		follows[follow.FeedFollow.FeedID] = follow
+	}
+
+	for _, id := range r.Form["s"] {
+		stream, err := greader.ParseStream(id)
+		if err != nil || stream.Kind != greader.StreamFeed {
+			app.badRequestResponse(w, r, errors.New("s must be a feed"))
+			return
+		}
+
+		if action == "subscribe" {
+			feedURL := stream.URL
+			if stream.FeedID != uuid.Nil {
+				feed, err := app.db.GetFeedByID(r.Context(), stream.FeedID)
+				if err != nil {
+					switch {
+					case errors.Is(err, sql.ErrNoRows):
+						app.notFoundResponse(w, r)
+					default:
+						app.serverErrorResponse(w, r, err)
+					}
+					return
+				}
+				feedURL = feed.Url
+			}
+
+			result, err := app.importOPMLEntry(r, user.ID, opml.Entry{Title: title, URL: feedURL, Folder: add}, folders, follows)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+			if result.Status == opmlFailed {
+				app.badRequestResponse(w, r, errors.New(result.Error))
+				return
+			}
+			continue
+		}
+
+		feed, err := app.getGReaderFeed(r, stream)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		if action == "unsubscribe" {
+			deleted, err := app.db.DeleteFeedFollowsForUser(r.Context(), database.DeleteFeedFollowsForUserParams{
+				FeedID: feed.ID,
+				UserID: user.ID,
+			})
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+			if deleted == 0 {
+				app.notFoundResponse(w, r)
+				return
+			}
+			delete(follows, feed.ID)
+			continue
+		}
+
+		follow, ok := follows[feed.ID]
+		if !ok {
+			app.notFoundResponse(w, r)
+			return
+		}
+
+		if title != "" {
+			follow.FeedFollow, err = app.db.UpdateFeedFollow(r.Context(), database.UpdateFeedFollowParams{
+				ID:        follow.FeedFollow.ID,
+				UserID:    user.ID,
+				Title:     sql.NullString{String: title, Valid: true},
+				Priority:  follow.FeedFollow.Priority,
+				Muted:     follow.FeedFollow.Muted,
+				Notify:    follow.FeedFollow.Notify,
+				UpdatedAt: time.Now().UTC(),
+			})
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+		}
+
+		if add != "" {
+			err = app.addFeedFollowToFolder(r, user.ID, &follow, add, folders)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+		}
+
+		if folderID, ok := folders[remove]; ok && slices.Contains(follow.FolderIds, folderID) {
+			follow.FolderIds = slices.DeleteFunc(follow.FolderIds, func(id uuid.UUID) bool { return id == folderID })
+			err = app.db.SetFeedFollowFolders(r.Context(), database.SetFeedFollowFoldersParams{
+				FeedFollowID: follow.FeedFollow.ID,
+				FolderIds:    follow.FolderIds,
+				UserID:       user.ID,
+			})
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+		}
+
+		follows[feed.ID] = follow
+	}
+
+	app.writeText(w, http.StatusOK, "OK")
+}
+
+// HandlerGReaderQuickAdd subscribes to the feed at a URL, creating it if it
+// doesn't exist yet.
+func (app *application) HandlerGReaderQuickAdd(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	query := r.Form.Get("quickadd")
+	feedURL := strings.TrimPrefix(query, "feed/")
+
+	feedFollows, err := app.db.GetFeedFollows(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	follows := make(map[uuid.UUID]database.GetFeedFollowsRow, len(feedFollows))
+	for _, follow := range feedFollows {
+		follows[follow.FeedFollow.FeedID] = follow
+	}
+
+	result, err := app.importOPMLEntry(r, user.ID, opml.Entry{URL: feedURL}, nil, follows)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if result.Status == opmlFailed {
+		app.badRequestResponse(w, r, errors.New(result.Error))
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{
+		"numResults": 1,
+		"query":      query,
+		"streamId":   greader.FeedStreamID(*result.FeedID),
+	}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+// HandlerGReaderTagRename renames the folder labelled s to dest.
+func (app *application) HandlerGReaderTagRename(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	label, ok := app.readGReaderLabel(w, r, "s")
+	if !ok {
+		return
+	}
+	dest, ok := app.readGReaderLabel(w, r, "dest")
+	if !ok {
+		return
+	}
+	if label == "" || dest == "" || len([]rune(dest)) > 100 {
+		app.badRequestResponse(w, r, errors.New("s and dest must be labels of at most 100 characters"))
+		return
+	}
+
+	follows := greaderFollows{}
+	follows.folders, err = app.db.GetFolders(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	folder, ok := follows.folder(label)
+	if !ok {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	_, err = app.db.UpdateFolder(r.Context(), database.UpdateFolderParams{
+		ID:        folder.ID,
+		UserID:    user.ID,
+		Name:      dest,
+		UpdatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		switch {
+		case data.IsUniqueViolation(err):
+			app.badRequestResponse(w, r, errors.New("a folder with this name already exists"))
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.writeText(w, http.StatusOK, "OK")
+}
+
+// HandlerGReaderTagDisable deletes the folder labelled s. Its feeds stay
+// followed.
+func (app *application) HandlerGReaderTagDisable(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	label, ok := app.readGReaderLabel(w, r, "s")
+	if !ok {
+		return
+	}
+
+	follows := greaderFollows{}
+	follows.folders, err = app.db.GetFolders(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	folder, ok := follows.folder(label)
+	if !ok {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	_, err = app.db.DeleteFolder(r.Context(), database.DeleteFolderParams{
+		ID:     folder.ID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeText(w, http.StatusOK, "OK")
+}
+
+// greaderStreamInput is a page of a stream's posts, as asked for by the
+// request's n, r, c, xt, it and ot parameters.
+type greaderStreamInput struct {
+	id     string
+	sort   string
+	limit  int
+	params database.GetPostsForUserParams
+}
+
+// readGReaderStream reads which page of a stream's posts to list, newest
+// first unless r is "o". Only read posts can be excluded with xt, and only
+// read or starred posts included with it. ot is the oldest time, in seconds,
+// posts were collected at, and c a continuation from an earlier page.
+func (app *application) readGReaderStream(w http.ResponseWriter, r *http.Request, userID uuid.UUID, id string) (greaderStreamInput, bool) {
+	input := greaderStreamInput{
+		id:    id,
+		sort:  "-published_at",
+		limit: 20,
+		params: database.GetPostsForUserParams{
+			UserID:   userID,
+			FeedIds:  []uuid.UUID{},
+			Status:   "all",
+			Tags:     []string{},
+			SortKey:  "published_at",
+			SortDesc: true,
+		},
+	}
+
+	stream, err := greader.ParseStream(id)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return input, false
+	}
+
+	switch stream.Kind {
+	case greader.StreamRead:
+		input.params.Status = "read"
+	case greader.StreamStarred:
+		input.params.Starred = true
+	case greader.StreamFeed:
+		feed, err := app.getGReaderFeed(r, stream)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return input, false
+		}
+		input.params.FeedIds = []uuid.UUID{feed.ID}
+	case greader.StreamLabel:
+		follows := greaderFollows{}
+		follows.folders, err = app.db.GetFolders(r.Context(), userID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return input, false
+		}
+		folder, ok := follows.folder(stream.Label)
+		if !ok {
+			app.notFoundResponse(w, r)
+			return input, false
+		}
+		input.params.FolderID = folder.ID
+	}
+
+	for _, state := range r.Form["xt"] {
+		if greader.Normalize(state) == greader.StateRead {
+			input.params.Status = "unread"
+		}
+	}
+	for _, state := range r.Form["it"] {
+		switch greader.Normalize(state) {
+		case greader.StateRead:
+			input.params.Status = "read"
+		case greader.StateStarred:
+			input.params.Starred = true
+		}
+	}
+
+	if ot := r.Form.Get("ot"); ot != "" {
+		seconds, err := strconv.ParseInt(ot, 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, errors.New("ot must be a number of seconds"))
+			return input, false
+		}
+		input.params.CreatedAfter = sql.NullTime{Time: time.Unix(seconds, 0).UTC(), Valid: true}
+	}
+
+	if n := r.Form.Get("n"); n != "" {
+		input.limit, err = strconv.Atoi(n)
+		if err != nil || input.limit < 1 {
+			app.badRequestResponse(w, r, errors.New("n must be a positive number"))
+			return input, false
+		}
+		input.limit = min(input.limit, greaderMaxItems)
+	}
+
+	if r.Form.Get("r") == "o" {
+		input.sort = "published_at"
+		input.params.SortDesc = false
+	}
+
+	if c := r.Form.Get("c"); c != "" {
+		cursor, err := data.DecodeCursor(c)
+		if err != nil || cursor.Sort != input.sort || cursor.Time == nil {
+			app.badRequestResponse(w, r, errors.New("c must be a continuation from the same stream"))
+			return input, false
+		}
+		input.params.CursorID = cursor.ID
+		input.params.CursorTime = *cursor.Time
+	}
+
+	input.params.Lim = int32(input.limit + 1) //#nosec G115
+	return input, true
+}
+
+// getGReaderStream returns a page of a stream's posts and the continuation
+// for the next page, if there's one.
+func (app *application) getGReaderStream(r *http.Request, input greaderStreamInput) ([]database.GetPostsForUserRow, string, error) {
+	posts, err := app.db.GetPostsForUser(r.Context(), input.params)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(posts) <= input.limit {
+		return posts, "", nil
+	}
+
+	posts = posts[:input.limit]
+	return posts, data.PostCursor(input.sort, posts[len(posts)-1]).Encode(), nil
+}
+
+// HandlerGReaderItemIDs lists the IDs of the items in the stream s.
+func (app *application) HandlerGReaderItemIDs(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	input, ok := app.readGReaderStream(w, r, user.ID, r.Form.Get("s"))
+	if !ok {
+		return
+	}
+
+	posts, continuation, err := app.getGReaderStream(r, input)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	refs := make([]greader.ItemRef, len(posts))
+	for i, post := range posts {
+		refs[i] = greader.ItemRef{
+			ID:              greader.ShortItemID(post.ItemID),
+			DirectStreamIDs: []string{},
+			TimestampUsec:   greader.Usec(post.CreatedAt),
+		}
+	}
+
+	response := envelope{"itemRefs": refs}
+	if continuation != "" {
+		response["continuation"] = continuation
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+// HandlerGReaderStreamContents returns the items in the stream named in the
+// URL, or in s.
+func (app *application) HandlerGReaderStreamContents(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	id := strings.TrimPrefix(httprouter.ParamsFromContext(r.Context()).ByName("streamID"), "/")
+	if id == "" {
+		id = r.Form.Get("s")
+	}
+
+	input, ok := app.readGReaderStream(w, r, user.ID, id)
+	if !ok {
+		return
+	}
+
+	posts, continuation, err := app.getGReaderStream(r, input)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	itemIDs := make([]int64, len(posts))
+	for i, post := range posts {
+		itemIDs[i] = post.ItemID
+	}
+
+	items, err := app.getGReaderItems(r, user.ID, itemIDs)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	response := envelope{
+		"id":      id,
+		"updated": time.Now().Unix(),
+		"items":   items,
+	}
+	if continuation != "" {
+		response["continuation"] = continuation
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+// readGReaderItemIDs reads the item IDs in the i fields.
+func (app *application) readGReaderItemIDs(w http.ResponseWriter, r *http.Request) ([]int64, bool) {
+	ids := r.Form["i"]
+	if len(ids) == 0 || len(ids) > greaderMaxItems {
+		app.badRequestResponse(w, r, fmt.Errorf("i must list between 1 and %d items", greaderMaxItems))
+		return nil, false
+	}
+
+	itemIDs := make([]int64, len(ids))
+	for i, id := range ids {
+		itemID, err := greader.ParseItemID(id)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return nil, false
+		}
+		itemIDs[i] = itemID
+	}
+	return itemIDs, true
+}
+
+// HandlerGReaderItemContents returns the items in the i fields.
+func (app *application) HandlerGReaderItemContents(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	itemIDs, ok := app.readGReaderItemIDs(w, r)
+	if !ok {
+		return
+	}
+
+	items, err := app.getGReaderItems(r, user.ID, itemIDs)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{
+		"id":      greader.StateReadingList,
+		"updated": time.Now().Unix(),
+		"items":   items,
+	}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+// getGReaderItems returns the items with the given IDs in the same order,
+// leaving out those not in the user's followed feeds.
+func (app *application) getGReaderItems(r *http.Request, userID uuid.UUID, itemIDs []int64) ([]greader.Item, error) {
+	rows, err := app.db.GetGReaderItems(r.Context(), database.GetGReaderItemsParams{
+		UserID:  userID,
+		ItemIds: itemIDs,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	follows, err := app.getGReaderFollows(r, userID)
+	if err != nil {
+		return nil, err
+	}
+	feeds := make(map[uuid.UUID]database.GetFollowedFeedTitlesRow, len(follows.feeds))
+	for _, feed := range follows.feeds {
+		feeds[feed.ID] = feed
+	}
+
+	byItemID := make(map[int64]database.GetGReaderItemsRow, len(rows))
+	for _, row := range rows {
+		byItemID[row.Post.ItemID] = row
+	}
+
+	items := []greader.Item{}
+	for _, itemID := range itemIDs {
+		row, ok := byItemID[itemID]
+		if !ok {
+			continue
+		}
+		post := row.Post
+
+		published := post.CreatedAt
+		if post.PublishedAt.Valid {
+			published = post.PublishedAt.Time
+		}
+
+		content := post.Description.String
+		if post.Content.String != "" {
+			content = post.Content.String
+		}
+
+		categories := []string{greader.StateReadingList}
+		if row.ReadAt.Valid {
+			categories = append(categories, greader.StateRead)
+		}
+		if row.StarredAt.Valid {
+			categories = append(categories, greader.StateStarred)
+		}
+		for _, folder := range follows.feedFolders[post.FeedID] {
+			categories = append(categories, greader.LabelStreamID(folder.Name))
+		}
+
+		item := greader.Item{
+			ID:            greader.LongItemID(post.ItemID),
+			CrawlTimeMsec: greader.Msec(post.CreatedAt),
+			TimestampUsec: greader.Usec(post.CreatedAt),
+			Published:     published.Unix(),
+			Updated:       post.UpdatedAt.Unix(),
+			Title:         post.Title,
+			Canonical:     []greader.Link{{Href: post.Url}},
+			Alternate:     []greader.Link{{Href: post.Url, Type: "text/html"}},
+			Summary:       greader.Content{Direction: "ltr", Content: content},
+			Author:        post.Author.String,
+			Categories:    categories,
+			Origin: greader.Origin{
+				StreamID: greader.FeedStreamID(post.FeedID),
+				Title:    feeds[post.FeedID].Title,
+				HTMLURL:  greader.SiteURL(feeds[post.FeedID].Url),
+			},
+		}
+		if post.EnclosureUrl.Valid {
+			item.Enclosure = []greader.Link{{Href: post.EnclosureUrl.String, Type: post.EnclosureType.String}}
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// HandlerGReaderEditTag adds the read and starred states in the a fields to
+// the items in the i fields, and removes those in the r fields. Marking an
+// item kept unread marks it unread. Other tags are ignored.
+func (app *application) HandlerGReaderEditTag(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	itemIDs, ok := app.readGReaderItemIDs(w, r)
+	if !ok {
+		return
+	}
+
+	postIDs, err := app.db.GetGReaderPostIDs(r.Context(), itemIDs)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var read, starred *bool
+	for _, tag := range r.Form["a"] {
+		switch greader.Normalize(tag) {
+		case greader.StateRead:
+			read = &[]bool{true}[0]
+		case greader.StateKeptUnread:
+			read = &[]bool{false}[0]
+		case greader.StateStarred:
+			starred = &[]bool{true}[0]
+		}
+	}
+	for _, tag := range r.Form["r"] {
+		switch greader.Normalize(tag) {
+		case greader.StateRead:
+			read = &[]bool{false}[0]
+		case greader.StateStarred:
+			starred = &[]bool{false}[0]
+		}
+	}
+
+	now := time.Now().UTC()
+
+	if read != nil {
+		params := database.SetPostsReadStateParams{
+			UserID:    user.ID,
+			UpdatedAt: now,
+			PostIds:   postIDs,
+		}
+		if *read {
+			params.ReadAt = sql.NullTime{Time: now, Valid: true}
+		}
+
+		_, err = app.db.SetPostsReadState(r.Context(), params)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	if starred != nil {
+		for _, postID := range postIDs {
+			params := database.SetPostStarredParams{
+				UserID:    user.ID,
+				UpdatedAt: now,
+				PostID:    postID,
+			}
+			if *starred {
+				params.StarredAt = sql.NullTime{Time: now, Valid: true}
+			}
+
+			_, err = app.db.SetPostStarred(r.Context(), params)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+		}
+	}
+
+	app.writeText(w, http.StatusOK, "OK")
+}
+
+// HandlerGReaderMarkAllRead marks the posts in the stream s collected up to
+// ts, in microseconds, as read. Only the reading list, feeds and labels can
+// be marked read.
+func (app *application) HandlerGReaderMarkAllRead(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	stream, err := greader.ParseStream(r.Form.Get("s"))
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	now := time.Now().UTC()
+	before := now
+	if ts := r.Form.Get("ts"); ts != "" {
+		usec, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, errors.New("ts must be a number of microseconds"))
+			return
+		}
+		before = time.UnixMicro(usec).UTC()
+	}
+
+	var feedIDs []uuid.UUID
+	switch stream.Kind {
+	case greader.StreamReadingList:
+		feedIDs = []uuid.UUID{uuid.Nil}
+	case greader.StreamFeed:
+		feed, err := app.getGReaderFeed(r, stream)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+		feedIDs = []uuid.UUID{feed.ID}
+	case greader.StreamLabel:
+		follows, err := app.getGReaderFollows(r, user.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		folder, ok := follows.folder(stream.Label)
+		if !ok {
+			app.notFoundResponse(w, r)
+			return
+		}
+		for feedID, folders := range follows.feedFolders {
+			if slices.Contains(folders, folder) {
+				feedIDs = append(feedIDs, feedID)
+			}
+		}
+	default:
+		app.badRequestResponse(w, r, errors.New("s must be the reading list, a feed or a label"))
+		return
+	}
+
+	for _, feedID := range feedIDs {
+		_, err = app.db.MarkAllPostsRead(r.Context(), database.MarkAllPostsReadParams{
+			UserID: user.ID,
+			ReadAt: now,
+			Before: before,
+			FeedID: feedID,
+		})
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	app.writeText(w, http.StatusOK, "OK")
+}
+
+// HandlerGReaderUnreadCounts returns the number of unread posts in each
+// followed feed and label, and in the reading list.
+func (app *application) HandlerGReaderUnreadCounts(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	counts, err := app.db.GetFeedFollowCounts(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	folderCounts, err := app.db.GetFolderCounts(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	folders, err := app.db.GetFolders(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	labels := make(map[uuid.UUID]string, len(folders))
+	for _, folder := range folders {
+		labels[folder.ID] = folder.Name
+	}
+
+	var total int64
+	unreadCounts := []greader.UnreadCount{}
+	for _, count := range counts {
+		total += count.Unread
+		unreadCounts = append(unreadCounts, greader.UnreadCount{ID: greader.FeedStreamID(count.FeedID), Count: count.Unread})
+	}
+	for _, count := range folderCounts {
+		unreadCounts = append(unreadCounts, greader.UnreadCount{ID: greader.LabelStreamID(labels[count.FolderID]), Count: count.Unread})
+	}
+	unreadCounts = append(unreadCounts, greader.UnreadCount{ID: greader.StateReadingList, Count: total})
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"max": total, "unreadcounts": unreadCounts}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
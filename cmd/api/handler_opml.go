@@ -0,0 +1,351 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/opml"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/urlnorm"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/validator"
+	"github.com/google/uuid"
+)
+
+// opmlMaxBytes is the largest OPML file that can be imported.
+const opmlMaxBytes = 5 * 1_048_576
+
+// opmlMaxEntries is the most feeds an OPML file can list.
+const opmlMaxEntries = 1000
+
+// Statuses of the entries of an OPML import.
+const (
+	opmlCreated   = "created"
+	opmlFollowed  = "followed"
+	opmlFollowing = "already_following"
+	opmlFailed    = "failed"
+)
+
+type opmlImportResult struct {
+	Title  string     `json:"title"`
+	URL    string     `json:"url"`
+	Folder string     `json:"folder,omitempty"`
+	Status string     `json:"status"`
+	FeedID *uuid.UUID `json:"feed_id,omitempty"`
+	Error  string     `json:"error,omitempty"`
+}
+
+// truncate shortens s to at most n characters.
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// readOPML returns the body of an import, sent either as the request body or
+// as the "file" field of a multipart form.
+func (app *application) readOPML(w http.ResponseWriter, r *http.Request) (io.Reader, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, opmlMaxBytes)
+
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		return r.Body, nil
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			return nil, fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit)
+		}
+		return nil, errors.New(`body must have an OPML file in its "file" field`)
+	}
+	return file, nil
+}
+
+// HandlerOPMLImport follows every feed in an OPML file, creating the feeds
+// that don't exist yet and putting each in the folder it's listed under. Feeds
+// already followed keep their settings but are added to the listed folders.
+// Entries are imported one by one, and the result of each is reported: one
+// that fails, even on a database error, doesn't stop the rest, and as feeds,
+// follows and folders are matched by name and URL, importing the file again
+// finishes what was left.
+func (app *application) HandlerOPMLImport(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	body, err := app.readOPML(w, r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	entries, err := opml.Parse(body)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			err = fmt.Errorf("body must not be larger than %d bytes", maxBytesError.Limit)
+		}
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(len(entries) > 0, "OPML", "must list at least one feed")
+	v.Check(len(entries) <= opmlMaxEntries, "OPML", fmt.Sprintf("must not list more than %d feeds", opmlMaxEntries))
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	userFolders, err := app.db.GetFolders(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	folders := make(map[string]uuid.UUID, len(userFolders))
+	for _, folder := range userFolders {
+		folders[folder.Name] = folder.ID
+	}
+
+	feedFollows, err := app.db.GetFeedFollows(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	follows := make(map[uuid.UUID]database.GetFeedFollowsRow, len(feedFollows))
+	for _, follow := range feedFollows {
+		follows[follow.FeedFollow.FeedID] = follow
+	}
+
+	results := make([]opmlImportResult, len(entries))
+	for i, entry := range entries {
+		results[i], err = app.importOPMLEntry(r, user.ID, entry, folders, follows)
+		if err != nil {
+			app.logError(r, err)
+			results[i].Status = opmlFailed
+			results[i].Error = "could not be imported, try again later"
+		}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"Results": results}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+// importOPMLEntry follows the feed of one OPML entry, recording new folders
+// and follows in folders and follows so later entries see them, even if a
+// later step fails. Problems with the entry are reported in its result, and
+// only database errors returned.
+func (app *application) importOPMLEntry(r *http.Request, userID uuid.UUID, entry opml.Entry, folders map[string]uuid.UUID, follows map[uuid.UUID]database.GetFeedFollowsRow) (opmlImportResult, error) {
+	result := opmlImportResult{
+		Title:  entry.Title,
+		URL:    entry.URL,
+		Folder: entry.Folder,
+	}
+
+	feedURL, err := urlnorm.Canonical(entry.URL)
+	if err != nil {
+		result.Status = opmlFailed
+		result.Error = "url must be an absolute http or https url"
+		return result, nil
+	}
+	if utf8.RuneCountInString(entry.Folder) > 100 {
+		result.Status = opmlFailed
+		result.Error = "folder name must not be more than 100 characters"
+		return result, nil
+	}
+
+	title := truncate(entry.Title, 100)
+
+	result.Status = opmlFollowed
+	feed, err := app.db.GetFeedByURL(r.Context(), feedURL)
+	if errors.Is(err, sql.ErrNoRows) {
+		name := title
+		if utf8.RuneCountInString(name) < 2 {
+			name = truncate(feedURL, 100)
+		}
+
+		result.Status = opmlCreated
+		feed, err = app.db.CreateFeed(r.Context(), database.CreateFeedParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+			UpdatedAt: time.Now().UTC(),
+			Name:      name,
+			Url:       feedURL,
+			UserID:    userID,
+		})
+	}
+	if err != nil {
+		return result, err
+	}
+	result.FeedID = &feed.ID
+
+	follow, ok := follows[feed.ID]
+	if ok {
+		result.Status = opmlFollowing
+	} else {
+		follow.FeedFollow, err = app.db.CreateFeedFollow(r.Context(), database.CreateFeedFollowParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+			UpdatedAt: time.Now().UTC(),
+			UserID:    userID,
+			FeedID:    feed.ID,
+		})
+		if err != nil {
+			return result, err
+		}
+		follows[feed.ID] = follow
+
+		// Names given to existing feeds by other readers are kept as the
+		// follow's custom title.
+		if title != "" && title != feed.Name {
+			follow.FeedFollow, err = app.db.UpdateFeedFollow(r.Context(), database.UpdateFeedFollowParams{
+				ID:        follow.FeedFollow.ID,
+				UserID:    userID,
+				Title:     sql.NullString{String: title, Valid: true},
+				Priority:  follow.FeedFollow.Priority,
+				Muted:     follow.FeedFollow.Muted,
+				Notify:    follow.FeedFollow.Notify,
+				UpdatedAt: time.Now().UTC(),
+			})
+			if err != nil {
+				return result, err
+			}
+			follows[feed.ID] = follow
+		}
+	}
+
+	if entry.Folder != "" {
+		err = app.addFeedFollowToFolder(r, userID, &follow, entry.Folder, folders)
+		if err != nil {
+			return result, err
+		}
+		follows[feed.ID] = follow
+	}
+
+	return result, nil
+}
+
+// addFeedFollowToFolder puts a follow in the user's folder with the given
+// name, creating the folder if there's none yet and recording it in folders.
+func (app *application) addFeedFollowToFolder(r *http.Request, userID uuid.UUID, follow *database.GetFeedFollowsRow, name string, folders map[string]uuid.UUID) error {
+	folderID, ok := folders[name]
+	if !ok {
+		folder, err := app.db.CreateFolder(r.Context(), database.CreateFolderParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+			UpdatedAt: time.Now().UTC(),
+			UserID:    userID,
+			Name:      name,
+		})
+		if err != nil {
+			return err
+		}
+		folderID = folder.ID
+		folders[name] = folderID
+	}
+
+	if slices.Contains(follow.FolderIds, folderID) {
+		return nil
+	}
+
+	folderIDs := append(slices.Clone(follow.FolderIds), folderID)
+	err := app.db.SetFeedFollowFolders(r.Context(), database.SetFeedFollowFoldersParams{
+		FeedFollowID: follow.FeedFollow.ID,
+		FolderIds:    folderIDs,
+		UserID:       userID,
+	})
+	if err != nil {
+		return err
+	}
+	follow.FolderIds = folderIDs
+	return nil
+}
+
+// HandlerOPMLExport returns the user's follows as an OPML file, under their
+// custom titles. Feeds are listed in each of their folders, in the folders'
+// order, followed by the feeds that aren't in any folder.
+func (app *application) HandlerOPMLExport(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	folders, err := app.db.GetFolders(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	feedFollows, err := app.db.GetFeedFollows(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	feeds, err := app.db.GetFollowedFeedTitles(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	// The same feed may be followed more than once, but is only listed once.
+	seen := map[uuid.UUID]bool{}
+	feeds = slices.DeleteFunc(feeds, func(feed database.GetFollowedFeedTitlesRow) bool {
+		duplicate := seen[feed.ID]
+		seen[feed.ID] = true
+		return duplicate
+	})
+	sort.Slice(feeds, func(i, j int) bool {
+		return strings.ToLower(feeds[i].Title) < strings.ToLower(feeds[j].Title)
+	})
+
+	inFolder := map[uuid.UUID]map[uuid.UUID]bool{}
+	for _, follow := range feedFollows {
+		for _, folderID := range follow.FolderIds {
+			if inFolder[folderID] == nil {
+				inFolder[folderID] = map[uuid.UUID]bool{}
+			}
+			inFolder[folderID][follow.FeedFollow.FeedID] = true
+		}
+	}
+
+	var entries []opml.Entry
+	inAnyFolder := map[uuid.UUID]bool{}
+	for _, folder := range folders {
+		for _, feed := range feeds {
+			if inFolder[folder.ID][feed.ID] {
+				entries = append(entries, opml.Entry{Title: feed.Title, URL: feed.Url, Folder: folder.Name})
+				inAnyFolder[feed.ID] = true
+			}
+		}
+	}
+	for _, feed := range feeds {
+		if !inAnyFolder[feed.ID] {
+			entries = append(entries, opml.Entry{Title: feed.Title, URL: feed.Url})
+		}
+	}
+
+	body, err := opml.Render(fmt.Sprintf("%s's subscriptions", user.Name), time.Now(), entries)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/x-opml; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="subscriptions.opml"`)
+	w.Write(body)
+}
@@ -0,0 +1,439 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/data"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/syndication"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/validator"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// outputFeedSize is how many of the newest posts an output feed lists.
+const outputFeedSize = 50
+
+// outputFeedInput is the request body for creating and replacing output
+// feeds. Folder, tag and saved search feeds name the folder, tag or saved
+// search their posts come from.
+type outputFeedInput struct {
+	Name          string     `json:"name" validate:"required,max=200"`
+	Source        string     `json:"source" validate:"required"`
+	FolderID      *uuid.UUID `json:"folder_id"`
+	Tag           string     `json:"tag"`
+	SavedSearchID *uuid.UUID `json:"saved_search_id"`
+}
+
+// readOutputFeedInput decodes and validates an output feed, sending the error
+// response itself when the feed is invalid.
+func (app *application) readOutputFeedInput(w http.ResponseWriter, r *http.Request, userID uuid.UUID) (outputFeedInput, bool) {
+	var input outputFeedInput
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return input, false
+	}
+
+	// Only the field for the feed's source is kept.
+	if input.Source != data.OutputSourceFolder {
+		input.FolderID = nil
+	}
+	if input.Source != data.OutputSourceSavedSearch {
+		input.SavedSearchID = nil
+	}
+	tags := data.NormalizeTags([]string{input.Tag})
+	input.Tag = ""
+	if input.Source == data.OutputSourceTag && len(tags) > 0 {
+		input.Tag = tags[0]
+	}
+
+	v := validator.New()
+	v.ValidateStruct(input)
+	v.Check(validator.PermittedValue(input.Source, data.OutputSources...), "Source", "must be one of all, folder, starred, tag or saved_search")
+
+	switch input.Source {
+	case data.OutputSourceFolder:
+		v.Check(input.FolderID != nil, "FolderID", "must be provided for folder feeds")
+	case data.OutputSourceTag:
+		v.Check(input.Tag != "", "Tag", "must be provided for tag feeds")
+		data.ValidateTags(v, "Tag", []string{input.Tag})
+	case data.OutputSourceSavedSearch:
+		v.Check(input.SavedSearchID != nil, "SavedSearchID", "must be provided for saved search feeds")
+	}
+
+	if input.FolderID != nil && v.Valid() {
+		_, err = app.db.GetFolder(r.Context(), database.GetFolderParams{
+			ID:     *input.FolderID,
+			UserID: userID,
+		})
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			v.AddError("FolderID", "folder not found")
+		case err != nil:
+			app.serverErrorResponse(w, r, err)
+			return input, false
+		}
+	}
+
+	if input.SavedSearchID != nil && v.Valid() {
+		_, err = app.db.GetSavedSearch(r.Context(), database.GetSavedSearchParams{
+			ID:     *input.SavedSearchID,
+			UserID: userID,
+		})
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			v.AddError("SavedSearchID", "saved search not found")
+		case err != nil:
+			app.serverErrorResponse(w, r, err)
+			return input, false
+		}
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return input, false
+	}
+
+	return input, true
+}
+
+func (input outputFeedInput) folderID() uuid.NullUUID {
+	if input.FolderID == nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: *input.FolderID, Valid: true}
+}
+
+func (input outputFeedInput) tag() sql.NullString {
+	return sql.NullString{String: input.Tag, Valid: input.Tag != ""}
+}
+
+func (input outputFeedInput) savedSearchID() uuid.NullUUID {
+	if input.SavedSearchID == nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: *input.SavedSearchID, Valid: true}
+}
+
+func (app *application) HandlerOutputFeedsCreate(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	input, ok := app.readOutputFeedInput(w, r, user.ID)
+	if !ok {
+		return
+	}
+
+	token, err := data.NewOutputFeedToken()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	feed, err := app.db.CreateOutputFeed(r.Context(), database.CreateOutputFeedParams{
+		ID:            uuid.New(),
+		CreatedAt:     time.Now().UTC(),
+		UpdatedAt:     time.Now().UTC(),
+		UserID:        user.ID,
+		Name:          input.Name,
+		Source:        input.Source,
+		FolderID:      input.folderID(),
+		Tag:           input.tag(),
+		SavedSearchID: input.savedSearchID(),
+		Token:         token,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"OutputFeed": data.DatabaseOutputFeedToOutputFeed(feed, app.config.baseURL)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) HandlerOutputFeedsGet(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	feeds, err := app.db.GetOutputFeeds(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"OutputFeeds": data.DatabaseOutputFeedsToOutputFeeds(feeds, app.config.baseURL)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) HandlerOutputFeedsUpdate(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	outputFeedID, err := app.readIDParam(r, "outputFeedID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	input, ok := app.readOutputFeedInput(w, r, user.ID)
+	if !ok {
+		return
+	}
+
+	feed, err := app.db.UpdateOutputFeed(r.Context(), database.UpdateOutputFeedParams{
+		ID:            outputFeedID,
+		UserID:        user.ID,
+		Name:          input.Name,
+		Source:        input.Source,
+		FolderID:      input.folderID(),
+		Tag:           input.tag(),
+		SavedSearchID: input.savedSearchID(),
+		UpdatedAt:     time.Now().UTC(),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"OutputFeed": data.DatabaseOutputFeedToOutputFeed(feed, app.config.baseURL)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+// HandlerOutputFeedTokenRotate revokes an output feed's URLs by giving it a
+// new token, and responds with the new URLs.
+func (app *application) HandlerOutputFeedTokenRotate(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	outputFeedID, err := app.readIDParam(r, "outputFeedID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	token, err := data.NewOutputFeedToken()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	feed, err := app.db.RotateOutputFeedToken(r.Context(), database.RotateOutputFeedTokenParams{
+		ID:        outputFeedID,
+		UserID:    user.ID,
+		Token:     token,
+		UpdatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"OutputFeed": data.DatabaseOutputFeedToOutputFeed(feed, app.config.baseURL)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) HandlerOutputFeedsDelete(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	outputFeedID, err := app.readIDParam(r, "outputFeedID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	deleted, err := app.db.DeleteOutputFeed(r.Context(), database.DeleteOutputFeedParams{
+		ID:     outputFeedID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if deleted == 0 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "output feed deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+// HandlerOutputFeedRender serves an output feed at its secret URL, such as
+// /v1/out/:token.atom, to feed readers, which can't log in. Responses carry
+// an ETag and a Last-Modified time, so readers polling an unchanged feed are
+// answered with 304 Not Modified.
+func (app *application) HandlerOutputFeedRender(w http.ResponseWriter, r *http.Request) {
+	token, format, _ := strings.Cut(httprouter.ParamsFromContext(r.Context()).ByName("file"), ".")
+	if !slices.Contains(syndication.Formats, format) {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	feed, err := app.db.GetOutputFeedByToken(r.Context(), token)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	params := database.GetPostsForUserParams{
+		UserID:   feed.UserID,
+		FeedIds:  []uuid.UUID{},
+		Status:   "all",
+		Tags:     []string{},
+		SortKey:  "published_at",
+		SortDesc: true,
+		Lim:      outputFeedSize,
+	}
+	switch feed.Source {
+	case data.OutputSourceAll:
+		params.Collapse = true
+	case data.OutputSourceFolder:
+		params.FolderID = feed.FolderID.UUID
+		params.Collapse = true
+	case data.OutputSourceStarred:
+		params.Starred = true
+	case data.OutputSourceTag:
+		params.Tags = []string{feed.Tag.String}
+	case data.OutputSourceSavedSearch:
+		search, err := app.db.GetSavedSearch(r.Context(), database.GetSavedSearchParams{
+			ID:     feed.SavedSearchID.UUID,
+			UserID: feed.UserID,
+		})
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		params.Search = search.Query
+		if search.FeedID.Valid {
+			params.FeedIds = []uuid.UUID{search.FeedID.UUID}
+		}
+		params.FolderID = search.FolderID.UUID
+		params.Tags = search.Tags
+		params.MatchAllTags = search.MatchAllTags
+	}
+
+	posts, err := app.db.GetPostsForUser(r.Context(), params)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Posts are credited to the feed they came from, by the title the user
+	// gave it.
+	followed, err := app.db.GetFollowedFeedTitles(r.Context(), feed.UserID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	sources := make(map[uuid.UUID]database.GetFollowedFeedTitlesRow, len(followed))
+	for _, source := range followed {
+		sources[source.ID] = source
+	}
+
+	out := syndication.Feed{
+		ID:      "urn:uuid:" + feed.ID.String(),
+		Title:   feed.Name,
+		SelfURL: data.OutputFeedURL(app.config.baseURL, feed.Token, format),
+		Updated: feed.UpdatedAt,
+		Items:   make([]syndication.Item, len(posts)),
+	}
+	for i, post := range posts {
+		published := post.CreatedAt
+		if post.PublishedAt.Valid {
+			published = post.PublishedAt.Time
+		}
+		if post.UpdatedAt.After(out.Updated) {
+			out.Updated = post.UpdatedAt
+		}
+
+		out.Items[i] = syndication.Item{
+			ID:            "urn:uuid:" + post.ID.String(),
+			Title:         post.Title,
+			Url:           post.Url,
+			Summary:       post.Description.String,
+			Author:        post.Author.String,
+			Source:        sources[post.FeedID].Title,
+			SourceURL:     sources[post.FeedID].Url,
+			Published:     published,
+			Updated:       post.UpdatedAt,
+			EnclosureUrl:  post.EnclosureUrl.String,
+			EnclosureType: post.EnclosureType.String,
+		}
+	}
+
+	body, err := syndication.Render(format, out)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	etag, err := app.etag(body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", syndication.ContentTypes[format])
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, no-cache")
+
+	// ServeContent answers If-None-Match. It's given no modification time, as
+	// starring, tagging and untagging posts change what's listed without
+	// changing when any post or feed was updated, so only the ETag is used.
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(body))
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/validator"
+	"github.com/google/uuid"
+)
+
+func (app *application) HandlerPostReadSet(w http.ResponseWriter, r *http.Request) {
+	app.setPostReadState(w, r, true)
+}
+
+func (app *application) HandlerPostReadDelete(w http.ResponseWriter, r *http.Request) {
+	app.setPostReadState(w, r, false)
+}
+
+// setPostReadState marks the post in the URL as read or unread for the
+// current user.
+func (app *application) setPostReadState(w http.ResponseWriter, r *http.Request, read bool) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	postID, err := app.readIDParam(r, "postID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	now := time.Now().UTC()
+	params := database.SetPostsReadStateParams{
+		UserID:    user.ID,
+		UpdatedAt: now,
+		PostIds:   []uuid.UUID{postID},
+	}
+	if read {
+		params.ReadAt = sql.NullTime{Time: now, Valid: true}
+	}
+
+	updated, err := app.db.SetPostsReadState(r.Context(), params)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	// Posts outside the user's followed feeds are treated as missing.
+	if updated == 0 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	message := "post marked as unread"
+	if read {
+		message = "post marked as read"
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": message}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) HandlerPostStarSet(w http.ResponseWriter, r *http.Request) {
+	app.setPostStarred(w, r, true)
+}
+
+func (app *application) HandlerPostStarDelete(w http.ResponseWriter, r *http.Request) {
+	app.setPostStarred(w, r, false)
+}
+
+// setPostStarred stars or unstars the post in the URL for the current user.
+// Starring an already starred post keeps its original starred_at.
+func (app *application) setPostStarred(w http.ResponseWriter, r *http.Request, starred bool) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	postID, err := app.readIDParam(r, "postID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	now := time.Now().UTC()
+	params := database.SetPostStarredParams{
+		UserID:    user.ID,
+		UpdatedAt: now,
+		PostID:    postID,
+	}
+	if starred {
+		params.StarredAt = sql.NullTime{Time: now, Valid: true}
+	}
+
+	updated, err := app.db.SetPostStarred(r.Context(), params)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if updated == 0 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	message := "post unstarred"
+	if starred {
+		message = "post starred"
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": message}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) HandlerPostStatesUpdate(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	var input struct {
+		PostIDs []uuid.UUID `json:"post_ids" validate:"required,min=1,max=1000"`
+		Read    *bool       `json:"read" validate:"required"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.ValidateStruct(input)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	now := time.Now().UTC()
+	params := database.SetPostsReadStateParams{
+		UserID:    user.ID,
+		UpdatedAt: now,
+		PostIds:   input.PostIDs,
+	}
+	if *input.Read {
+		params.ReadAt = sql.NullTime{Time: now, Valid: true}
+	}
+
+	updated, err := app.db.SetPostsReadState(r.Context(), params)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"updated": updated}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+// HandlerPostStatesMarkRead marks every post collected up to a point in time
+// as read, either across all followed feeds or for a single feed.
+func (app *application) HandlerPostStatesMarkRead(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	var input struct {
+		FeedID uuid.UUID  `json:"feed_id"`
+		Before *time.Time `json:"before"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	now := time.Now().UTC()
+	before := now
+	if input.Before != nil {
+		before = input.Before.UTC()
+	}
+
+	updated, err := app.db.MarkAllPostsRead(r.Context(), database.MarkAllPostsReadParams{
+		UserID: user.ID,
+		ReadAt: now,
+		Before: before,
+		FeedID: input.FeedID,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"updated": updated}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
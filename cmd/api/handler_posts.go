@@ -1,7 +1,14 @@
 package main
 
 import (
+	"database/sql"
 	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/data"
 	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
@@ -9,13 +16,85 @@ import (
 	"github.com/google/uuid"
 )
 
-func (app *application) HandlerPostsGet(w http.ResponseWriter, r *http.Request) {
-	var input struct {
-		Title  string
-		FeedID uuid.UUID
-		data.Filters
+var languageRX = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{1,8})*$`)
+
+// postsQuery is a listing of posts: what to filter on, and how to sort and
+// page through the results.
+type postsQuery struct {
+	Search          string
+	FeedIDs         []uuid.UUID
+	FolderID        uuid.UUID
+	Collapse        bool
+	Status          string
+	Starred         bool
+	Hidden          bool
+	Tags            []string
+	TagMode         string
+	PublishedAfter  time.Time
+	PublishedBefore time.Time
+	CreatedAfter    time.Time
+	Author          string
+	HasEnclosure    *bool
+	Language        string
+	After           string
+	Before          string
+	Count           bool
+	data.Filters
+}
+
+func (app *application) readPostsQuery(qs url.Values, v *validator.Validator) postsQuery {
+	var input postsQuery
+
+	// title is the name the search parameter had before q.
+	input.Search = app.readString(qs, "q", app.readString(qs, "title", ""))
+	input.FeedIDs = app.readUUIDs(qs, "feed_id", v)
+	v.Check(len(input.FeedIDs) <= 100, "feed_id", "must not contain more than 100 feeds")
+	input.FolderID = app.readUUID(qs, "folder_id", v)
+
+	input.Collapse = app.readBool(qs, "collapse", false, v)
+
+	input.Status = app.readString(qs, "status", "all")
+	v.Check(validator.PermittedValue(input.Status, "all", "read", "unread"), "status", "must be one of all, read or unread")
+
+	input.Starred = app.readBool(qs, "starred", false, v)
+	input.Hidden = app.readBool(qs, "include_hidden", false, v)
+
+	input.Tags = data.NormalizeTags(app.readCSV(qs, "tags", []string{}))
+	data.ValidateTags(v, "tags", input.Tags)
+	input.TagMode = app.readString(qs, "tag_mode", "any")
+	v.Check(validator.PermittedValue(input.TagMode, "any", "all"), "tag_mode", "must be either any or all")
+
+	input.PublishedAfter = app.readTime(qs, "published_after", v)
+	input.PublishedBefore = app.readTime(qs, "published_before", v)
+	v.Check(input.PublishedAfter.IsZero() || input.PublishedBefore.IsZero() || input.PublishedAfter.Before(input.PublishedBefore),
+		"published_before", "must be later than published_after")
+	input.CreatedAfter = app.readTime(qs, "created_after", v)
+
+	input.Author = strings.TrimSpace(app.readString(qs, "author", ""))
+	v.Check(utf8.RuneCountInString(input.Author) <= 200, "author", "must not be more than 200 characters long")
+
+	if qs.Has("has_enclosure") {
+		hasEnclosure := app.readBool(qs, "has_enclosure", false, v)
+		input.HasEnclosure = &hasEnclosure
 	}
 
+	input.Language = strings.ToLower(app.readString(qs, "language", ""))
+	v.Check(input.Language == "" || languageRX.MatchString(input.Language), "language", "must be a language tag such as en or en-gb")
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+
+	input.Filters.Sort = app.readString(qs, "sort", "published_at")
+	input.Filters.SortSafelist = []string{"id", "title", "published_at", "relevance", "-id", "-title", "-published_at"}
+
+	input.After = app.readString(qs, "after", "")
+	input.Before = app.readString(qs, "before", "")
+	input.Count = app.readBool(qs, "count", true, v)
+
+	return input
+}
+
+func (app *application) HandlerPostsGet(w http.ResponseWriter, r *http.Request) {
 	user := app.contextGetUser(r)
 	if user.IsAnonymous() {
 		app.authenticationRequiredResponse(w, r)
@@ -23,50 +102,134 @@ func (app *application) HandlerPostsGet(w http.ResponseWriter, r *http.Request)
 	}
 
 	v := validator.New()
+	input := app.readPostsQuery(r.URL.Query(), v)
 
-	qs := r.URL.Query()
+	app.writePosts(w, r, user.ID, input, v)
+}
 
-	input.Title = app.readString(qs, "title", "")
-	feedIDStr := app.readString(qs, "feed_id", "")
-	if feedIDStr != "" {
-		feedID, err := uuid.Parse(feedIDStr)
-		if err != nil {
-			v.AddError("feed_id", "must be a valid UUID")
-		} else {
-			input.FeedID = feedID
-		}
-	}
+// writePosts validates a posts listing and responds with the requested page
+// of it. Pages are chosen either by number or, for listings that change while
+// they're read, by the after and before cursors in each page's metadata.
+func (app *application) writePosts(w http.ResponseWriter, r *http.Request, userID uuid.UUID, input postsQuery, v *validator.Validator) {
+	v.Check(input.Filters.Sort != "relevance" || input.Search != "", "sort", "relevance requires a q search")
+	v.Check(input.After == "" || input.Before == "", "after", "can't be combined with before")
 
-	input.Filters.Page = app.readInt(qs, "page", 1, v)
-	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
-
-	input.Filters.Sort = app.readString(qs, "sort", "published_at")
-	input.Filters.SortSafelist = []string{"id", "title", "published_at", "-id", "-title", "-published_at"}
+	var (
+		cursor data.Cursor
+		err    error
+	)
+	switch {
+	case input.After != "":
+		cursor, err = data.DecodeCursor(input.After)
+		v.Check(err == nil && cursor.Sort == input.Filters.Sort, "after", "must be a cursor from a listing with the same sort")
+	case input.Before != "":
+		cursor, err = data.DecodeCursor(input.Before)
+		v.Check(err == nil && cursor.Sort == input.Filters.Sort, "before", "must be a cursor from a listing with the same sort")
+	}
+	paging := input.After != "" || input.Before != ""
+	v.Check(!paging || input.Filters.Page == 1, "page", "can't be combined with a cursor")
 
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
+	// Pages before a cursor are read backwards from it, then put back in
+	// order.
+	sortKey, sortDesc := data.SortKey(input.Filters.Sort)
+	backwards := input.Before != ""
+	if backwards {
+		sortDesc = !sortDesc
+	}
+
+	var cursorTime time.Time
+	if cursor.Time != nil {
+		cursorTime = *cursor.Time
+	}
+
+	// One extra post is read to tell whether there's another page.
 	posts, err := app.db.GetPostsForUser(r.Context(), database.GetPostsForUserParams{
-		UserID: user.ID,
-		Title:  input.Title,
-		FeedID: input.FeedID,
-		Sort:   input.Filters.Sort,
-		Lim:    int32(input.Filters.Limit()),  //#nosec G115
-		Off:    int32(input.Filters.Offset()), //#nosec G115
+		UserID:          userID,
+		Search:          input.Search,
+		FeedIds:         input.FeedIDs,
+		FolderID:        input.FolderID,
+		Collapse:        input.Collapse,
+		Status:          input.Status,
+		Starred:         input.Starred,
+		IncludeHidden:   input.Hidden,
+		Tags:            input.Tags,
+		MatchAllTags:    input.TagMode == "all",
+		PublishedAfter:  nullTime(input.PublishedAfter),
+		PublishedBefore: nullTime(input.PublishedBefore),
+		CreatedAfter:    nullTime(input.CreatedAfter),
+		Author:          input.Author,
+		HasEnclosure:    nullBool(input.HasEnclosure),
+		Language:        input.Language,
+		CursorID:        cursor.ID,
+		SortDesc:        sortDesc,
+		SortKey:         sortKey,
+		CursorText:      cursor.Text,
+		CursorTime:      cursorTime,
+		CursorRank:      cursor.Rank,
+		Off:             int32(input.Filters.Offset()),    //#nosec G115
+		Lim:             int32(input.Filters.Limit() + 1), //#nosec G115
 	})
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	totalRecords := 0
-	if len(posts) > 0 {
-		totalRecords = int(posts[0].Count)
+	more := len(posts) > input.Filters.Limit()
+	if more {
+		posts = posts[:input.Filters.Limit()]
+	}
+	if backwards {
+		slices.Reverse(posts)
 	}
 
-	metadata := data.CalculateMetadata(totalRecords, input.Filters.Page, input.Filters.PageSize)
+	var metadata data.Metadata
+	if input.Count {
+		total, err := app.db.CountPostsForUser(r.Context(), database.CountPostsForUserParams{
+			UserID:          userID,
+			FeedIds:         input.FeedIDs,
+			Search:          input.Search,
+			FolderID:        input.FolderID,
+			Collapse:        input.Collapse,
+			Status:          input.Status,
+			Starred:         input.Starred,
+			IncludeHidden:   input.Hidden,
+			Tags:            input.Tags,
+			MatchAllTags:    input.TagMode == "all",
+			PublishedAfter:  nullTime(input.PublishedAfter),
+			PublishedBefore: nullTime(input.PublishedBefore),
+			CreatedAfter:    nullTime(input.CreatedAfter),
+			Author:          input.Author,
+			HasEnclosure:    nullBool(input.HasEnclosure),
+			Language:        input.Language,
+		})
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		if paging {
+			metadata = data.Metadata{PageSize: input.Filters.PageSize, TotalRecords: int(total)}
+		} else {
+			metadata = data.CalculateMetadata(int(total), input.Filters.Page, input.Filters.PageSize)
+		}
+	}
+
+	if len(posts) > 0 {
+		first := data.PostCursor(input.Filters.Sort, posts[0]).Encode()
+		last := data.PostCursor(input.Filters.Sort, posts[len(posts)-1]).Encode()
+
+		if more || backwards {
+			metadata.NextCursor = last
+		}
+		if (backwards && more) || input.After != "" || (!paging && input.Filters.Page > 1) {
+			metadata.PrevCursor = first
+		}
+	}
 
 	err = app.writeJSON(w, http.StatusOK, envelope{
 		"Metadata": metadata,
@@ -77,3 +240,14 @@ func (app *application) HandlerPostsGet(w http.ResponseWriter, r *http.Request)
 		return
 	}
 }
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+func nullBool(b *bool) sql.NullBool {
+	if b == nil {
+		return sql.NullBool{}
+	}
+	return sql.NullBool{Bool: *b, Valid: true}
+}
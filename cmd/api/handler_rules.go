@@ -0,0 +1,313 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/data"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/rules"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/validator"
+	"github.com/google/uuid"
+)
+
+// ruleInput is the request body for creating and replacing rules. A rule
+// without a feed_id applies to every feed the user follows.
+type ruleInput struct {
+	Name      string     `json:"name" validate:"required,min=1,max=100"`
+	FeedID    *uuid.UUID `json:"feed_id"`
+	Field     string     `json:"field" validate:"required"`
+	MatchType string     `json:"match_type" validate:"required"`
+	Pattern   string     `json:"pattern" validate:"required,max=500"`
+	Actions   []string   `json:"actions" validate:"required,min=1"`
+	Tag       string     `json:"tag"`
+	Enabled   *bool      `json:"enabled"`
+}
+
+// readRuleInput decodes and validates a rule, sending the error response
+// itself when the rule is invalid.
+func (app *application) readRuleInput(w http.ResponseWriter, r *http.Request) (ruleInput, bool) {
+	var input ruleInput
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return input, false
+	}
+
+	v := validator.New()
+	v.ValidateStruct(input)
+	if tags := data.NormalizeTags([]string{input.Tag}); len(tags) > 0 {
+		input.Tag = tags[0]
+	} else {
+		input.Tag = ""
+	}
+	if v.Valid() {
+		data.ValidateRule(v, input.Field, input.MatchType, input.Pattern, input.Actions, input.Tag)
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return input, false
+	}
+
+	if input.Enabled == nil {
+		enabled := true
+		input.Enabled = &enabled
+	}
+
+	return input, true
+}
+
+func (input ruleInput) feedID() uuid.NullUUID {
+	if input.FeedID == nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: *input.FeedID, Valid: true}
+}
+
+func (input ruleInput) tag() sql.NullString {
+	return sql.NullString{String: input.Tag, Valid: input.Tag != ""}
+}
+
+func (app *application) HandlerRulesCreate(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	input, ok := app.readRuleInput(w, r)
+	if !ok {
+		return
+	}
+
+	rule, err := app.db.CreateRule(r.Context(), database.CreateRuleParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		UserID:    user.ID,
+		Name:      input.Name,
+		FeedID:    input.feedID(),
+		Field:     input.Field,
+		MatchType: input.MatchType,
+		Pattern:   input.Pattern,
+		Actions:   input.Actions,
+		Tag:       input.tag(),
+		Enabled:   *input.Enabled,
+	})
+	if err != nil {
+		switch {
+		case data.IsForeignKeyViolation(err):
+			app.failedValidationResponse(w, r, map[string]string{"FeedID": "feed not found"})
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"Rule": data.DatabaseRuleToRule(rule)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) HandlerRulesGet(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	userRules, err := app.db.GetRules(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"Rules": data.DatabaseRulesToRules(userRules)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) HandlerRulesUpdate(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	ruleID, err := app.readIDParam(r, "ruleID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	input, ok := app.readRuleInput(w, r)
+	if !ok {
+		return
+	}
+
+	rule, err := app.db.UpdateRule(r.Context(), database.UpdateRuleParams{
+		ID:        ruleID,
+		UserID:    user.ID,
+		Name:      input.Name,
+		FeedID:    input.feedID(),
+		Field:     input.Field,
+		MatchType: input.MatchType,
+		Pattern:   input.Pattern,
+		Actions:   input.Actions,
+		Tag:       input.tag(),
+		Enabled:   *input.Enabled,
+		UpdatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			app.notFoundResponse(w, r)
+		case data.IsForeignKeyViolation(err):
+			app.failedValidationResponse(w, r, map[string]string{"FeedID": "feed not found"})
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"Rule": data.DatabaseRuleToRule(rule)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) HandlerRulesDelete(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	ruleID, err := app.readIDParam(r, "ruleID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	deleted, err := app.db.DeleteRule(r.Context(), database.DeleteRuleParams{
+		ID:     ruleID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if deleted == 0 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "rule deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+// HandlerRulesApply runs a rule against posts that were collected before it
+// was created or changed. It works on disabled rules too, so a rule can be
+// used for a one-off clean up.
+func (app *application) HandlerRulesApply(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	ruleID, err := app.readIDParam(r, "ruleID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	rule, err := app.db.GetRule(r.Context(), database.GetRuleParams{
+		ID:     ruleID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	matched, err := rules.New(app.db).Apply(r.Context(), rule)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"Matched": matched}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) HandlerNotificationsGet(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.Filters
+	}
+
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = "-created_at"
+	input.Filters.SortSafelist = []string{"-created_at"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	notifications, err := app.db.GetNotifications(r.Context(), database.GetNotificationsParams{
+		UserID: user.ID,
+		Off:    int32(input.Filters.Offset()), //#nosec G115
+		Lim:    int32(input.Filters.Limit()),  //#nosec G115
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	totalRecords := 0
+	if len(notifications) > 0 {
+		totalRecords = int(notifications[0].Count)
+	}
+
+	metadata := data.CalculateMetadata(totalRecords, input.Filters.Page, input.Filters.PageSize)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{
+		"Metadata":      metadata,
+		"Notifications": data.DatabaseNotificationsToNotifications(notifications),
+	}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
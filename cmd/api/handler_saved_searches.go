@@ -0,0 +1,274 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/data"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/validator"
+	"github.com/google/uuid"
+)
+
+// savedSearchInput is the request body for creating and replacing saved
+// searches. Its fields mean the same as the matching /v1/posts parameters.
+type savedSearchInput struct {
+	Name     string     `json:"name" validate:"required,min=1,max=100"`
+	Query    string     `json:"query" validate:"max=500"`
+	FeedID   *uuid.UUID `json:"feed_id"`
+	FolderID *uuid.UUID `json:"folder_id"`
+	Tags     []string   `json:"tags"`
+	TagMode  string     `json:"tag_mode"`
+}
+
+// readSavedSearchInput decodes and validates a saved search, sending the
+// error response itself when the search is invalid.
+func (app *application) readSavedSearchInput(w http.ResponseWriter, r *http.Request, userID uuid.UUID) (savedSearchInput, bool) {
+	var input savedSearchInput
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return input, false
+	}
+
+	if input.TagMode == "" {
+		input.TagMode = "any"
+	}
+	input.Tags = data.NormalizeTags(input.Tags)
+
+	v := validator.New()
+	v.ValidateStruct(input)
+	v.Check(validator.PermittedValue(input.TagMode, "any", "all"), "TagMode", "must be either any or all")
+	data.ValidateTags(v, "Tags", input.Tags)
+	v.Check(input.Query != "" || input.FeedID != nil || input.FolderID != nil || len(input.Tags) > 0, "Query", "must be provided when there are no other filters")
+
+	if input.FolderID != nil && v.Valid() {
+		_, err = app.db.GetFolder(r.Context(), database.GetFolderParams{
+			ID:     *input.FolderID,
+			UserID: userID,
+		})
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			v.AddError("FolderID", "folder not found")
+		case err != nil:
+			app.serverErrorResponse(w, r, err)
+			return input, false
+		}
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return input, false
+	}
+
+	return input, true
+}
+
+func (input savedSearchInput) feedID() uuid.NullUUID {
+	if input.FeedID == nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: *input.FeedID, Valid: true}
+}
+
+func (input savedSearchInput) folderID() uuid.NullUUID {
+	if input.FolderID == nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: *input.FolderID, Valid: true}
+}
+
+// savedSearchErrorResponse responds to errors saving a saved search.
+func (app *application) savedSearchErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		app.notFoundResponse(w, r)
+	case data.IsUniqueViolation(err):
+		app.failedValidationResponse(w, r, map[string]string{"Name": "a saved search with this name already exists"})
+	case data.IsForeignKeyViolation(err):
+		app.failedValidationResponse(w, r, map[string]string{"FeedID": "feed not found"})
+	default:
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) HandlerSavedSearchesCreate(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	input, ok := app.readSavedSearchInput(w, r, user.ID)
+	if !ok {
+		return
+	}
+
+	search, err := app.db.CreateSavedSearch(r.Context(), database.CreateSavedSearchParams{
+		ID:           uuid.New(),
+		CreatedAt:    time.Now().UTC(),
+		UpdatedAt:    time.Now().UTC(),
+		UserID:       user.ID,
+		Name:         input.Name,
+		Query:        input.Query,
+		FeedID:       input.feedID(),
+		FolderID:     input.folderID(),
+		Tags:         input.Tags,
+		MatchAllTags: input.TagMode == "all",
+	})
+	if err != nil {
+		app.savedSearchErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"SavedSearch": data.DatabaseSavedSearchToSavedSearch(search)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) HandlerSavedSearchesGet(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	searches, err := app.db.GetSavedSearches(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"SavedSearches": data.DatabaseSavedSearchesToSavedSearches(searches)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) HandlerSavedSearchesUpdate(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	searchID, err := app.readIDParam(r, "savedSearchID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	input, ok := app.readSavedSearchInput(w, r, user.ID)
+	if !ok {
+		return
+	}
+
+	search, err := app.db.UpdateSavedSearch(r.Context(), database.UpdateSavedSearchParams{
+		ID:           searchID,
+		UserID:       user.ID,
+		Name:         input.Name,
+		Query:        input.Query,
+		FeedID:       input.feedID(),
+		FolderID:     input.folderID(),
+		Tags:         input.Tags,
+		MatchAllTags: input.TagMode == "all",
+		UpdatedAt:    time.Now().UTC(),
+	})
+	if err != nil {
+		app.savedSearchErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"SavedSearch": data.DatabaseSavedSearchToSavedSearch(search)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) HandlerSavedSearchesDelete(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	searchID, err := app.readIDParam(r, "savedSearchID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	deleted, err := app.db.DeleteSavedSearch(r.Context(), database.DeleteSavedSearchParams{
+		ID:     searchID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if deleted == 0 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "saved search deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+// HandlerSavedSearchPostsGet lists the posts matching a saved search. It
+// takes the same parameters as /v1/posts, except that the saved search's own
+// filters replace q, feed_id, folder_id, tags and tag_mode.
+func (app *application) HandlerSavedSearchPostsGet(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	searchID, err := app.readIDParam(r, "savedSearchID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	search, err := app.db.GetSavedSearch(r.Context(), database.GetSavedSearchParams{
+		ID:     searchID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	v := validator.New()
+	input := app.readPostsQuery(r.URL.Query(), v)
+
+	input.Search = search.Query
+	input.FeedIDs = []uuid.UUID{}
+	if search.FeedID.Valid {
+		input.FeedIDs = []uuid.UUID{search.FeedID.UUID}
+	}
+	input.FolderID = search.FolderID.UUID
+	input.Tags = search.Tags
+	input.TagMode = "any"
+	if search.MatchAllTags {
+		input.TagMode = "all"
+	}
+
+	app.writePosts(w, r, user.ID, input, v)
+}
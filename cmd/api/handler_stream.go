@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/data"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/validator"
+	"github.com/google/uuid"
+)
+
+const (
+	// streamSort is the sort recorded in stream event IDs, which are cursors
+	// over the transactions that collected posts, then the order posts were
+	// inserted in each. Posts are only streamed once every older transaction
+	// has ended, so, unlike ordering by when they were collected or by an ID
+	// assigned on insert, a post committed after later ones were streamed
+	// can't fall behind a cursor.
+	streamSort = "xid"
+
+	// legacyStreamSort is the sort of event IDs from before transaction
+	// cursors, which were cursors over when posts were collected.
+	legacyStreamSort = "created_at"
+
+	// stateChangesSort is the sort of cursors over post state changes, which
+	// are ordered like posts in streams, by the transactions that made them.
+	stateChangesSort = "changed_xid"
+
+	streamBatchSize = 100
+
+	// Each write to a stream gets streamWriteTimeout to complete, in place
+	// of the server's WriteTimeout, which would otherwise end every stream.
+	streamWriteTimeout = 10 * time.Second
+
+	// Comments are sent while there are no posts so proxies keep the
+	// connection open and closed connections are noticed. Posts are checked
+	// for then too, in case a notification was lost.
+	streamHeartbeat = 30 * time.Second
+)
+
+// HandlerStreamGet streams posts from the user's followed feeds as they're
+// collected, as Server-Sent Events. Each event's ID marks the post's place in
+// the stream, so clients reconnecting with a Last-Event-ID get the posts they
+// missed first.
+func (app *application) HandlerStreamGet(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	var cursor data.Cursor
+	if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+		var err error
+		cursor, err = data.DecodeCursor(lastEventID)
+
+		v := validator.New()
+		v.Check(err == nil && (cursor.Sort == streamSort || cursor.Sort == legacyStreamSort), "Last-Event-ID", "must be the ID of a stream event")
+		if !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+	}
+
+	cursor, err := app.streamCursor(r.Context(), cursor)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Subscribe before the first read, so posts announced in between aren't
+	// missed.
+	subscription := app.stream.Subscribe(user.ID)
+	defer subscription.Close()
+
+	rc := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	write := func(event string) error {
+		err := rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprint(w, event)
+		if err != nil {
+			return err
+		}
+
+		return rc.Flush()
+	}
+
+	// send writes every post after the cursor, moving the cursor past them.
+	send := func() error {
+		var err error
+		cursor, err = app.readNewPosts(r.Context(), user.ID, cursor, func(post data.Post, postCursor data.Cursor) error {
+			js, err := json.Marshal(post)
+			if err != nil {
+				return err
+			}
+
+			return write(fmt.Sprintf("id: %s\nevent: post\ndata: %s\n\n", postCursor.Encode(), js))
+		})
+		return err
+	}
+
+	// The response has started, so errors can only be logged, and not at
+	// all once the client has gone.
+	fail := func(err error) {
+		if r.Context().Err() == nil {
+			app.logError(r, err)
+		}
+	}
+
+	// Posts missed since the Last-Event-ID are sent before anything else.
+	err = send()
+	if err == nil {
+		err = write(": connected\n\n")
+	}
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case _, ok := <-subscription.C:
+			if !ok {
+				return
+			}
+		case <-heartbeat.C:
+			err := write(": heartbeat\n\n")
+			if err != nil {
+				fail(err)
+				return
+			}
+		}
+
+		err := send()
+		if err != nil {
+			fail(err)
+			return
+		}
+	}
+}
+
+// streamCursor returns where a stream resumes from a client's cursor: after
+// the post it names, or after every post committed so far for new streams.
+// Cursors from before transaction cursors resume after the post they name
+// too, or like new streams if it's gone.
+func (app *application) streamCursor(ctx context.Context, cursor data.Cursor) (data.Cursor, error) {
+	if cursor.Sort == streamSort {
+		return cursor, nil
+	}
+
+	if cursor.Sort == legacyStreamSort {
+		order, err := app.db.GetPostStreamOrder(ctx, cursor.ID)
+		if err == nil {
+			return data.Cursor{Sort: streamSort, ID: cursor.ID, Xid: order.CreatedXid, Seq: order.StreamSeq}, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return cursor, err
+		}
+	}
+
+	// Posts of transactions older than the horizon have all been committed,
+	// and the rest are yet to be streamed.
+	horizon, err := app.db.GetStreamHorizon(ctx)
+	if err != nil {
+		return cursor, err
+	}
+	return data.Cursor{Sort: streamSort, Xid: horizon}, nil
+}
+
+// readNewPosts calls fn, in order, for each post from the user's followed
+// feeds collected after cursor, with the cursor after that post. It returns
+// the cursor after the last post fn accepted.
+func (app *application) readNewPosts(ctx context.Context, userID uuid.UUID, cursor data.Cursor, fn func(post data.Post, cursor data.Cursor) error) (data.Cursor, error) {
+	for {
+		posts, err := app.db.GetNewPostsForUser(ctx, database.GetNewPostsForUserParams{
+			UserID:   userID,
+			AfterXid: cursor.Xid,
+			AfterSeq: cursor.Seq,
+			Lim:      streamBatchSize,
+		})
+		if err != nil {
+			return cursor, err
+		}
+
+		for _, post := range posts {
+			next := data.Cursor{Sort: streamSort, ID: post.Post.ID, Xid: post.Post.CreatedXid, Seq: post.Post.StreamSeq}
+
+			err = fn(data.DatabaseNewPostToPost(post), next)
+			if err != nil {
+				return cursor, err
+			}
+			cursor = next
+		}
+
+		if len(posts) < streamBatchSize {
+			return cursor, nil
+		}
+	}
+}
+
+// readStateChanges calls fn with each batch of the user's post states changed
+// after cursor, in the order they changed. It returns the cursor after the
+// last batch fn accepted.
+func (app *application) readStateChanges(ctx context.Context, userID uuid.UUID, cursor data.Cursor, fn func(states []data.PostState) error) (data.Cursor, error) {
+	for {
+		states, err := app.db.GetPostStatesChangedForUser(ctx, database.GetPostStatesChangedForUserParams{
+			UserID:   userID,
+			AfterXid: cursor.Xid,
+			AfterSeq: cursor.Seq,
+			Lim:      streamBatchSize,
+		})
+		if err != nil {
+			return cursor, err
+		}
+
+		if len(states) > 0 {
+			err = fn(data.DatabasePostStatesToPostStates(states))
+			if err != nil {
+				return cursor, err
+			}
+
+			last := states[len(states)-1]
+			cursor = data.Cursor{Sort: stateChangesSort, ID: last.PostID, Xid: last.ChangedXid, Seq: last.ChangeSeq}
+		}
+
+		if len(states) < streamBatchSize {
+			return cursor, nil
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/data"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/validator"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+func (app *application) HandlerPostTagsCreate(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	postID, err := app.readIDParam(r, "postID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Tags []string `json:"tags" validate:"required,min=1"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.ValidateStruct(input)
+	input.Tags = data.NormalizeTags(input.Tags)
+	v.Check(len(input.Tags) > 0, "Tags", "must contain at least one tag")
+	if data.ValidateTags(v, "Tags", input.Tags); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	visible, err := app.db.IsPostVisibleToUser(r.Context(), database.IsPostVisibleToUserParams{
+		ID:     postID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !visible {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	_, err = app.db.AddPostTags(r.Context(), database.AddPostTagsParams{
+		CreatedAt: time.Now().UTC(),
+		UserID:    user.ID,
+		Names:     input.Tags,
+		PostID:    postID,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writePostTags(w, r, user.ID, postID)
+}
+
+func (app *application) HandlerPostTagsDelete(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	postID, err := app.readIDParam(r, "postID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	tags := data.NormalizeTags([]string{httprouter.ParamsFromContext(r.Context()).ByName("tag")})
+	if len(tags) == 0 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	removed, err := app.db.RemovePostTag(r.Context(), database.RemovePostTagParams{
+		UserID: user.ID,
+		Name:   tags[0],
+		PostID: postID,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if removed == 0 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	app.writePostTags(w, r, user.ID, postID)
+}
+
+// writePostTags responds with the current user's tags on a post.
+func (app *application) writePostTags(w http.ResponseWriter, r *http.Request, userID, postID uuid.UUID) {
+	tags, err := app.db.GetPostTags(r.Context(), database.GetPostTagsParams{
+		UserID: userID,
+		PostID: postID,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if tags == nil {
+		tags = []string{}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"Tags": tags}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) HandlerTagsGet(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	tags, err := app.db.GetTagsForUser(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"Tags": data.DatabaseTagsToTags(tags)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
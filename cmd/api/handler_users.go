@@ -64,7 +64,7 @@ func (app *application) HandlerUsersCreate(w http.ResponseWriter, r *http.Reques
 
 	err = app.db.GrantPermissionToUser(r.Context(), database.GrantPermissionToUserParams{
 		UserID: dbUser.ID,
-		Codes:  []string{"feeds:read", "feeds:write", "feed_follows:write", "feed_follows:read", "posts:read"},
+		Codes:  []string{"feeds:read", "feeds:write", "feed_follows:write", "feed_follows:read", "posts:read", "posts:write"},
 	})
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/data"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/netguard"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/validator"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/webhooks"
+	"github.com/google/uuid"
+)
+
+// webhookInput is the request body for creating and replacing webhooks. A
+// webhook is sent the new posts of every feed the user follows that pass all
+// of its filters: keywords match when any of them is in the post's title or
+// description. For Matrix webhooks, the URL is a room's send endpoint and the
+// secret is an access token. Slack and Discord URLs are secret themselves, so
+// their webhooks needn't have a secret.
+type webhookInput struct {
+	Url      string     `json:"url" validate:"required,url,max=2000"`
+	Secret   string     `json:"secret" validate:"omitempty,min=16,max=500"`
+	Format   string     `json:"format"`
+	FeedID   *uuid.UUID `json:"feed_id"`
+	FolderID *uuid.UUID `json:"folder_id"`
+	Keywords []string   `json:"keywords" validate:"max=20,dive,max=100"`
+	Enabled  *bool      `json:"enabled"`
+}
+
+// readWebhookInput decodes and validates a webhook, sending the error
+// response itself when the webhook is invalid.
+func (app *application) readWebhookInput(w http.ResponseWriter, r *http.Request, userID uuid.UUID) (webhookInput, bool) {
+	var input webhookInput
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return input, false
+	}
+
+	keywords := []string{}
+	for _, keyword := range input.Keywords {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			keywords = append(keywords, keyword)
+		}
+	}
+	input.Keywords = keywords
+
+	if input.Format == "" {
+		input.Format = webhooks.FormatJSON
+	}
+
+	v := validator.New()
+	v.ValidateStruct(input)
+	v.Check(validator.PermittedValue(input.Format, webhooks.Formats...), "Format", "must be one of json, slack, discord or matrix")
+	if input.Format == webhooks.FormatJSON || input.Format == webhooks.FormatMatrix {
+		v.Check(input.Secret != "", "Secret", "must be provided")
+	}
+	if u, err := url.Parse(input.Url); err == nil {
+		v.Check(u.Scheme == "http" || u.Scheme == "https", "Url", "must be an http or https URL")
+		v.Check(app.config.webhooks.allowPrivate || netguard.IsPublicHost(u.Hostname()), "Url", "must not be a private or local address")
+		if input.Format == webhooks.FormatMatrix {
+			v.Check(strings.HasSuffix(u.Path, "/send/m.room.message"), "Url", "must be a Matrix room's send/m.room.message endpoint")
+		}
+	}
+
+	if input.FolderID != nil && v.Valid() {
+		_, err = app.db.GetFolder(r.Context(), database.GetFolderParams{
+			ID:     *input.FolderID,
+			UserID: userID,
+		})
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			v.AddError("FolderID", "folder not found")
+		case err != nil:
+			app.serverErrorResponse(w, r, err)
+			return input, false
+		}
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return input, false
+	}
+
+	if input.Enabled == nil {
+		enabled := true
+		input.Enabled = &enabled
+	}
+
+	return input, true
+}
+
+func (input webhookInput) feedID() uuid.NullUUID {
+	if input.FeedID == nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: *input.FeedID, Valid: true}
+}
+
+func (input webhookInput) folderID() uuid.NullUUID {
+	if input.FolderID == nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: *input.FolderID, Valid: true}
+}
+
+// webhookErrorResponse responds to errors saving a webhook.
+func (app *application) webhookErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		app.notFoundResponse(w, r)
+	case data.IsForeignKeyViolation(err):
+		app.failedValidationResponse(w, r, map[string]string{"FeedID": "feed not found"})
+	default:
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readWebhook looks up the user's webhook named in the URL, sending the error
+// response itself when there isn't one.
+func (app *application) readWebhook(w http.ResponseWriter, r *http.Request, userID uuid.UUID) (database.Webhook, bool) {
+	webhookID, err := app.readIDParam(r, "webhookID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return database.Webhook{}, false
+	}
+
+	hook, err := app.db.GetWebhook(r.Context(), database.GetWebhookParams{
+		ID:     webhookID,
+		UserID: userID,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return database.Webhook{}, false
+	}
+
+	return hook, true
+}
+
+func (app *application) HandlerWebhooksCreate(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	input, ok := app.readWebhookInput(w, r, user.ID)
+	if !ok {
+		return
+	}
+
+	hook, err := app.db.CreateWebhook(r.Context(), database.CreateWebhookParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		UserID:    user.ID,
+		Url:       input.Url,
+		Secret:    input.Secret,
+		FeedID:    input.feedID(),
+		FolderID:  input.folderID(),
+		Keywords:  input.Keywords,
+		Enabled:   *input.Enabled,
+		Format:    input.Format,
+	})
+	if err != nil {
+		app.webhookErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"Webhook": data.DatabaseWebhookToWebhook(hook)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) HandlerWebhooksGet(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	hooks, err := app.db.GetWebhooks(r.Context(), user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"Webhooks": data.DatabaseWebhooksToWebhooks(hooks)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) HandlerWebhookGet(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	hook, ok := app.readWebhook(w, r, user.ID)
+	if !ok {
+		return
+	}
+
+	err := app.writeJSON(w, http.StatusOK, envelope{"Webhook": data.DatabaseWebhookToWebhook(hook)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) HandlerWebhooksUpdate(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	webhookID, err := app.readIDParam(r, "webhookID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	input, ok := app.readWebhookInput(w, r, user.ID)
+	if !ok {
+		return
+	}
+
+	hook, err := app.db.UpdateWebhook(r.Context(), database.UpdateWebhookParams{
+		ID:        webhookID,
+		UserID:    user.ID,
+		Url:       input.Url,
+		Secret:    input.Secret,
+		FeedID:    input.feedID(),
+		FolderID:  input.folderID(),
+		Keywords:  input.Keywords,
+		Enabled:   *input.Enabled,
+		Format:    input.Format,
+		UpdatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		app.webhookErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"Webhook": data.DatabaseWebhookToWebhook(hook)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) HandlerWebhooksDelete(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	webhookID, err := app.readIDParam(r, "webhookID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	deleted, err := app.db.DeleteWebhook(r.Context(), database.DeleteWebhookParams{
+		ID:     webhookID,
+		UserID: user.ID,
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if deleted == 0 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "webhook deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+// HandlerWebhookDeliveriesGet lists a webhook's deliveries, newest first.
+func (app *application) HandlerWebhookDeliveriesGet(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.Filters
+	}
+
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	webhookID, err := app.readIDParam(r, "webhookID")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = "-created_at"
+	input.Filters.SortSafelist = []string{"-created_at"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	deliveries, err := app.db.GetWebhookDeliveries(r.Context(), database.GetWebhookDeliveriesParams{
+		WebhookID: webhookID,
+		UserID:    user.ID,
+		Off:       int32(input.Filters.Offset()), //#nosec G115
+		Lim:       int32(input.Filters.Limit()),  //#nosec G115
+	})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	totalRecords := 0
+	if len(deliveries) > 0 {
+		totalRecords = int(deliveries[0].Count)
+	}
+
+	metadata := data.CalculateMetadata(totalRecords, input.Filters.Page, input.Filters.PageSize)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{
+		"Metadata":   metadata,
+		"Deliveries": data.DatabaseWebhookDeliveriesToWebhookDeliveries(deliveries),
+	}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+// HandlerWebhookTest sends a test event to a webhook, even a disabled one,
+// and responds with how the delivery went. Failed test deliveries are retried
+// like any other.
+func (app *application) HandlerWebhookTest(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		app.authenticationRequiredResponse(w, r)
+		return
+	}
+
+	hook, ok := app.readWebhook(w, r, user.ID)
+	if !ok {
+		return
+	}
+
+	delivery, err := webhooks.New(app.db, app.config.webhooks.allowPrivate).SendTest(r.Context(), hook)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"Delivery": data.DatabaseWebhookDeliveryToWebhookDelivery(delivery)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/data"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/validator"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// Clients that can't send an Authorization header authenticate with
+	// their first message, which must arrive within wsAuthTimeout.
+	wsAuthTimeout = 10 * time.Second
+
+	// Pings are sent every wsPingInterval, and connections that have sent
+	// nothing, not even a pong, for wsPongTimeout are closed.
+	wsPingInterval = 30 * time.Second
+	wsPongTimeout  = 2 * wsPingInterval
+
+	// Clients that don't take a message within wsWriteTimeout are too slow
+	// to keep up, and are disconnected.
+	wsWriteTimeout = 10 * time.Second
+
+	wsMaxMessageSize        = 64 << 10
+	wsMaxConnectionsPerUser = 5
+
+	// Posts are starred one at a time, so star commands take fewer.
+	wsMaxReadPostIDs = 1000
+	wsMaxStarPostIDs = 100
+
+	// Commands are limited to wsCommandRate a second, in bursts of up to
+	// wsCommandBurst. Replies to at most wsReplyBuffer commands wait to be
+	// written, and clients that don't read them are disconnected.
+	wsCommandRate  = 10
+	wsCommandBurst = 20
+	wsReplyBuffer  = 16
+)
+
+// wsCommand is a message from a client.
+type wsCommand struct {
+	Type string `json:"type"`
+	// ID is echoed in the command's reply.
+	ID      string      `json:"id"`
+	Token   string      `json:"token"`
+	PostIDs []uuid.UUID `json:"post_ids"`
+	Read    *bool       `json:"read"`
+	Starred *bool       `json:"starred"`
+}
+
+// wsMessage is a message to a client: a new post, changed post states, or a
+// reply to a command.
+type wsMessage struct {
+	Type    string            `json:"type"`
+	ID      string            `json:"id,omitempty"`
+	Post    *data.Post        `json:"post,omitempty"`
+	States  []data.PostState  `json:"states,omitempty"`
+	Updated *int64            `json:"updated,omitempty"`
+	Error   string            `json:"error,omitempty"`
+	Errors  map[string]string `json:"errors,omitempty"`
+}
+
+// connectionLimiter counts each user's open connections.
+type connectionLimiter struct {
+	mu    sync.Mutex
+	conns map[uuid.UUID]int
+}
+
+// acquire takes one of a user's max connections, reporting whether one was
+// free.
+func (l *connectionLimiter) acquire(userID uuid.UUID, max int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conns == nil {
+		l.conns = make(map[uuid.UUID]int)
+	}
+	if l.conns[userID] >= max {
+		return false
+	}
+	l.conns[userID]++
+
+	return true
+}
+
+func (l *connectionLimiter) release(userID uuid.UUID) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.conns[userID]--
+	if l.conns[userID] <= 0 {
+		delete(l.conns, userID)
+	}
+}
+
+// checkWebSocketOrigin accepts connections from the API's own origin and its
+// trusted CORS origins, and from clients that aren't browsers.
+func (app *application) checkWebSocketOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	return slices.Contains(app.config.cors.trustedOrigins, origin)
+}
+
+// HandlerWebSocket keeps a client in sync over a WebSocket. New posts from
+// followed feeds are pushed as "post" messages and changes to the user's post
+// states, made from any device, as "states" messages. Clients can send
+// "mark_read" and "star" commands, which are answered with an "ack" or an
+// "error" carrying the command's id.
+func (app *application) HandlerWebSocket(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{CheckOrigin: app.checkWebSocketOrigin}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already responded.
+		return
+	}
+	defer conn.Close()
+
+	conn.SetReadLimit(wsMaxMessageSize)
+
+	// closeWith ends the connection, telling the client why.
+	closeWith := func(code int, reason string) {
+		msg := websocket.FormatCloseMessage(code, reason)
+		conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(wsWriteTimeout)) //#nosec G104
+	}
+
+	user := app.contextGetUser(r)
+	if user.IsAnonymous() {
+		user, err = app.authenticateWebSocket(r.Context(), conn)
+		if err != nil {
+			closeWith(websocket.ClosePolicyViolation, err.Error())
+			return
+		}
+	}
+
+	canRead, err := app.userHasPermission(r, user.ID, "posts:read")
+	if err != nil {
+		app.logError(r, err)
+		closeWith(websocket.CloseInternalServerErr, "the server encountered a problem")
+		return
+	}
+	if !user.Activated || !canRead {
+		closeWith(websocket.ClosePolicyViolation, "your user account doesn't have the necessary permissions to access this resource")
+		return
+	}
+	canWrite, err := app.userHasPermission(r, user.ID, "posts:write")
+	if err != nil {
+		app.logError(r, err)
+		closeWith(websocket.CloseInternalServerErr, "the server encountered a problem")
+		return
+	}
+
+	if !app.websockets.acquire(user.ID, wsMaxConnectionsPerUser) {
+		closeWith(websocket.ClosePolicyViolation, "too many connections")
+		return
+	}
+	defer app.websockets.release(user.ID)
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	// Subscribe before the first read, so nothing announced in between is
+	// missed.
+	subscription := app.stream.Subscribe(user.ID)
+	defer subscription.Close()
+
+	postsCursor, err := app.streamCursor(ctx, data.Cursor{})
+	if err != nil {
+		app.logError(r, err)
+		closeWith(websocket.CloseInternalServerErr, "the server encountered a problem")
+		return
+	}
+	// States changed by transactions older than the horizon have all been
+	// committed, and the rest are yet to be synced.
+	horizon, err := app.db.GetStreamHorizon(ctx)
+	if err != nil {
+		app.logError(r, err)
+		closeWith(websocket.CloseInternalServerErr, "the server encountered a problem")
+		return
+	}
+	statesCursor := data.Cursor{Sort: stateChangesSort, Xid: horizon}
+
+	replies := make(chan wsMessage, wsReplyBuffer)
+	go func() {
+		defer cancel()
+		app.readWebSocketCommands(ctx, conn, user.ID, canWrite, replies)
+	}()
+
+	// Everything else is written from here, as connections only allow one
+	// writer at a time.
+	write := func(msg wsMessage) error {
+		err := conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+		if err != nil {
+			return err
+		}
+		return conn.WriteJSON(msg)
+	}
+
+	sendPosts := func() error {
+		postsCursor, err = app.readNewPosts(ctx, user.ID, postsCursor, func(post data.Post, _ data.Cursor) error {
+			return write(wsMessage{Type: "post", Post: &post})
+		})
+		return err
+	}
+	sendStates := func() error {
+		statesCursor, err = app.readStateChanges(ctx, user.ID, statesCursor, func(states []data.PostState) error {
+			return write(wsMessage{Type: "states", States: states})
+		})
+		return err
+	}
+
+	err = write(wsMessage{Type: "ready"})
+
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+
+	for err == nil {
+		select {
+		case <-ctx.Done():
+			// The client has gone, or stopped following the protocol.
+			return
+		case _, ok := <-subscription.C:
+			if !ok {
+				closeWith(websocket.CloseGoingAway, "server shutting down")
+				return
+			}
+			err = sendPosts()
+		case _, ok := <-subscription.States:
+			if !ok {
+				closeWith(websocket.CloseGoingAway, "server shutting down")
+				return
+			}
+			err = sendStates()
+		case reply := <-replies:
+			err = write(reply)
+		case <-ping.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout))
+		}
+	}
+
+	// Connections are closed after any error, which only needs logging if
+	// it wasn't the client going or falling behind.
+	var netErr net.Error
+	if ctx.Err() == nil && !(errors.As(err, &netErr) && netErr.Timeout()) {
+		app.logError(r, err)
+	}
+}
+
+// authenticateWebSocket reads the "auth" message clients without an
+// Authorization header must send first, and returns the user its token is
+// for.
+func (app *application) authenticateWebSocket(ctx context.Context, conn *websocket.Conn) (*data.User, error) {
+	err := conn.SetReadDeadline(time.Now().Add(wsAuthTimeout))
+	if err != nil {
+		return nil, err
+	}
+
+	var cmd wsCommand
+	err = conn.ReadJSON(&cmd)
+	if err != nil || cmd.Type != "auth" {
+		return nil, errors.New("you must be authenticated to access this resource")
+	}
+
+	v := validator.New()
+	if data.ValidateTokenPlaintext(v, cmd.Token); !v.Valid() {
+		return nil, errors.New("invalid or missing authentication token")
+	}
+
+	user, err := data.GetForToken(ctx, data.ScopeActivation, cmd.Token, app.db)
+	if err != nil {
+		return nil, errors.New("invalid or missing authentication token")
+	}
+
+	return &user, nil
+}
+
+// readWebSocketCommands reads and runs commands until the connection fails
+// or ctx is done, handing each reply to the writer through replies.
+func (app *application) readWebSocketCommands(ctx context.Context, conn *websocket.Conn, userID uuid.UUID, canWrite bool, replies chan<- wsMessage) {
+	// Every message, pongs included, shows the client is still there.
+	extend := func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	}
+	conn.SetPongHandler(extend)
+
+	limiter := rate.NewLimiter(wsCommandRate, wsCommandBurst)
+
+	for {
+		if extend("") != nil {
+			return
+		}
+
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var cmd wsCommand
+		err = json.Unmarshal(msg, &cmd)
+		if err != nil {
+			cmd = wsCommand{Type: "invalid"}
+		}
+
+		var reply wsMessage
+		switch {
+		case !limiter.Allow():
+			reply = wsMessage{Type: "error", ID: cmd.ID, Error: "rate limit exceeded"}
+		case !canWrite:
+			reply = wsMessage{Type: "error", ID: cmd.ID, Error: "your user account doesn't have the necessary permissions to access this resource"}
+		default:
+			reply = app.runWebSocketCommand(ctx, userID, cmd)
+		}
+
+		select {
+		case replies <- reply:
+		default:
+			// The client sends commands but doesn't read the replies.
+			return
+		}
+	}
+}
+
+// runWebSocketCommand runs a command and returns its reply.
+func (app *application) runWebSocketCommand(ctx context.Context, userID uuid.UUID, cmd wsCommand) wsMessage {
+	v := validator.New()
+	v.Check(len(cmd.PostIDs) > 0, "post_ids", "must be provided")
+
+	switch cmd.Type {
+	case "mark_read":
+		v.Check(len(cmd.PostIDs) <= wsMaxReadPostIDs, "post_ids", fmt.Sprintf("must not contain more than %d posts", wsMaxReadPostIDs))
+		v.Check(cmd.Read != nil, "read", "must be provided")
+	case "star":
+		v.Check(len(cmd.PostIDs) <= wsMaxStarPostIDs, "post_ids", fmt.Sprintf("must not contain more than %d posts", wsMaxStarPostIDs))
+		v.Check(cmd.Starred != nil, "starred", "must be provided")
+	default:
+		return wsMessage{Type: "error", ID: cmd.ID, Error: "must be a JSON command with a type of mark_read or star"}
+	}
+	if !v.Valid() {
+		return wsMessage{Type: "error", ID: cmd.ID, Errors: v.Errors}
+	}
+
+	now := time.Now().UTC()
+	var updated int64
+
+	switch cmd.Type {
+	case "mark_read":
+		params := database.SetPostsReadStateParams{
+			UserID:    userID,
+			UpdatedAt: now,
+			PostIds:   cmd.PostIDs,
+		}
+		if *cmd.Read {
+			params.ReadAt = sql.NullTime{Time: now, Valid: true}
+		}
+
+		n, err := app.db.SetPostsReadState(ctx, params)
+		if err != nil {
+			app.logger.Error(err.Error(), "command", cmd.Type)
+			return wsMessage{Type: "error", ID: cmd.ID, Error: "the server encountered a problem and could not process your request"}
+		}
+		updated = n
+
+	case "star":
+		for _, postID := range cmd.PostIDs {
+			params := database.SetPostStarredParams{
+				UserID:    userID,
+				UpdatedAt: now,
+				PostID:    postID,
+			}
+			if *cmd.Starred {
+				params.StarredAt = sql.NullTime{Time: now, Valid: true}
+			}
+
+			n, err := app.db.SetPostStarred(ctx, params)
+			if err != nil {
+				app.logger.Error(err.Error(), "command", cmd.Type)
+				return wsMessage{Type: "error", ID: cmd.ID, Error: "the server encountered a problem and could not process your request"}
+			}
+			updated += n
+		}
+	}
+
+	return wsMessage{Type: "ack", ID: cmd.ID, Updated: &updated}
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -12,7 +14,10 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/data"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
 	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/validator"
 	"github.com/google/uuid"
 	"github.com/julienschmidt/httprouter"
@@ -102,7 +107,6 @@ func (app *application) readString(qs url.Values, key string, defaultValue strin
 	return s
 }
 
-/*
 func (app *application) readCSV(qs url.Values, key string, defaultValue []string) []string {
 	csv := qs.Get(key)
 
@@ -112,7 +116,6 @@ func (app *application) readCSV(qs url.Values, key string, defaultValue []string
 
 	return strings.Split(csv, ",")
 }
-*/
 
 func (app *application) readInt(qs url.Values, key string, defaultValue int, v *validator.Validator) int {
 	s := qs.Get(key)
@@ -130,6 +133,82 @@ func (app *application) readInt(qs url.Values, key string, defaultValue int, v *
 	return i
 }
 
+func (app *application) readBool(qs url.Values, key string, defaultValue bool, v *validator.Validator) bool {
+	s := qs.Get(key)
+
+	if s == "" {
+		return defaultValue
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		v.AddError(key, "must be a boolean value")
+		return defaultValue
+	}
+
+	return b
+}
+
+func (app *application) readUUID(qs url.Values, key string, v *validator.Validator) uuid.UUID {
+	s := qs.Get(key)
+
+	if s == "" {
+		return uuid.Nil
+	}
+
+	id, err := uuid.Parse(s)
+	if err != nil {
+		v.AddError(key, "must be a valid UUID")
+		return uuid.Nil
+	}
+
+	return id
+}
+
+// readUUIDs reads a list of UUIDs given either as repeated parameters or
+// comma-separated.
+func (app *application) readUUIDs(qs url.Values, key string, v *validator.Validator) []uuid.UUID {
+	ids := []uuid.UUID{}
+
+	for _, value := range qs[key] {
+		for _, s := range strings.Split(value, ",") {
+			if s == "" {
+				continue
+			}
+
+			id, err := uuid.Parse(s)
+			if err != nil {
+				v.AddError(key, "must contain valid UUIDs")
+				return []uuid.UUID{}
+			}
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+// readTime reads an RFC 3339 timestamp or a date, which is taken as midnight
+// UTC. Missing parameters are returned as the zero time.
+func (app *application) readTime(qs url.Values, key string, v *validator.Validator) time.Time {
+	s := qs.Get(key)
+
+	if s == "" {
+		return time.Time{}
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t, err = time.Parse(time.DateOnly, s)
+	}
+	if err != nil {
+		v.AddError(key, "must be an RFC 3339 timestamp or a date")
+		return time.Time{}
+	}
+
+	return t.UTC()
+}
+
 func (app *application) readFeedFollowIDParam(r *http.Request) (uuid.UUID, error) {
 	params := httprouter.ParamsFromContext(r.Context())
 
@@ -141,6 +220,46 @@ func (app *application) readFeedFollowIDParam(r *http.Request) (uuid.UUID, error
 	return id, nil
 }
 
+func (app *application) readIDParam(r *http.Request, name string) (uuid.UUID, error) {
+	params := httprouter.ParamsFromContext(r.Context())
+
+	id, err := uuid.Parse(params.ByName(name))
+	if err != nil {
+		return uuid.UUID{}, errors.New("invalid id parameter")
+	}
+
+	return id, nil
+}
+
+func (app *application) userHasPermission(r *http.Request, userID uuid.UUID, code string) (bool, error) {
+	permissions, err := app.db.GetAllPermissionsForUser(r.Context(), userID)
+	if err != nil {
+		return false, err
+	}
+
+	return data.Include(permissions, code), nil
+}
+
+// etag returns a strong entity tag for the JSON encoding of v.
+func (app *application) etag(v any) (string, error) {
+	js, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(js)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`, nil
+}
+
+// canManageFeed reports whether user may see a feed's history and change its
+// settings: only the feed's owner and admins can.
+func (app *application) canManageFeed(r *http.Request, user *data.User, feed database.Feed) (bool, error) {
+	if feed.UserID == user.ID {
+		return true, nil
+	}
+	return app.userHasPermission(r, user.ID, "feeds:admin")
+}
+
 func (app *application) background(fn func()) {
 	app.wg.Add(1)
 
@@ -3,6 +3,7 @@ package main
 import (
 	"database/sql"
 	"expvar"
+	"fmt"
 	"log"
 	"log/slog"
 	"os"
@@ -12,10 +13,16 @@ import (
 	"sync"
 	"time"
 
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/alerts"
 	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/digests"
 	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/mailer"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/retention"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/rules"
 	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/scraper"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/stream"
 	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/vcs"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/webhooks"
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
@@ -28,6 +35,7 @@ var (
 type config struct {
 	port    int
 	env     string
+	baseURL string
 	limiter struct {
 		enabled bool
 		rps     float64
@@ -37,6 +45,19 @@ type config struct {
 	cors struct {
 		trustedOrigins []string
 	}
+
+	retention struct {
+		days     int
+		maxPosts int
+	}
+
+	scraper struct {
+		allowPrivate bool
+	}
+
+	webhooks struct {
+		allowPrivate bool
+	}
 }
 
 type application struct {
@@ -44,7 +65,10 @@ type application struct {
 	db     *database.Queries
 	mailer mailer.Mailer
 	logger *slog.Logger
+	stream *stream.Broker
 	wg     sync.WaitGroup
+
+	websockets connectionLimiter
 }
 
 func main() {
@@ -77,6 +101,13 @@ func main() {
 		log.Fatal("Invalid PORT: ", err)
 	}
 
+	// Links in emails point at BASE_URL, which should be the API's public
+	// address.
+	cfg.baseURL = strings.TrimSuffix(os.Getenv("BASE_URL"), "/")
+	if cfg.baseURL == "" {
+		cfg.baseURL = fmt.Sprintf("http://localhost:%d", cfg.port)
+	}
+
 	dbURL := os.Getenv("DB")
 	if dbURL == "" {
 		log.Fatal("DB environment variable is not set")
@@ -139,6 +170,38 @@ func main() {
 
 	cfg.cors.trustedOrigins = strings.Fields(os.Getenv("TRUSTED_ORIGINS"))
 
+	// Posts are kept forever unless a default retention is configured.
+	if retentionDays := os.Getenv("POST_RETENTION_DAYS"); retentionDays != "" {
+		cfg.retention.days, err = strconv.Atoi(retentionDays)
+		if err != nil || cfg.retention.days < 0 {
+			log.Fatal("Invalid POST_RETENTION_DAYS: ", retentionDays)
+		}
+	}
+	if retentionMaxPosts := os.Getenv("POST_RETENTION_MAX_POSTS"); retentionMaxPosts != "" {
+		cfg.retention.maxPosts, err = strconv.Atoi(retentionMaxPosts)
+		if err != nil || cfg.retention.maxPosts < 0 {
+			log.Fatal("Invalid POST_RETENTION_MAX_POSTS: ", retentionMaxPosts)
+		}
+	}
+
+	// Feeds are only fetched from public addresses unless this is set, for
+	// servers that aggregate feeds on the same network.
+	if allowPrivate := os.Getenv("FEEDS_ALLOW_PRIVATE"); allowPrivate != "" {
+		cfg.scraper.allowPrivate, err = strconv.ParseBool(allowPrivate)
+		if err != nil {
+			log.Fatal("Invalid FEEDS_ALLOW_PRIVATE: ", err)
+		}
+	}
+
+	// Webhooks are only sent to public addresses unless this is set, for
+	// servers whose users' receivers are on the same network.
+	if allowPrivate := os.Getenv("WEBHOOKS_ALLOW_PRIVATE"); allowPrivate != "" {
+		cfg.webhooks.allowPrivate, err = strconv.ParseBool(allowPrivate)
+		if err != nil {
+			log.Fatal("Invalid WEBHOOKS_ALLOW_PRIVATE: ", err)
+		}
+	}
+
 	db, err := sql.Open("postgres", dbURL)
 	if err != nil {
 		logger.Error(err.Error())
@@ -148,6 +211,18 @@ func main() {
 
 	dbQueries := database.New(db)
 
+	// Every instance hears about every new post, whichever instance's
+	// scraper collected it.
+	listener, err := stream.Listen(dbURL)
+	if err != nil {
+		logger.Error(err.Error())
+		os.Exit(1)
+	}
+	defer listener.Close()
+
+	broker := stream.NewBroker()
+	go broker.Run(listener.Notify)
+
 	expvar.NewString("version").Set(version)
 
 	expvar.Publish("goroutines", expvar.Func(func() any {
@@ -158,6 +233,19 @@ func main() {
 		return db.Stats()
 	}))
 
+	postPruner := retention.New(dbQueries, retention.Policy{
+		MaxAgeDays: cfg.retention.days,
+		MaxPosts:   cfg.retention.maxPosts,
+	})
+
+	expvar.Publish("posts_pruned", expvar.Func(func() any {
+		return postPruner.Pruned()
+	}))
+
+	expvar.Publish("stream_subscribers", expvar.Func(func() any {
+		return broker.Subscribers()
+	}))
+
 	expvar.Publish("timestamp", expvar.Func(func() any {
 		return time.Now().Unix()
 	}))
@@ -167,13 +255,34 @@ func main() {
 		db:     dbQueries,
 		mailer: mailerClient,
 		logger: logger,
+		stream: broker,
 	}
 
 	const (
 		collectionConcurrency = 10
 		collectionInterval    = time.Minute
+		fetchHistoryRetention = 30 * 24 * time.Hour
+		fetchTimeout          = 10 * time.Second
+		pruneInterval         = time.Hour
+		prunedPostsRetention  = 180 * 24 * time.Hour
+		webhookConcurrency    = 10
+		webhookInterval       = 10 * time.Second
+		webhookLogRetention   = 30 * 24 * time.Hour
+		digestInterval        = time.Minute
+		alertInterval         = 30 * time.Second
 	)
-	go scraper.StartScraping(dbQueries, collectionConcurrency, collectionInterval)
+	webhookDispatcher := webhooks.New(dbQueries, cfg.webhooks.allowPrivate)
+	alerter := alerts.New(dbQueries, mailerClient)
+	feedScraper := scraper.New(dbQueries, scraper.NewHTTPFetcher(fetchTimeout, cfg.scraper.allowPrivate), scraper.RSSParser{})
+	feedScraper.AddHook(rules.New(dbQueries).PostCreated)
+	feedScraper.AddHook(stream.NewPublisher(dbQueries).PostCreated)
+	feedScraper.AddHook(webhookDispatcher.PostCreated)
+	feedScraper.AddHook(alerter.PostCreated)
+	go feedScraper.Start(collectionConcurrency, collectionInterval, fetchHistoryRetention)
+	go postPruner.Start(pruneInterval, prunedPostsRetention)
+	go webhookDispatcher.Start(webhookConcurrency, webhookInterval, webhookLogRetention)
+	go digests.New(dbQueries, mailerClient, cfg.baseURL).Start(digestInterval)
+	go alerter.Start(alertInterval)
 
 	err = app.serve()
 	if err != nil {
@@ -1,13 +1,16 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"database/sql"
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"io"
 	"log/slog"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -20,9 +23,17 @@ import (
 	"testing"
 	"time"
 
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/alerts"
 	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/digests"
 	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/mailer"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/opml"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/retention"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/rules"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/stream"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/webhooks"
 	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
 	smtpmock "github.com/mocktools/go-smtp-mock/v2"
 	"github.com/pressly/goose/v3"
 	"github.com/stretchr/testify/suite"
@@ -132,6 +143,9 @@ func (suite *APITestSuite) SetupSuite() {
 		},
 	}
 
+	// Test webhook receivers listen on localhost.
+	cfg.webhooks.allowPrivate = true
+
 	suite.smtpServer = smtpmock.New(smtpmock.ConfigurationAttr{
 		LogToStdout:       true,
 		LogServerActivity: true,
@@ -155,6 +169,7 @@ func (suite *APITestSuite) SetupSuite() {
 		db:     suite.queries,
 		mailer: mailerClient,
 		logger: logger,
+		stream: stream.NewBroker(),
 	}
 
 	suite.setupTestServer()
@@ -614,8 +629,9 @@ func (suite *APITestSuite) TestPosts() {
 	// For the purpose of this test, we'll manually insert some posts into the database
 	// In a real scenario, these would be created by the feed scraper
 	for i := 0; i < 15; i++ {
+		postID := uuid.New()
 		_, err := suite.app.db.CreatePost(context.Background(), database.CreatePostParams{
-			ID:        uuid.New(),
+			ID:        postID,
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
 			Title:     fmt.Sprintf("Test Post %d", i+1),
@@ -628,7 +644,8 @@ func (suite *APITestSuite) TestPosts() {
 				Time:  time.Now(),
 				Valid: true,
 			},
-			FeedID: createFeedResponse.Feed.Feed.ID,
+			FeedID:    createFeedResponse.Feed.Feed.ID,
+			ClusterID: postID,
 		})
 		suite.Require().NoError(err)
 	}
@@ -708,6 +725,2370 @@ func (suite *APITestSuite) TestPosts() {
 			"Posts should be sorted by published_at in descending order")
 	}
 }
+
+func (suite *APITestSuite) TestFeedFetches() {
+	feedID := suite.createFeed("Test Feed for Fetches", "http://example.com/rss/feed4.xml")
+
+	// Record a successful and a failed fetch as the scraper would
+	startedAt := time.Now().UTC().Add(-time.Minute)
+	_, err := suite.app.db.CreateFeedFetch(context.Background(), database.CreateFeedFetchParams{
+		ID:         uuid.New(),
+		FeedID:     feedID,
+		StartedAt:  startedAt,
+		FinishedAt: startedAt.Add(time.Second),
+		StatusCode: sql.NullInt32{Int32: http.StatusOK, Valid: true},
+		Bytes:      2048,
+		ItemsSeen:  10,
+		ItemsNew:   3,
+	})
+	suite.Require().NoError(err)
+
+	_, err = suite.app.db.CreateFeedFetch(context.Background(), database.CreateFeedFetchParams{
+		ID:         uuid.New(),
+		FeedID:     feedID,
+		StartedAt:  startedAt.Add(30 * time.Second),
+		FinishedAt: startedAt.Add(31 * time.Second),
+		StatusCode: sql.NullInt32{Int32: http.StatusBadGateway, Valid: true},
+		Error:      sql.NullString{String: "unexpected status code 502", Valid: true},
+	})
+	suite.Require().NoError(err)
+
+	resp, err := suite.authenticatedClient.Get(fmt.Sprintf("%s/v1/feeds/%s/fetches", suite.server.URL, feedID))
+	suite.Require().NoError(err)
+	defer resp.Body.Close()
+
+	suite.Require().Equal(http.StatusOK, resp.StatusCode, "Failed to get feed fetches")
+
+	var getFetchesResponse struct {
+		Fetches []struct {
+			StatusCode *int    `json:"status_code"`
+			ItemsNew   int     `json:"items_new"`
+			Error      *string `json:"error"`
+		} `json:"Fetches"`
+		Metadata struct {
+			TotalRecords int `json:"total_records"`
+		} `json:"Metadata"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&getFetchesResponse)
+	suite.Require().NoError(err)
+
+	suite.Require().Equal(2, getFetchesResponse.Metadata.TotalRecords)
+	suite.Require().Len(getFetchesResponse.Fetches, 2)
+
+	// The most recent fetch is returned first
+	suite.Require().Equal(http.StatusBadGateway, *getFetchesResponse.Fetches[0].StatusCode)
+	suite.Require().NotNil(getFetchesResponse.Fetches[0].Error)
+	suite.Require().Equal(3, getFetchesResponse.Fetches[1].ItemsNew)
+	suite.Require().Nil(getFetchesResponse.Fetches[1].Error)
+
+	// Fetch history of an unknown feed is not found
+	resp, err = suite.authenticatedClient.Get(fmt.Sprintf("%s/v1/feeds/%s/fetches", suite.server.URL, uuid.New()))
+	suite.Require().NoError(err)
+	defer resp.Body.Close()
+
+	suite.Require().Equal(http.StatusNotFound, resp.StatusCode)
+}
+
+// createFeed creates a feed through the API as the authenticated user, which
+// also follows it, and returns the new feed's ID.
+func (suite *APITestSuite) createFeed(name, feedURL string) uuid.UUID {
+	body := fmt.Sprintf(`{"name":"%s","url":"%s"}`, name, feedURL)
+	resp, err := suite.authenticatedClient.Post(suite.server.URL+"/v1/feeds", "application/json", strings.NewReader(body))
+	suite.Require().NoError(err)
+	defer resp.Body.Close()
+
+	suite.Require().Equal(http.StatusOK, resp.StatusCode, "Failed to create feed")
+
+	var createFeedResponse struct {
+		Feed struct {
+			Feed struct {
+				ID uuid.UUID `json:"id"`
+			} `json:"feed"`
+		} `json:"Feed"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&createFeedResponse)
+	suite.Require().NoError(err)
+
+	return createFeedResponse.Feed.Feed.ID
+}
+
+// createPost inserts a post directly, as the scraper would.
+func (suite *APITestSuite) createPost(feedID uuid.UUID, title, postURL string) uuid.UUID {
+	return suite.createPostInCluster(feedID, title, postURL, uuid.Nil)
+}
+
+// createPostInCluster inserts a post into an existing cluster, or into a new
+// cluster of its own when clusterID is uuid.Nil.
+func (suite *APITestSuite) createPostInCluster(feedID uuid.UUID, title, postURL string, clusterID uuid.UUID) uuid.UUID {
+	postID := uuid.New()
+	if clusterID == uuid.Nil {
+		clusterID = postID
+	}
+
+	post, err := suite.app.db.CreatePost(context.Background(), database.CreatePostParams{
+		ID:        postID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Title:     title,
+		Url:       postURL,
+		PublishedAt: sql.NullTime{
+			Time:  time.Now(),
+			Valid: true,
+		},
+		FeedID:    feedID,
+		ClusterID: clusterID,
+	})
+	suite.Require().NoError(err)
+
+	return post.ID
+}
+
+func (suite *APITestSuite) TestPostsCollapseDuplicates() {
+	feedA := suite.createFeed("Syndicating Feed A", "http://example.com/rss/feed5.xml")
+	feedB := suite.createFeed("Syndicating Feed B", "http://example.com/rss/feed6.xml")
+
+	// The same article published by both feeds, plus one unique to feed B.
+	// The scraper puts copies of the same URL in the same cluster.
+	clusterID := suite.createPost(feedA, "Syndicated Post", "https://example.com/syndicated")
+	suite.createPostInCluster(feedB, "Syndicated Post", "https://example.com/syndicated", clusterID)
+	suite.createPost(feedB, "Unique Post", "https://example.com/unique")
+
+	// A reworded copy of the story under another URL is clustered as a
+	// near-duplicate
+	suite.createPostInCluster(feedB, "BREAKING: Syndicated Post", "https://example.com/wire/syndicated", clusterID)
+
+	var getPostsResponse struct {
+		Posts []struct {
+			Url            string    `json:"url"`
+			FeedID         uuid.UUID `json:"feedid"`
+			ClusterID      uuid.UUID `json:"cluster_id"`
+			DuplicateCount int       `json:"duplicate_count"`
+		} `json:"Posts"`
+		Metadata struct {
+			TotalRecords int `json:"total_records"`
+		} `json:"Metadata"`
+	}
+
+	resp, err := suite.authenticatedClient.Get(suite.server.URL + "/v1/posts")
+	suite.Require().NoError(err)
+	defer resp.Body.Close()
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	err = json.NewDecoder(resp.Body).Decode(&getPostsResponse)
+	suite.Require().NoError(err)
+	suite.Require().Len(getPostsResponse.Posts, 4)
+
+	resp, err = suite.authenticatedClient.Get(suite.server.URL + "/v1/posts?collapse=true")
+	suite.Require().NoError(err)
+	defer resp.Body.Close()
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	getPostsResponse.Posts = nil
+	err = json.NewDecoder(resp.Body).Decode(&getPostsResponse)
+	suite.Require().NoError(err)
+	suite.Require().Len(getPostsResponse.Posts, 2, "Copies of a story should collapse into one post")
+
+	for _, post := range getPostsResponse.Posts {
+		if post.ClusterID == clusterID {
+			suite.Require().Equal("https://example.com/syndicated", post.Url)
+			suite.Require().Equal(feedA, post.FeedID, "The earliest copy should be kept")
+			suite.Require().Equal(2, post.DuplicateCount)
+		}
+	}
+	suite.Require().Equal(2, getPostsResponse.Metadata.TotalRecords)
+
+	// A story whose earliest copy is filtered out is shown by the earliest
+	// copy that's left
+	req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("%s/v1/posts/%s/read", suite.server.URL, clusterID), nil)
+	suite.Require().NoError(err)
+	resp, err = suite.authenticatedClient.Do(req)
+	suite.Require().NoError(err)
+	defer resp.Body.Close()
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	resp, err = suite.authenticatedClient.Get(suite.server.URL + "/v1/posts?collapse=true&status=unread")
+	suite.Require().NoError(err)
+	defer resp.Body.Close()
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	getPostsResponse.Posts = nil
+	err = json.NewDecoder(resp.Body).Decode(&getPostsResponse)
+	suite.Require().NoError(err)
+	suite.Require().Len(getPostsResponse.Posts, 2)
+	suite.Require().Equal(2, getPostsResponse.Metadata.TotalRecords)
+	for _, post := range getPostsResponse.Posts {
+		if post.ClusterID == clusterID {
+			suite.Require().Equal(feedB, post.FeedID)
+		}
+	}
+
+	// An invalid collapse value is rejected
+	resp, err = suite.authenticatedClient.Get(suite.server.URL + "/v1/posts?collapse=maybe")
+	suite.Require().NoError(err)
+	defer resp.Body.Close()
+	suite.Require().Equal(http.StatusUnprocessableEntity, resp.StatusCode)
+}
+
+func (suite *APITestSuite) TestPostRetention() {
+	feedID := suite.createFeed("Test Feed for Retention", "http://example.com/rss/feed7.xml")
+
+	suite.createPost(feedID, "Oldest Post", "https://example.com/retention/1")
+	suite.createPost(feedID, "Older Post", "https://example.com/retention/2")
+	suite.createPost(feedID, "Newest Post", "https://example.com/retention/3")
+
+	retentionURL := fmt.Sprintf("%s/v1/feeds/%s/retention", suite.server.URL, feedID)
+	req, err := http.NewRequest(http.MethodPut, retentionURL, strings.NewReader(`{"retention_max_posts":2}`))
+	suite.Require().NoError(err)
+	resp, err := suite.authenticatedClient.Do(req)
+	suite.Require().NoError(err)
+	defer resp.Body.Close()
+
+	suite.Require().Equal(http.StatusOK, resp.StatusCode, "Failed to update feed retention")
+
+	var updateResponse struct {
+		Feed struct {
+			RetentionDays     *int `json:"retention_days"`
+			RetentionMaxPosts *int `json:"retention_max_posts"`
+		} `json:"Feed"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&updateResponse)
+	suite.Require().NoError(err)
+	suite.Require().Nil(updateResponse.Feed.RetentionDays)
+	suite.Require().NotNil(updateResponse.Feed.RetentionMaxPosts)
+	suite.Require().Equal(2, *updateResponse.Feed.RetentionMaxPosts)
+
+	// The feed's override applies even though the default keeps posts forever
+	pruned, err := retention.New(suite.app.db, retention.Policy{}).PruneOnce(context.Background())
+	suite.Require().NoError(err)
+	suite.Require().Equal(int64(1), pruned)
+
+	resp, err = suite.authenticatedClient.Get(fmt.Sprintf("%s/v1/posts?feed_id=%s", suite.server.URL, feedID))
+	suite.Require().NoError(err)
+	defer resp.Body.Close()
+
+	var getPostsResponse struct {
+		Posts []struct {
+			Title string `json:"title"`
+		} `json:"Posts"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&getPostsResponse)
+	suite.Require().NoError(err)
+	suite.Require().Len(getPostsResponse.Posts, 2)
+	for _, post := range getPostsResponse.Posts {
+		suite.Require().NotEqual("Oldest Post", post.Title)
+	}
+
+	// A pruned post isn't collected again
+	_, err = suite.app.db.CreatePost(context.Background(), database.CreatePostParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Title:     "Oldest Post",
+		Url:       "https://example.com/retention/1",
+		FeedID:    feedID,
+		ClusterID: uuid.New(),
+	})
+	suite.Require().ErrorIs(err, sql.ErrNoRows)
+
+	// Negative limits are rejected
+	req, err = http.NewRequest(http.MethodPut, retentionURL, strings.NewReader(`{"retention_days":-1}`))
+	suite.Require().NoError(err)
+	resp, err = suite.authenticatedClient.Do(req)
+	suite.Require().NoError(err)
+	defer resp.Body.Close()
+
+	suite.Require().Equal(http.StatusUnprocessableEntity, resp.StatusCode)
+}
+
+func (suite *APITestSuite) TestPostReadState() {
+	feedA := suite.createFeed("Test Feed for Read State A", "http://example.com/rss/feed8.xml")
+	feedB := suite.createFeed("Test Feed for Read State B", "http://example.com/rss/feed9.xml")
+
+	postA1 := suite.createPost(feedA, "Read State A1", "https://example.com/read/a1")
+	postA2 := suite.createPost(feedA, "Read State A2", "https://example.com/read/a2")
+	postB1 := suite.createPost(feedB, "Read State B1", "https://example.com/read/b1")
+
+	getPosts := func(query string) []uuid.UUID {
+		resp, err := suite.authenticatedClient.Get(suite.server.URL + "/v1/posts?" + query)
+		suite.Require().NoError(err)
+		defer resp.Body.Close()
+		suite.Require().Equal(http.StatusOK, resp.StatusCode)
+
+		var getPostsResponse struct {
+			Posts []struct {
+				ID uuid.UUID `json:"id"`
+			} `json:"Posts"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&getPostsResponse)
+		suite.Require().NoError(err)
+
+		var ids []uuid.UUID
+		for _, post := range getPostsResponse.Posts {
+			ids = append(ids, post.ID)
+		}
+		return ids
+	}
+
+	send := func(method, path, body string) int {
+		req, err := http.NewRequest(method, suite.server.URL+path, strings.NewReader(body))
+		suite.Require().NoError(err)
+		resp, err := suite.authenticatedClient.Do(req)
+		suite.Require().NoError(err)
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Mark a single post read
+	suite.Require().Equal(http.StatusOK, send(http.MethodPut, fmt.Sprintf("/v1/posts/%s/read", postA1), ""))
+	suite.Require().Equal([]uuid.UUID{postA1}, getPosts("status=read"))
+	suite.Require().NotContains(getPosts("status=unread"), postA1)
+
+	// And unread again
+	suite.Require().Equal(http.StatusOK, send(http.MethodDelete, fmt.Sprintf("/v1/posts/%s/read", postA1), ""))
+	suite.Require().Empty(getPosts("status=read"))
+
+	// Unknown posts are not found
+	suite.Require().Equal(http.StatusNotFound, send(http.MethodPut, fmt.Sprintf("/v1/posts/%s/read", uuid.New()), ""))
+
+	// Mark a batch read
+	body := fmt.Sprintf(`{"post_ids":["%s","%s"],"read":true}`, postA1, postB1)
+	suite.Require().Equal(http.StatusOK, send(http.MethodPatch, "/v1/post_states", body))
+	suite.Require().ElementsMatch([]uuid.UUID{postA1, postB1}, getPosts("status=read"))
+
+	// Marking a read post read again keeps when it was first read
+	var firstReadAt time.Time
+	err := suite.tx.QueryRow("SELECT read_at FROM post_states WHERE post_id = $1", postA1).Scan(&firstReadAt)
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.StatusOK, send(http.MethodPut, fmt.Sprintf("/v1/posts/%s/read", postA1), ""))
+	var readAt time.Time
+	err = suite.tx.QueryRow("SELECT read_at FROM post_states WHERE post_id = $1", postA1).Scan(&readAt)
+	suite.Require().NoError(err)
+	suite.Require().True(firstReadAt.Equal(readAt))
+
+	// Batches must say whether posts are read
+	body = fmt.Sprintf(`{"post_ids":["%s"]}`, postA1)
+	suite.Require().Equal(http.StatusUnprocessableEntity, send(http.MethodPatch, "/v1/post_states", body))
+
+	// Mark everything in feed A read
+	body = fmt.Sprintf(`{"feed_id":"%s"}`, feedA)
+	suite.Require().Equal(http.StatusOK, send(http.MethodPost, "/v1/post_states/mark_read", body))
+	suite.Require().Contains(getPosts("status=read"), postA2)
+
+	// Posts collected after the cut-off stay unread
+	postB2 := suite.createPost(feedB, "Read State B2", "https://example.com/read/b2")
+	body = fmt.Sprintf(`{"before":"%s"}`, time.Now().UTC().Add(-time.Hour).Format(time.RFC3339))
+	suite.Require().Equal(http.StatusOK, send(http.MethodPost, "/v1/post_states/mark_read", body))
+	suite.Require().Contains(getPosts("status=unread"), postB2)
+
+	suite.Require().Equal(http.StatusOK, send(http.MethodPost, "/v1/post_states/mark_read", `{}`))
+	suite.Require().Empty(getPosts("status=unread"))
+
+	// An invalid status is rejected
+	resp, err := suite.authenticatedClient.Get(suite.server.URL + "/v1/posts?status=skimmed")
+	suite.Require().NoError(err)
+	defer resp.Body.Close()
+	suite.Require().Equal(http.StatusUnprocessableEntity, resp.StatusCode)
+}
+
+func (suite *APITestSuite) TestPostStars() {
+	feedID := suite.createFeed("Test Feed for Stars", "http://example.com/rss/feed10.xml")
+
+	oldest := suite.createPost(feedID, "Starred Post", "https://example.com/stars/1")
+	older := suite.createPost(feedID, "Older Post", "https://example.com/stars/2")
+	newest := suite.createPost(feedID, "Newest Post", "https://example.com/stars/3")
+
+	send := func(method, path, body string) int {
+		req, err := http.NewRequest(method, suite.server.URL+path, strings.NewReader(body))
+		suite.Require().NoError(err)
+		resp, err := suite.authenticatedClient.Do(req)
+		suite.Require().NoError(err)
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	getStarred := func() []uuid.UUID {
+		resp, err := suite.authenticatedClient.Get(suite.server.URL + "/v1/posts?starred=true")
+		suite.Require().NoError(err)
+		defer resp.Body.Close()
+		suite.Require().Equal(http.StatusOK, resp.StatusCode)
+
+		var getPostsResponse struct {
+			Posts []struct {
+				ID        uuid.UUID  `json:"id"`
+				StarredAt *time.Time `json:"starred_at"`
+			} `json:"Posts"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&getPostsResponse)
+		suite.Require().NoError(err)
+
+		var ids []uuid.UUID
+		for _, post := range getPostsResponse.Posts {
+			suite.Require().NotNil(post.StarredAt)
+			ids = append(ids, post.ID)
+		}
+		return ids
+	}
+
+	suite.Require().Equal(http.StatusOK, send(http.MethodPut, fmt.Sprintf("/v1/posts/%s/star", oldest), ""))
+	suite.Require().Equal(http.StatusOK, send(http.MethodPut, fmt.Sprintf("/v1/posts/%s/star", older), ""))
+	suite.Require().ElementsMatch([]uuid.UUID{oldest, older}, getStarred())
+
+	suite.Require().Equal(http.StatusOK, send(http.MethodDelete, fmt.Sprintf("/v1/posts/%s/star", older), ""))
+	suite.Require().Equal([]uuid.UUID{oldest}, getStarred())
+
+	suite.Require().Equal(http.StatusNotFound, send(http.MethodPut, fmt.Sprintf("/v1/posts/%s/star", uuid.New()), ""))
+
+	// Keeping one post prunes the older unstarred post but not the starred one
+	retentionURL := fmt.Sprintf("/v1/feeds/%s/retention", feedID)
+	suite.Require().Equal(http.StatusOK, send(http.MethodPut, retentionURL, `{"retention_max_posts":1}`))
+
+	pruned, err := retention.New(suite.app.db, retention.Policy{}).PruneOnce(context.Background())
+	suite.Require().NoError(err)
+	suite.Require().Equal(int64(1), pruned)
+
+	resp, err := suite.authenticatedClient.Get(fmt.Sprintf("%s/v1/posts?feed_id=%s", suite.server.URL, feedID))
+	suite.Require().NoError(err)
+	defer resp.Body.Close()
+
+	var getPostsResponse struct {
+		Posts []struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"Posts"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&getPostsResponse)
+	suite.Require().NoError(err)
+
+	var remaining []uuid.UUID
+	for _, post := range getPostsResponse.Posts {
+		remaining = append(remaining, post.ID)
+	}
+	suite.Require().ElementsMatch([]uuid.UUID{oldest, newest}, remaining)
+}
+
+func (suite *APITestSuite) TestFeedFollowCounts() {
+	feedA := suite.createFeed("Test Feed for Counts A", "http://example.com/rss/feed11.xml")
+	feedB := suite.createFeed("Test Feed for Counts B", "http://example.com/rss/feed12.xml")
+
+	postA1 := suite.createPost(feedA, "Counts A1", "https://example.com/counts/a1")
+	suite.createPost(feedA, "Counts A2", "https://example.com/counts/a2")
+
+	// Hidden posts aren't counted at all
+	hidden := suite.createPost(feedA, "Counts A3", "https://example.com/counts/a3")
+	_, err := suite.tx.Exec(`INSERT INTO post_states (user_id, post_id, hidden_at, updated_at)
+		SELECT user_id, $1, now(), now() FROM feed_follows WHERE feed_id = $2`, hidden, feedA)
+	suite.Require().NoError(err)
+
+	req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("%s/v1/posts/%s/read", suite.server.URL, postA1), nil)
+	suite.Require().NoError(err)
+	resp, err := suite.authenticatedClient.Do(req)
+	suite.Require().NoError(err)
+	defer resp.Body.Close()
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	resp, err = suite.authenticatedClient.Get(suite.server.URL + "/v1/feed_follows/counts")
+	suite.Require().NoError(err)
+	defer resp.Body.Close()
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	etag := resp.Header.Get("ETag")
+	suite.Require().NotEmpty(etag)
+
+	var countsResponse struct {
+		Counts []struct {
+			FeedID uuid.UUID `json:"feedid"`
+			Total  int       `json:"total"`
+			Unread int       `json:"unread"`
+		} `json:"Counts"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&countsResponse)
+	suite.Require().NoError(err)
+
+	counts := make(map[uuid.UUID][2]int)
+	for _, count := range countsResponse.Counts {
+		counts[count.FeedID] = [2]int{count.Total, count.Unread}
+	}
+	suite.Require().Equal([2]int{2, 1}, counts[feedA])
+	suite.Require().Equal([2]int{0, 0}, counts[feedB], "Feeds without posts should be included")
+
+	// Unchanged counts aren't sent again
+	req, err = http.NewRequest(http.MethodGet, suite.server.URL+"/v1/feed_follows/counts", nil)
+	suite.Require().NoError(err)
+	req.Header.Set("If-None-Match", etag)
+	resp, err = suite.authenticatedClient.Do(req)
+	suite.Require().NoError(err)
+	defer resp.Body.Close()
+	suite.Require().Equal(http.StatusNotModified, resp.StatusCode)
+
+	suite.createPost(feedB, "Counts B1", "https://example.com/counts/b1")
+
+	resp, err = suite.authenticatedClient.Do(req)
+	suite.Require().NoError(err)
+	defer resp.Body.Close()
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+	suite.Require().NotEqual(etag, resp.Header.Get("ETag"))
+}
+
+func (suite *APITestSuite) TestFolders() {
+	feedA := suite.createFeed("Test Feed for Folders A", "http://example.com/rss/feed13.xml")
+	feedB := suite.createFeed("Test Feed for Folders B", "http://example.com/rss/feed14.xml")
+
+	postA := suite.createPost(feedA, "Folders A1", "https://example.com/folders/a1")
+	suite.createPost(feedB, "Folders B1", "https://example.com/folders/b1")
+
+	send := func(method, path, body string, dst any) int {
+		req, err := http.NewRequest(method, suite.server.URL+path, strings.NewReader(body))
+		suite.Require().NoError(err)
+		resp, err := suite.authenticatedClient.Do(req)
+		suite.Require().NoError(err)
+		defer resp.Body.Close()
+		if dst != nil && resp.StatusCode < 300 {
+			suite.Require().NoError(json.NewDecoder(resp.Body).Decode(dst))
+		}
+		return resp.StatusCode
+	}
+
+	type folder struct {
+		ID       uuid.UUID `json:"id"`
+		Name     string    `json:"name"`
+		Position int       `json:"position"`
+	}
+	createFolder := func(name string) folder {
+		var response struct {
+			Folder folder `json:"Folder"`
+		}
+		suite.Require().Equal(http.StatusCreated, send(http.MethodPost, "/v1/folders", fmt.Sprintf(`{"name":"%s"}`, name), &response))
+		return response.Folder
+	}
+
+	eng := createFolder("Eng")
+	news := createFolder("News")
+	suite.Require().Equal(0, eng.Position)
+	suite.Require().Equal(1, news.Position)
+
+	// Names are unique per user
+	suite.Require().Equal(http.StatusUnprocessableEntity, send(http.MethodPost, "/v1/folders", `{"name":"Eng"}`, nil))
+
+	// Reorder
+	var foldersResponse struct {
+		Folders []folder `json:"Folders"`
+	}
+	body := fmt.Sprintf(`{"folder_ids":["%s","%s"]}`, news.ID, eng.ID)
+	suite.Require().Equal(http.StatusOK, send(http.MethodPut, "/v1/folders/order", body, &foldersResponse))
+	suite.Require().Len(foldersResponse.Folders, 2)
+	suite.Require().Equal(news.ID, foldersResponse.Folders[0].ID)
+	suite.Require().Equal(eng.ID, foldersResponse.Folders[1].ID)
+
+	body = fmt.Sprintf(`{"folder_ids":["%s"]}`, news.ID)
+	suite.Require().Equal(http.StatusUnprocessableEntity, send(http.MethodPut, "/v1/folders/order", body, nil), "Every folder must be listed")
+
+	// Rename
+	var folderResponse struct {
+		Folder folder `json:"Folder"`
+	}
+	suite.Require().Equal(http.StatusOK, send(http.MethodPatch, fmt.Sprintf("/v1/folders/%s", eng.ID), `{"name":"Engineering"}`, &folderResponse))
+	suite.Require().Equal("Engineering", folderResponse.Folder.Name)
+
+	// File feed A under both folders
+	var followsResponse struct {
+		FeedFollows []struct {
+			ID        uuid.UUID   `json:"id"`
+			FeedID    uuid.UUID   `json:"feedid"`
+			FolderIDs []uuid.UUID `json:"folder_ids"`
+		} `json:"feed follows"`
+	}
+	suite.Require().Equal(http.StatusOK, send(http.MethodGet, "/v1/feed_follows", "", &followsResponse))
+	var followA uuid.UUID
+	for _, follow := range followsResponse.FeedFollows {
+		suite.Require().Empty(follow.FolderIDs)
+		if follow.FeedID == feedA {
+			followA = follow.ID
+		}
+	}
+	suite.Require().NotEqual(uuid.Nil, followA)
+
+	var setResponse struct {
+		FeedFollow struct {
+			FolderIDs []uuid.UUID `json:"folder_ids"`
+		} `json:"FeedFollow"`
+	}
+	body = fmt.Sprintf(`{"folder_ids":["%s","%s"]}`, eng.ID, news.ID)
+	suite.Require().Equal(http.StatusOK, send(http.MethodPut, fmt.Sprintf("/v1/feed_follows/%s/folders", followA), body, &setResponse))
+	suite.Require().ElementsMatch([]uuid.UUID{eng.ID, news.ID}, setResponse.FeedFollow.FolderIDs)
+
+	body = fmt.Sprintf(`{"folder_ids":["%s"]}`, uuid.New())
+	suite.Require().Equal(http.StatusUnprocessableEntity, send(http.MethodPut, fmt.Sprintf("/v1/feed_follows/%s/folders", followA), body, nil))
+
+	body = fmt.Sprintf(`{"folder_ids":["%s","%s"]}`, eng.ID, eng.ID)
+	suite.Require().Equal(http.StatusUnprocessableEntity, send(http.MethodPut, fmt.Sprintf("/v1/feed_follows/%s/folders", followA), body, nil))
+
+	suite.Require().Equal(http.StatusOK, send(http.MethodGet, "/v1/feed_follows", "", &followsResponse))
+	for _, follow := range followsResponse.FeedFollows {
+		if follow.ID == followA {
+			suite.Require().ElementsMatch([]uuid.UUID{eng.ID, news.ID}, follow.FolderIDs)
+		}
+	}
+
+	// Updating a follow keeps its folders
+	var updateResponse struct {
+		FeedFollow struct {
+			FolderIDs []uuid.UUID `json:"folder_ids"`
+		} `json:"FeedFollow"`
+	}
+	suite.Require().Equal(http.StatusOK, send(http.MethodPatch, fmt.Sprintf("/v1/feed_follows/%s", followA), `{"muted":false}`, &updateResponse))
+	suite.Require().ElementsMatch([]uuid.UUID{eng.ID, news.ID}, updateResponse.FeedFollow.FolderIDs)
+
+	// Only feed A's posts are in the folder
+	var postsResponse struct {
+		Posts []struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"Posts"`
+	}
+	suite.Require().Equal(http.StatusOK, send(http.MethodGet, fmt.Sprintf("/v1/posts?folder_id=%s", eng.ID), "", &postsResponse))
+	suite.Require().Len(postsResponse.Posts, 1)
+	suite.Require().Equal(postA, postsResponse.Posts[0].ID)
+
+	// Folder counts
+	var countsResponse struct {
+		Folders []struct {
+			FolderID uuid.UUID `json:"folder_id"`
+			Total    int       `json:"total"`
+			Unread   int       `json:"unread"`
+		} `json:"Folders"`
+	}
+	suite.Require().Equal(http.StatusOK, send(http.MethodGet, "/v1/feed_follows/counts", "", &countsResponse))
+	suite.Require().Len(countsResponse.Folders, 2)
+	for _, count := range countsResponse.Folders {
+		suite.Require().Equal(1, count.Total)
+		suite.Require().Equal(1, count.Unread)
+	}
+
+	// Deleting a folder removes it from follows
+	suite.Require().Equal(http.StatusOK, send(http.MethodDelete, fmt.Sprintf("/v1/folders/%s", news.ID), "", nil))
+	suite.Require().Equal(http.StatusNotFound, send(http.MethodDelete, fmt.Sprintf("/v1/folders/%s", news.ID), "", nil))
+
+	suite.Require().Equal(http.StatusOK, send(http.MethodGet, "/v1/feed_follows", "", &followsResponse))
+	for _, follow := range followsResponse.FeedFollows {
+		if follow.ID == followA {
+			suite.Require().Equal([]uuid.UUID{eng.ID}, follow.FolderIDs)
+		}
+	}
+}
+
+func (suite *APITestSuite) TestFeedFollowSettings() {
+	feedA := suite.createFeed("Test Feed for Settings A", "http://example.com/rss/feed15.xml")
+	feedB := suite.createFeed("Test Feed for Settings B", "http://example.com/rss/feed16.xml")
+
+	suite.createPost(feedA, "Settings A1", "https://example.com/settings/a1")
+	postB := suite.createPost(feedB, "Settings B1", "https://example.com/settings/b1")
+
+	send := func(method, path, body string, dst any) int {
+		req, err := http.NewRequest(method, suite.server.URL+path, strings.NewReader(body))
+		suite.Require().NoError(err)
+		resp, err := suite.authenticatedClient.Do(req)
+		suite.Require().NoError(err)
+		defer resp.Body.Close()
+		if dst != nil && resp.StatusCode < 300 {
+			suite.Require().NoError(json.NewDecoder(resp.Body).Decode(dst))
+		}
+		return resp.StatusCode
+	}
+
+	type feedFollow struct {
+		ID       uuid.UUID `json:"id"`
+		FeedID   uuid.UUID `json:"feedid"`
+		Title    *string   `json:"title"`
+		Priority int       `json:"priority"`
+		Muted    bool      `json:"muted"`
+		Notify   bool      `json:"notify"`
+	}
+
+	var followsResponse struct {
+		FeedFollows []feedFollow `json:"feed follows"`
+	}
+	suite.Require().Equal(http.StatusOK, send(http.MethodGet, "/v1/feed_follows", "", &followsResponse))
+	var followA uuid.UUID
+	for _, follow := range followsResponse.FeedFollows {
+		suite.Require().Nil(follow.Title)
+		suite.Require().True(follow.Notify)
+		if follow.FeedID == feedA {
+			followA = follow.ID
+		}
+	}
+
+	var updateResponse struct {
+		FeedFollow feedFollow `json:"FeedFollow"`
+	}
+	body := `{"title":"My Feed","priority":5,"muted":true,"notify":false}`
+	suite.Require().Equal(http.StatusOK, send(http.MethodPatch, fmt.Sprintf("/v1/feed_follows/%s", followA), body, &updateResponse))
+	suite.Require().NotNil(updateResponse.FeedFollow.Title)
+	suite.Require().Equal("My Feed", *updateResponse.FeedFollow.Title)
+	suite.Require().Equal(5, updateResponse.FeedFollow.Priority)
+	suite.Require().True(updateResponse.FeedFollow.Muted)
+	suite.Require().False(updateResponse.FeedFollow.Notify)
+
+	// Follows are listed by priority
+	suite.Require().Equal(http.StatusOK, send(http.MethodGet, "/v1/feed_follows", "", &followsResponse))
+	suite.Require().Equal(followA, followsResponse.FeedFollows[0].ID)
+
+	// Muted feeds are left out of the river unless asked for
+	var postsResponse struct {
+		Posts []struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"Posts"`
+	}
+	suite.Require().Equal(http.StatusOK, send(http.MethodGet, "/v1/posts", "", &postsResponse))
+	suite.Require().Len(postsResponse.Posts, 1)
+	suite.Require().Equal(postB, postsResponse.Posts[0].ID)
+
+	postsResponse.Posts = nil
+	suite.Require().Equal(http.StatusOK, send(http.MethodGet, fmt.Sprintf("/v1/posts?feed_id=%s", feedA), "", &postsResponse))
+	suite.Require().Len(postsResponse.Posts, 1)
+
+	// Only the fields sent are changed, and an empty title clears it
+	suite.Require().Equal(http.StatusOK, send(http.MethodPatch, fmt.Sprintf("/v1/feed_follows/%s", followA), `{"title":""}`, &updateResponse))
+	suite.Require().Nil(updateResponse.FeedFollow.Title)
+	suite.Require().Equal(5, updateResponse.FeedFollow.Priority)
+	suite.Require().True(updateResponse.FeedFollow.Muted)
+
+	suite.Require().Equal(http.StatusUnprocessableEntity, send(http.MethodPatch, fmt.Sprintf("/v1/feed_follows/%s", followA), `{"priority":1000}`, nil))
+	suite.Require().Equal(http.StatusNotFound, send(http.MethodPatch, fmt.Sprintf("/v1/feed_follows/%s", uuid.New()), `{"muted":false}`, nil))
+}
+
+func (suite *APITestSuite) TestPostTags() {
+	feedID := suite.createFeed("Test Feed for Tags", "http://example.com/rss/feed17.xml")
+
+	postGo := suite.createPost(feedID, "Tags Go", "https://example.com/tags/go")
+	postBoth := suite.createPost(feedID, "Tags Both", "https://example.com/tags/both")
+	postNone := suite.createPost(feedID, "Tags None", "https://example.com/tags/none")
+
+	send := func(method, path, body string, dst any) int {
+		req, err := http.NewRequest(method, suite.server.URL+path, strings.NewReader(body))
+		suite.Require().NoError(err)
+		resp, err := suite.authenticatedClient.Do(req)
+		suite.Require().NoError(err)
+		defer resp.Body.Close()
+		if dst != nil && resp.StatusCode < 300 {
+			suite.Require().NoError(json.NewDecoder(resp.Body).Decode(dst))
+		}
+		return resp.StatusCode
+	}
+
+	var tagsResponse struct {
+		Tags []string `json:"Tags"`
+	}
+	suite.Require().Equal(http.StatusOK, send(http.MethodPost, fmt.Sprintf("/v1/posts/%s/tags", postGo), `{"tags":["Go"," go "]}`, &tagsResponse))
+	suite.Require().Equal([]string{"go"}, tagsResponse.Tags)
+
+	suite.Require().Equal(http.StatusOK, send(http.MethodPost, fmt.Sprintf("/v1/posts/%s/tags", postBoth), `{"tags":["go","postgres"]}`, &tagsResponse))
+	suite.Require().Equal([]string{"go", "postgres"}, tagsResponse.Tags)
+
+	suite.Require().Equal(http.StatusNotFound, send(http.MethodPost, fmt.Sprintf("/v1/posts/%s/tags", uuid.New()), `{"tags":["go"]}`, nil))
+	suite.Require().Equal(http.StatusUnprocessableEntity, send(http.MethodPost, fmt.Sprintf("/v1/posts/%s/tags", postGo), `{"tags":[" "]}`, nil))
+
+	// Tags are listed with counts
+	var listResponse struct {
+		Tags []struct {
+			Name  string `json:"name"`
+			Count int    `json:"count"`
+		} `json:"Tags"`
+	}
+	suite.Require().Equal(http.StatusOK, send(http.MethodGet, "/v1/tags", "", &listResponse))
+	suite.Require().Len(listResponse.Tags, 2)
+	suite.Require().Equal("go", listResponse.Tags[0].Name)
+	suite.Require().Equal(2, listResponse.Tags[0].Count)
+	suite.Require().Equal(1, listResponse.Tags[1].Count)
+
+	getPosts := func(query string) []uuid.UUID {
+		var postsResponse struct {
+			Posts []struct {
+				ID   uuid.UUID `json:"id"`
+				Tags []string  `json:"tags"`
+			} `json:"Posts"`
+		}
+		suite.Require().Equal(http.StatusOK, send(http.MethodGet, "/v1/posts?"+query, "", &postsResponse))
+		var ids []uuid.UUID
+		for _, post := range postsResponse.Posts {
+			suite.Require().NotNil(post.Tags)
+			ids = append(ids, post.ID)
+		}
+		return ids
+	}
+
+	suite.Require().ElementsMatch([]uuid.UUID{postGo, postBoth}, getPosts("tags=go,postgres"))
+	suite.Require().ElementsMatch([]uuid.UUID{postBoth}, getPosts("tags=go,postgres&tag_mode=all"))
+	suite.Require().ElementsMatch([]uuid.UUID{postGo, postBoth, postNone}, getPosts(""))
+
+	// Removing a tag
+	suite.Require().Equal(http.StatusOK, send(http.MethodDelete, fmt.Sprintf("/v1/posts/%s/tags/postgres", postBoth), "", &tagsResponse))
+	suite.Require().Equal([]string{"go"}, tagsResponse.Tags)
+	suite.Require().Equal(http.StatusNotFound, send(http.MethodDelete, fmt.Sprintf("/v1/posts/%s/tags/postgres", postBoth), "", nil))
+
+	// Tags left without posts are still listed
+	suite.Require().Equal(http.StatusOK, send(http.MethodGet, "/v1/tags", "", &listResponse))
+	suite.Require().Len(listResponse.Tags, 2)
+	suite.Require().Equal("postgres", listResponse.Tags[1].Name)
+	suite.Require().Equal(0, listResponse.Tags[1].Count)
+
+	// Tagged posts are kept by retention
+	suite.Require().Equal(http.StatusOK, send(http.MethodPut, fmt.Sprintf("/v1/feeds/%s/retention", feedID), `{"retention_max_posts":1}`, nil))
+	pruned, err := retention.New(suite.app.db, retention.Policy{}).PruneOnce(context.Background())
+	suite.Require().NoError(err)
+	suite.Require().Equal(int64(0), pruned, "Only the untagged post should be counted")
+}
+
+func (suite *APITestSuite) TestRules() {
+	feedID := suite.createFeed("Test Feed for Rules", "http://example.com/rss/feed18.xml")
+
+	oldAd := suite.createPost(feedID, "Sponsored: an old advert", "https://example.com/rules/old-ad")
+	oldPost := suite.createPost(feedID, "An old article", "https://example.com/rules/old-post")
+
+	send := func(method, path, body string, dst any) int {
+		req, err := http.NewRequest(method, suite.server.URL+path, strings.NewReader(body))
+		suite.Require().NoError(err)
+		resp, err := suite.authenticatedClient.Do(req)
+		suite.Require().NoError(err)
+		defer resp.Body.Close()
+		if dst != nil && resp.StatusCode < 300 {
+			suite.Require().NoError(json.NewDecoder(resp.Body).Decode(dst))
+		}
+		return resp.StatusCode
+	}
+
+	// Invalid rules are rejected
+	suite.Require().Equal(http.StatusUnprocessableEntity, send(http.MethodPost, "/v1/rules", `{"name":"Bad","field":"title","match_type":"regex","pattern":"(","actions":["hide"]}`, nil))
+	suite.Require().Equal(http.StatusUnprocessableEntity, send(http.MethodPost, "/v1/rules", `{"name":"Bad","field":"body","match_type":"text","pattern":"x","actions":["hide"]}`, nil))
+	suite.Require().Equal(http.StatusUnprocessableEntity, send(http.MethodPost, "/v1/rules", `{"name":"Bad","field":"title","match_type":"text","pattern":"x","actions":["tag"]}`, nil))
+	suite.Require().Equal(http.StatusUnprocessableEntity, send(http.MethodPost, "/v1/rules", `{"name":"Bad","field":"title","match_type":"text","pattern":"x","actions":["delete"]}`, nil))
+
+	var ruleResponse struct {
+		Rule struct {
+			ID      uuid.UUID `json:"id"`
+			Tag     *string   `json:"tag"`
+			Enabled bool      `json:"enabled"`
+		} `json:"Rule"`
+	}
+	body := fmt.Sprintf(`{"name":"Ads","feed_id":"%s","field":"title","match_type":"regex","pattern":"^sponsored","actions":["hide","tag","notify"],"tag":" Ads "}`, feedID)
+	suite.Require().Equal(http.StatusCreated, send(http.MethodPost, "/v1/rules", body, &ruleResponse))
+	suite.Require().True(ruleResponse.Rule.Enabled)
+	suite.Require().Equal("ads", *ruleResponse.Rule.Tag)
+	ruleID := ruleResponse.Rule.ID
+
+	var rulesResponse struct {
+		Rules []struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"Rules"`
+	}
+	suite.Require().Equal(http.StatusOK, send(http.MethodGet, "/v1/rules", "", &rulesResponse))
+	suite.Require().Len(rulesResponse.Rules, 1)
+
+	// New posts are matched as they're collected
+	newAd := suite.createPost(feedID, "Sponsored: a new advert", "https://example.com/rules/new-ad")
+	rules.New(suite.app.db).PostCreated(context.Background(),
+		database.Feed{ID: feedID, Name: "Test Feed for Rules"},
+		database.Post{ID: newAd, Title: "Sponsored: a new advert", FeedID: feedID},
+	)
+
+	getPosts := func(query string) []uuid.UUID {
+		var postsResponse struct {
+			Posts []struct {
+				ID uuid.UUID `json:"id"`
+			} `json:"Posts"`
+		}
+		suite.Require().Equal(http.StatusOK, send(http.MethodGet, "/v1/posts?"+query, "", &postsResponse))
+		var ids []uuid.UUID
+		for _, post := range postsResponse.Posts {
+			ids = append(ids, post.ID)
+		}
+		return ids
+	}
+
+	suite.Require().ElementsMatch([]uuid.UUID{oldAd, oldPost}, getPosts(""))
+	suite.Require().ElementsMatch([]uuid.UUID{newAd}, getPosts("include_hidden=true&tags=ads"))
+
+	var notificationsResponse struct {
+		Notifications []struct {
+			PostID uuid.UUID `json:"post_id"`
+			RuleID uuid.UUID `json:"rule_id"`
+		} `json:"Notifications"`
+	}
+	suite.Require().Equal(http.StatusOK, send(http.MethodGet, "/v1/notifications", "", &notificationsResponse))
+	suite.Require().Len(notificationsResponse.Notifications, 1)
+	suite.Require().Equal(newAd, notificationsResponse.Notifications[0].PostID)
+	suite.Require().Equal(ruleID, notificationsResponse.Notifications[0].RuleID)
+
+	// Existing posts are matched when the rule is applied
+	var applyResponse struct {
+		Matched int `json:"Matched"`
+	}
+	suite.Require().Equal(http.StatusOK, send(http.MethodPost, fmt.Sprintf("/v1/rules/%s/apply", ruleID), "", &applyResponse))
+	suite.Require().Equal(2, applyResponse.Matched)
+	suite.Require().ElementsMatch([]uuid.UUID{oldPost}, getPosts(""))
+	suite.Require().ElementsMatch([]uuid.UUID{oldAd, newAd, oldPost}, getPosts("include_hidden=true"))
+
+	suite.Require().Equal(http.StatusOK, send(http.MethodGet, "/v1/notifications", "", &notificationsResponse))
+	suite.Require().Len(notificationsResponse.Notifications, 1, "Applying a rule shouldn't notify about old posts")
+
+	// Updating and deleting
+	body = `{"name":"Ads","field":"any","match_type":"text","pattern":"advert","actions":["star"],"enabled":false}`
+	suite.Require().Equal(http.StatusOK, send(http.MethodPut, fmt.Sprintf("/v1/rules/%s", ruleID), body, &ruleResponse))
+	suite.Require().False(ruleResponse.Rule.Enabled)
+	suite.Require().Nil(ruleResponse.Rule.Tag)
+	suite.Require().Equal(http.StatusNotFound, send(http.MethodPut, fmt.Sprintf("/v1/rules/%s", uuid.New()), body, nil))
+
+	suite.Require().Equal(http.StatusOK, send(http.MethodDelete, fmt.Sprintf("/v1/rules/%s", ruleID), "", nil))
+	suite.Require().Equal(http.StatusNotFound, send(http.MethodDelete, fmt.Sprintf("/v1/rules/%s", ruleID), "", nil))
+}
+
+func (suite *APITestSuite) TestPostSearch() {
+	feedID := suite.createFeed("Test Feed for Search", "http://example.com/rss/feed19.xml")
+
+	create := func(title, description, content string) uuid.UUID {
+		post, err := suite.app.db.CreatePost(context.Background(), database.CreatePostParams{
+			ID:          uuid.New(),
+			CreatedAt:   time.Now(),
+			UpdatedAt:   time.Now(),
+			Title:       title,
+			Url:         "https://example.com/search/" + uuid.NewString(),
+			Description: sql.NullString{String: description, Valid: true},
+			PublishedAt: sql.NullTime{Time: time.Now(), Valid: true},
+			FeedID:      feedID,
+			ClusterID:   uuid.New(),
+			Content:     sql.NullString{String: content, Valid: content != ""},
+		})
+		suite.Require().NoError(err)
+		return post.ID
+	}
+
+	inTitle := create("Scheduling goroutines", "<p>How the runtime works</p>", "")
+	inDescription := create("Runtime notes", "<p>A look at <b>goroutine</b> stacks</p>", "")
+	inContent := create("Weekly links", "Links from this week", "<p>Including a goroutine leak detector</p>")
+	unrelated := create("Postgres indexing", "<p>GIN and GiST indexes</p>", "<p>Nothing about Go</p>")
+
+	type searchResponse struct {
+		Posts []struct {
+			ID      uuid.UUID `json:"id"`
+			Snippet *string   `json:"snippet"`
+		} `json:"Posts"`
+	}
+	search := func(query string) searchResponse {
+		resp, err := suite.authenticatedClient.Get(suite.server.URL + "/v1/posts?" + query)
+		suite.Require().NoError(err)
+		defer resp.Body.Close()
+		suite.Require().Equal(http.StatusOK, resp.StatusCode)
+
+		var response searchResponse
+		suite.Require().NoError(json.NewDecoder(resp.Body).Decode(&response))
+		return response
+	}
+	ids := func(response searchResponse) []uuid.UUID {
+		var result []uuid.UUID
+		for _, post := range response.Posts {
+			result = append(result, post.ID)
+		}
+		return result
+	}
+
+	// Words are stemmed and markup is ignored
+	response := search("q=goroutines&sort=relevance")
+	suite.Require().Equal([]uuid.UUID{inTitle, inDescription, inContent}, ids(response), "Title matches should rank first")
+	suite.Require().NotNil(response.Posts[1].Snippet)
+	suite.Require().Contains(*response.Posts[1].Snippet, "<b>goroutine</b>")
+	suite.Require().NotContains(*response.Posts[1].Snippet, "<p>")
+
+	// Web search syntax
+	suite.Require().ElementsMatch([]uuid.UUID{inTitle, inDescription}, ids(search("q="+url.QueryEscape("goroutine -leak"))))
+	suite.Require().ElementsMatch([]uuid.UUID{inContent, unrelated}, ids(search("q="+url.QueryEscape("leak or gist"))))
+	suite.Require().ElementsMatch([]uuid.UUID{inDescription}, ids(search("q="+url.QueryEscape(`"goroutine stacks"`))))
+
+	// Snippets are only returned for searches
+	for _, post := range search("feed_id=" + feedID.String()).Posts {
+		suite.Require().Nil(post.Snippet)
+	}
+
+	// Relevance needs something to rank against
+	resp, err := suite.authenticatedClient.Get(suite.server.URL + "/v1/posts?sort=relevance")
+	suite.Require().NoError(err)
+	defer resp.Body.Close()
+	suite.Require().Equal(http.StatusUnprocessableEntity, resp.StatusCode)
+}
+
+func (suite *APITestSuite) TestSavedSearches() {
+	engFeed := suite.createFeed("Test Feed for Saved Searches A", "http://example.com/rss/feed20.xml")
+	otherFeed := suite.createFeed("Test Feed for Saved Searches B", "http://example.com/rss/feed21.xml")
+
+	postgres := suite.createPost(engFeed, "Tuning Postgres", "https://example.com/saved/postgres")
+	sqlite := suite.createPost(engFeed, "SQLite in production", "https://example.com/saved/sqlite")
+	suite.createPost(engFeed, "Postgres jobs this week", "https://example.com/saved/jobs")
+	suite.createPost(otherFeed, "Postgres elsewhere", "https://example.com/saved/elsewhere")
+
+	send := func(method, path, body string, dst any) int {
+		req, err := http.NewRequest(method, suite.server.URL+path, strings.NewReader(body))
+		suite.Require().NoError(err)
+		resp, err := suite.authenticatedClient.Do(req)
+		suite.Require().NoError(err)
+		defer resp.Body.Close()
+		if dst != nil && resp.StatusCode < 300 {
+			suite.Require().NoError(json.NewDecoder(resp.Body).Decode(dst))
+		}
+		return resp.StatusCode
+	}
+
+	var folderResponse struct {
+		Folder struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"Folder"`
+	}
+	suite.Require().Equal(http.StatusCreated, send(http.MethodPost, "/v1/folders", `{"name":"Eng"}`, &folderResponse))
+	folderID := folderResponse.Folder.ID
+
+	var followsResponse struct {
+		FeedFollows []struct {
+			ID     uuid.UUID `json:"id"`
+			FeedID uuid.UUID `json:"feedid"`
+		} `json:"feed follows"`
+	}
+	suite.Require().Equal(http.StatusOK, send(http.MethodGet, "/v1/feed_follows", "", &followsResponse))
+	for _, follow := range followsResponse.FeedFollows {
+		if follow.FeedID == engFeed {
+			body := fmt.Sprintf(`{"folder_ids":["%s"]}`, folderID)
+			suite.Require().Equal(http.StatusOK, send(http.MethodPut, fmt.Sprintf("/v1/feed_follows/%s/folders", follow.ID), body, nil))
+		}
+	}
+
+	// Invalid searches are rejected
+	suite.Require().Equal(http.StatusUnprocessableEntity, send(http.MethodPost, "/v1/saved_searches", `{"name":"Empty"}`, nil))
+	suite.Require().Equal(http.StatusUnprocessableEntity, send(http.MethodPost, "/v1/saved_searches", fmt.Sprintf(`{"name":"Missing","folder_id":"%s"}`, uuid.New()), nil))
+
+	var searchResponse struct {
+		SavedSearch struct {
+			ID       uuid.UUID  `json:"id"`
+			Query    string     `json:"query"`
+			FolderID *uuid.UUID `json:"folder_id"`
+			TagMode  string     `json:"tag_mode"`
+		} `json:"SavedSearch"`
+	}
+	body := fmt.Sprintf(`{"name":"Databases","query":"postgres OR sqlite -jobs","folder_id":"%s"}`, folderID)
+	suite.Require().Equal(http.StatusCreated, send(http.MethodPost, "/v1/saved_searches", body, &searchResponse))
+	suite.Require().Equal(folderID, *searchResponse.SavedSearch.FolderID)
+	suite.Require().Equal("any", searchResponse.SavedSearch.TagMode)
+	searchID := searchResponse.SavedSearch.ID
+
+	suite.Require().Equal(http.StatusUnprocessableEntity, send(http.MethodPost, "/v1/saved_searches", body, nil), "Names are unique per user")
+
+	var listResponse struct {
+		SavedSearches []struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"SavedSearches"`
+	}
+	suite.Require().Equal(http.StatusOK, send(http.MethodGet, "/v1/saved_searches", "", &listResponse))
+	suite.Require().Len(listResponse.SavedSearches, 1)
+
+	// Results page like /v1/posts, with the saved filters in place of the request's
+	var postsResponse struct {
+		Posts []struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"Posts"`
+		Metadata struct {
+			TotalRecords int `json:"total_records"`
+			LastPage     int `json:"last_page"`
+		} `json:"Metadata"`
+	}
+	path := fmt.Sprintf("/v1/saved_searches/%s/posts?page_size=1&q=elsewhere", searchID)
+	suite.Require().Equal(http.StatusOK, send(http.MethodGet, path, "", &postsResponse))
+	suite.Require().Len(postsResponse.Posts, 1)
+	suite.Require().Equal(2, postsResponse.Metadata.TotalRecords)
+	suite.Require().Equal(2, postsResponse.Metadata.LastPage)
+
+	path = fmt.Sprintf("/v1/saved_searches/%s/posts?sort=title", searchID)
+	suite.Require().Equal(http.StatusOK, send(http.MethodGet, path, "", &postsResponse))
+	suite.Require().Equal([]uuid.UUID{sqlite, postgres}, []uuid.UUID{postsResponse.Posts[0].ID, postsResponse.Posts[1].ID})
+
+	suite.Require().Equal(http.StatusNotFound, send(http.MethodGet, fmt.Sprintf("/v1/saved_searches/%s/posts", uuid.New()), "", nil))
+
+	// Unread counts
+	suite.Require().Equal(http.StatusOK, send(http.MethodPut, fmt.Sprintf("/v1/posts/%s/read", sqlite), "", nil))
+
+	var countsResponse struct {
+		SavedSearches []struct {
+			SavedSearchID uuid.UUID `json:"saved_search_id"`
+			Total         int       `json:"total"`
+			Unread        int       `json:"unread"`
+		} `json:"SavedSearches"`
+	}
+	suite.Require().Equal(http.StatusOK, send(http.MethodGet, "/v1/feed_follows/counts", "", &countsResponse))
+	suite.Require().Len(countsResponse.SavedSearches, 1)
+	suite.Require().Equal(searchID, countsResponse.SavedSearches[0].SavedSearchID)
+	suite.Require().Equal(2, countsResponse.SavedSearches[0].Total)
+	suite.Require().Equal(1, countsResponse.SavedSearches[0].Unread)
+
+	// Replacing and deleting
+	body = `{"name":"Databases","query":"postgres"}`
+	suite.Require().Equal(http.StatusOK, send(http.MethodPut, fmt.Sprintf("/v1/saved_searches/%s", searchID), body, &searchResponse))
+	suite.Require().Nil(searchResponse.SavedSearch.FolderID)
+
+	suite.Require().Equal(http.StatusOK, send(http.MethodGet, fmt.Sprintf("/v1/saved_searches/%s/posts", searchID), "", &postsResponse))
+	suite.Require().Equal(3, postsResponse.Metadata.TotalRecords)
+
+	// Counts are of the same posts the search lists
+	suite.Require().Equal(http.StatusOK, send(http.MethodGet, "/v1/feed_follows/counts", "", &countsResponse))
+	suite.Require().Equal(postsResponse.Metadata.TotalRecords, countsResponse.SavedSearches[0].Total)
+
+	suite.Require().Equal(http.StatusOK, send(http.MethodDelete, fmt.Sprintf("/v1/saved_searches/%s", searchID), "", nil))
+	suite.Require().Equal(http.StatusNotFound, send(http.MethodDelete, fmt.Sprintf("/v1/saved_searches/%s", searchID), "", nil))
+}
+
+func (suite *APITestSuite) TestPostsCursorPagination() {
+	feedID := suite.createFeed("Test Feed for Cursors", "http://example.com/rss/feed22.xml")
+
+	var posts []uuid.UUID
+	for i := 1; i <= 5; i++ {
+		posts = append(posts, suite.createPost(feedID, fmt.Sprintf("Cursor Post %d", i), fmt.Sprintf("https://example.com/cursor/%d", i)))
+	}
+
+	type page struct {
+		Posts []struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"Posts"`
+		Metadata struct {
+			PageSize     int    `json:"page_size"`
+			TotalRecords int    `json:"total_records"`
+			NextCursor   string `json:"next_cursor"`
+			PrevCursor   string `json:"prev_cursor"`
+		} `json:"Metadata"`
+	}
+	get := func(query string) (page, int) {
+		resp, err := suite.authenticatedClient.Get(fmt.Sprintf("%s/v1/posts?feed_id=%s&page_size=2&sort=-published_at&%s", suite.server.URL, feedID, query))
+		suite.Require().NoError(err)
+		defer resp.Body.Close()
+
+		var p page
+		if resp.StatusCode == http.StatusOK {
+			suite.Require().NoError(json.NewDecoder(resp.Body).Decode(&p))
+		}
+		return p, resp.StatusCode
+	}
+	ids := func(p page) []uuid.UUID {
+		var result []uuid.UUID
+		for _, post := range p.Posts {
+			result = append(result, post.ID)
+		}
+		return result
+	}
+
+	first, status := get("")
+	suite.Require().Equal(http.StatusOK, status)
+	suite.Require().Equal([]uuid.UUID{posts[4], posts[3]}, ids(first))
+	suite.Require().Equal(5, first.Metadata.TotalRecords)
+	suite.Require().NotEmpty(first.Metadata.NextCursor)
+	suite.Require().Empty(first.Metadata.PrevCursor)
+
+	// New posts don't shift later pages
+	suite.createPost(feedID, "Cursor Post 6", "https://example.com/cursor/6")
+
+	second, status := get("after=" + first.Metadata.NextCursor)
+	suite.Require().Equal(http.StatusOK, status)
+	suite.Require().Equal([]uuid.UUID{posts[2], posts[1]}, ids(second))
+	suite.Require().Equal(6, second.Metadata.TotalRecords)
+
+	third, status := get("after=" + second.Metadata.NextCursor + "&count=false")
+	suite.Require().Equal(http.StatusOK, status)
+	suite.Require().Equal([]uuid.UUID{posts[0]}, ids(third))
+	suite.Require().Zero(third.Metadata.TotalRecords, "Counting can be skipped")
+	suite.Require().Empty(third.Metadata.NextCursor)
+
+	// Paging back
+	back, status := get("before=" + third.Metadata.PrevCursor)
+	suite.Require().Equal(http.StatusOK, status)
+	suite.Require().Equal([]uuid.UUID{posts[2], posts[1]}, ids(back))
+	suite.Require().NotEmpty(back.Metadata.PrevCursor)
+	suite.Require().Equal(second.Metadata.NextCursor, back.Metadata.NextCursor)
+
+	// Cursors are tied to their sort
+	_, status = get("after=not-a-cursor")
+	suite.Require().Equal(http.StatusUnprocessableEntity, status)
+	resp, err := suite.authenticatedClient.Get(fmt.Sprintf("%s/v1/posts?sort=title&after=%s", suite.server.URL, first.Metadata.NextCursor))
+	suite.Require().NoError(err)
+	defer resp.Body.Close()
+	suite.Require().Equal(http.StatusUnprocessableEntity, resp.StatusCode)
+	_, status = get("after=" + first.Metadata.NextCursor + "&page=2")
+	suite.Require().Equal(http.StatusUnprocessableEntity, status)
+}
+
+func (suite *APITestSuite) TestPostFilters() {
+	feedA := suite.createFeed("Test Feed for Filters A", "http://example.com/rss/feed23.xml")
+	feedB := suite.createFeed("Test Feed for Filters B", "http://example.com/rss/feed24.xml")
+	feedC := suite.createFeed("Test Feed for Filters C", "http://example.com/rss/feed25.xml")
+
+	day := func(d int) time.Time {
+		return time.Date(2024, time.March, d, 12, 0, 0, 0, time.UTC)
+	}
+	nullString := func(s string) sql.NullString {
+		return sql.NullString{String: s, Valid: s != ""}
+	}
+	create := func(feedID uuid.UUID, published time.Time, author, enclosure, language string) uuid.UUID {
+		postID := uuid.New()
+		enclosureType := ""
+		if enclosure != "" {
+			enclosureType = "audio/mpeg"
+		}
+
+		post, err := suite.app.db.CreatePost(context.Background(), database.CreatePostParams{
+			ID:            postID,
+			CreatedAt:     time.Now(),
+			UpdatedAt:     time.Now(),
+			Title:         "Filtered post",
+			Url:           "https://example.com/filters/" + uuid.NewString(),
+			PublishedAt:   sql.NullTime{Time: published, Valid: true},
+			FeedID:        feedID,
+			ClusterID:     postID,
+			Author:        nullString(author),
+			EnclosureUrl:  nullString(enclosure),
+			EnclosureType: nullString(enclosureType),
+			Language:      nullString(language),
+		})
+		suite.Require().NoError(err)
+		return post.ID
+	}
+
+	early := create(feedA, day(1), "Jane Doe", "", "en")
+	podcast := create(feedB, day(10), "jane doe", "https://example.com/episode.mp3", "en-gb")
+	late := create(feedC, day(20), "Joe Bloggs", "", "fr")
+
+	get := func(query string) ([]uuid.UUID, int) {
+		resp, err := suite.authenticatedClient.Get(suite.server.URL + "/v1/posts?" + query)
+		suite.Require().NoError(err)
+		defer resp.Body.Close()
+
+		var response struct {
+			Posts []struct {
+				ID uuid.UUID `json:"id"`
+			} `json:"Posts"`
+		}
+		if resp.StatusCode == http.StatusOK {
+			suite.Require().NoError(json.NewDecoder(resp.Body).Decode(&response))
+		}
+		var ids []uuid.UUID
+		for _, post := range response.Posts {
+			ids = append(ids, post.ID)
+		}
+		return ids, resp.StatusCode
+	}
+	mustGet := func(query string) []uuid.UUID {
+		ids, status := get(query)
+		suite.Require().Equal(http.StatusOK, status, query)
+		return ids
+	}
+
+	feeds := fmt.Sprintf("feed_id=%s&feed_id=%s", feedA, feedB)
+	suite.Require().ElementsMatch([]uuid.UUID{early, podcast}, mustGet(feeds))
+	suite.Require().ElementsMatch([]uuid.UUID{early, podcast}, mustGet(fmt.Sprintf("feed_id=%s,%s", feedA, feedB)))
+
+	all := fmt.Sprintf("feed_id=%s,%s,%s", feedA, feedB, feedC)
+	suite.Require().ElementsMatch([]uuid.UUID{podcast, late}, mustGet(all+"&published_after=2024-03-05"))
+	suite.Require().ElementsMatch([]uuid.UUID{early}, mustGet(all+"&published_before=2024-03-10T12:00:00Z"))
+	suite.Require().ElementsMatch([]uuid.UUID{early, podcast, late}, mustGet(all+"&created_after=2024-01-01"))
+	suite.Require().Empty(mustGet(all + "&created_after=" + url.QueryEscape(time.Now().Add(time.Hour).Format(time.RFC3339))))
+	suite.Require().ElementsMatch([]uuid.UUID{early, podcast}, mustGet(all+"&author="+url.QueryEscape("JANE DOE")))
+	suite.Require().ElementsMatch([]uuid.UUID{podcast}, mustGet(all+"&has_enclosure=true"))
+	suite.Require().ElementsMatch([]uuid.UUID{early, late}, mustGet(all+"&has_enclosure=false"))
+	suite.Require().ElementsMatch([]uuid.UUID{early, podcast}, mustGet(all+"&language=EN"))
+	suite.Require().ElementsMatch([]uuid.UUID{podcast}, mustGet(all+"&language=en-gb"))
+
+	for _, query := range []string{
+		"feed_id=not-a-uuid",
+		"published_after=yesterday",
+		"published_after=2024-03-10&published_before=2024-03-01",
+		"has_enclosure=maybe",
+		"language=english!",
+	} {
+		_, status := get(query)
+		suite.Require().Equal(http.StatusUnprocessableEntity, status, query)
+	}
+}
+
+func (suite *APITestSuite) TestStream() {
+	feedID := suite.createFeed("Test Feed for Streaming", "http://example.com/rss/feed26.xml")
+
+	type event struct {
+		ID   string
+		Post struct {
+			ID uuid.UUID `json:"id"`
+		}
+	}
+
+	// open connects to the stream and waits until it's caught up.
+	open := func(lastEventID string) (<-chan event, context.CancelFunc) {
+		ctx, cancel := context.WithCancel(context.Background())
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, suite.server.URL+"/v1/stream", nil)
+		suite.Require().NoError(err)
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+
+		resp, err := suite.authenticatedClient.Do(req)
+		suite.Require().NoError(err)
+		suite.Require().Equal(http.StatusOK, resp.StatusCode)
+		suite.Require().Equal("text/event-stream", resp.Header.Get("Content-Type"))
+
+		events := make(chan event, 10)
+		connected := make(chan struct{})
+		go func() {
+			defer resp.Body.Close()
+			defer close(events)
+
+			var e event
+			scanner := bufio.NewScanner(resp.Body)
+			for scanner.Scan() {
+				line := scanner.Text()
+				switch {
+				case line == ": connected":
+					close(connected)
+				case strings.HasPrefix(line, "id: "):
+					e.ID = strings.TrimPrefix(line, "id: ")
+				case strings.HasPrefix(line, "data: "):
+					if json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e.Post) != nil {
+						return
+					}
+				case line == "" && e.ID != "":
+					events <- e
+					e = event{}
+				}
+			}
+		}()
+
+		select {
+		case <-connected:
+		case <-time.After(5 * time.Second):
+			suite.FailNow("stream didn't connect")
+		}
+		return events, cancel
+	}
+	next := func(events <-chan event) event {
+		select {
+		case e, ok := <-events:
+			suite.Require().True(ok, "stream ended")
+			return e
+		case <-time.After(5 * time.Second):
+			suite.FailNow("no event received")
+			return event{}
+		}
+	}
+
+	events, cancel := open("")
+	first := suite.createPost(feedID, "First Streamed Post", "https://example.com/stream/1")
+	suite.app.stream.Publish()
+
+	e := next(events)
+	suite.Require().Equal(first, e.Post.ID)
+	suite.Require().NotEmpty(e.ID)
+	cancel()
+
+	// Posts collected while disconnected are sent on resuming, in the order
+	// they were stored, even when collected by an instance whose clock is
+	// behind.
+	second := suite.createPost(feedID, "Second Streamed Post", "https://example.com/stream/2")
+	skewed, err := suite.app.db.CreatePost(context.Background(), database.CreatePostParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now().Add(-time.Hour),
+		UpdatedAt: time.Now().Add(-time.Hour),
+		Title:     "Skewed Streamed Post",
+		Url:       "https://example.com/stream/skewed",
+		FeedID:    feedID,
+	})
+	suite.Require().NoError(err)
+	third := suite.createPost(feedID, "Third Streamed Post", "https://example.com/stream/3")
+
+	events, cancel = open(e.ID)
+	defer cancel()
+	suite.Require().Equal(second, next(events).Post.ID)
+	suite.Require().Equal(skewed.ID, next(events).Post.ID)
+	suite.Require().Equal(third, next(events).Post.ID)
+
+	// Streams end when the server shuts down.
+	broker := suite.app.stream
+	suite.app.stream = stream.NewBroker()
+	broker.Close()
+	select {
+	case _, ok := <-events:
+		suite.Require().False(ok)
+	case <-time.After(5 * time.Second):
+		suite.FailNow("stream didn't end")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, suite.server.URL+"/v1/stream", nil)
+	suite.Require().NoError(err)
+	req.Header.Set("Last-Event-ID", "not-an-event")
+	resp, err := suite.authenticatedClient.Do(req)
+	suite.Require().NoError(err)
+	defer resp.Body.Close()
+	suite.Require().Equal(http.StatusUnprocessableEntity, resp.StatusCode)
+}
+
+func (suite *APITestSuite) TestWebSocket() {
+	feedID := suite.createFeed("Test Feed for WebSockets", "http://example.com/rss/feed27.xml")
+
+	user, err := suite.app.db.GetUserByEmail(context.Background(), suite.authenticatedUserEmail)
+	suite.Require().NoError(err)
+	token := suite.authenticatedClient.Transport.(*AuthenticatedTransport).AuthToken
+
+	wsURL := "ws" + strings.TrimPrefix(suite.server.URL, "http") + "/v1/ws"
+
+	type message struct {
+		Type    string `json:"type"`
+		ID      string `json:"id"`
+		Updated int64  `json:"updated"`
+		Error   string `json:"error"`
+		Post    struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"post"`
+		States []struct {
+			PostID uuid.UUID  `json:"post_id"`
+			ReadAt *time.Time `json:"read_at"`
+		} `json:"states"`
+	}
+	read := func(conn *websocket.Conn) message {
+		suite.Require().NoError(conn.SetReadDeadline(time.Now().Add(5 * time.Second)))
+		var msg message
+		suite.Require().NoError(conn.ReadJSON(&msg))
+		return msg
+	}
+
+	// Browsers can't set headers, so they authenticate with a message.
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	suite.Require().NoError(err)
+	defer conn.Close()
+
+	suite.Require().NoError(conn.WriteJSON(map[string]string{"type": "auth", "token": token}))
+	suite.Require().Equal("ready", read(conn).Type)
+
+	postID := suite.createPost(feedID, "WebSocket Post", "https://example.com/ws/1")
+	suite.app.stream.Publish()
+
+	msg := read(conn)
+	suite.Require().Equal("post", msg.Type)
+	suite.Require().Equal(postID, msg.Post.ID)
+
+	suite.Require().NoError(conn.WriteJSON(map[string]any{"type": "mark_read", "id": "1", "post_ids": []uuid.UUID{postID}, "read": true}))
+	msg = read(conn)
+	suite.Require().Equal("ack", msg.Type)
+	suite.Require().Equal("1", msg.ID)
+	suite.Require().Equal(int64(1), msg.Updated)
+
+	// The post_states trigger announces the change once it's committed.
+	suite.app.stream.PublishStates(user.ID)
+	msg = read(conn)
+	suite.Require().Equal("states", msg.Type)
+	suite.Require().Len(msg.States, 1)
+	suite.Require().Equal(postID, msg.States[0].PostID)
+	suite.Require().NotNil(msg.States[0].ReadAt)
+
+	suite.Require().NoError(conn.WriteJSON(map[string]any{"type": "star", "id": "2"}))
+	msg = read(conn)
+	suite.Require().Equal("error", msg.Type)
+	suite.Require().Equal("2", msg.ID)
+
+	suite.Require().NoError(conn.WriteMessage(websocket.TextMessage, []byte("not json")))
+	suite.Require().Equal("error", read(conn).Type)
+
+	// Clients with an Authorization header are ready straight away.
+	header := http.Header{"Authorization": []string{"Bearer " + token}}
+	other, _, err := websocket.DefaultDialer.Dial(wsURL, header)
+	suite.Require().NoError(err)
+	defer other.Close()
+	suite.Require().Equal("ready", read(other).Type)
+
+	rejected, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	suite.Require().NoError(err)
+	defer rejected.Close()
+
+	suite.Require().NoError(rejected.WriteJSON(map[string]string{"type": "auth", "token": strings.Repeat("A", 26)}))
+	suite.Require().NoError(rejected.SetReadDeadline(time.Now().Add(5 * time.Second)))
+	_, _, err = rejected.ReadMessage()
+	suite.Require().True(websocket.IsCloseError(err, websocket.ClosePolicyViolation), err)
+}
+
+func (suite *APITestSuite) TestWebhooks() {
+	feedID := suite.createFeed("Test Feed for Webhooks", "http://example.com/rss/feed28.xml")
+	const secret = "a-very-secret-secret"
+
+	type request struct {
+		Header http.Header
+		Body   []byte
+	}
+	requests := make(chan request, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- request{Header: r.Header, Body: body}
+	}))
+	defer receiver.Close()
+
+	received := func() request {
+		select {
+		case req := <-requests:
+			signature := webhooks.Sign(secret, req.Header.Get("X-Webhook-Timestamp"), req.Body)
+			suite.Require().Equal(signature, req.Header.Get("X-Webhook-Signature"))
+			return req
+		default:
+			suite.FailNow("no webhook received")
+			return request{}
+		}
+	}
+
+	send := func(method, path, body string, dst any) int {
+		req, err := http.NewRequest(method, suite.server.URL+path, strings.NewReader(body))
+		suite.Require().NoError(err)
+		resp, err := suite.authenticatedClient.Do(req)
+		suite.Require().NoError(err)
+		defer resp.Body.Close()
+		if dst != nil && resp.StatusCode < 300 {
+			suite.Require().NoError(json.NewDecoder(resp.Body).Decode(dst))
+		}
+		return resp.StatusCode
+	}
+
+	// Invalid webhooks are rejected
+	suite.Require().Equal(http.StatusUnprocessableEntity, send(http.MethodPost, "/v1/webhooks", fmt.Sprintf(`{"url":"ftp://example.com","secret":"%s"}`, secret), nil))
+	suite.Require().Equal(http.StatusUnprocessableEntity, send(http.MethodPost, "/v1/webhooks", fmt.Sprintf(`{"url":"%s","secret":"short"}`, receiver.URL), nil))
+	suite.Require().Equal(http.StatusUnprocessableEntity, send(http.MethodPost, "/v1/webhooks", fmt.Sprintf(`{"url":"%s"}`, receiver.URL), nil))
+	suite.Require().Equal(http.StatusUnprocessableEntity, send(http.MethodPost, "/v1/webhooks", fmt.Sprintf(`{"url":"%s","secret":"%s","folder_id":"%s"}`, receiver.URL, secret, uuid.New()), nil))
+
+	var webhookResponse struct {
+		Webhook map[string]any `json:"Webhook"`
+	}
+	body := fmt.Sprintf(`{"url":"%s","secret":"%s","feed_id":"%s","keywords":["postgres", " "]}`, receiver.URL, secret, feedID)
+	suite.Require().Equal(http.StatusCreated, send(http.MethodPost, "/v1/webhooks", body, &webhookResponse))
+	suite.Require().NotContains(webhookResponse.Webhook, "secret")
+	suite.Require().Equal([]any{"postgres"}, webhookResponse.Webhook["keywords"])
+	suite.Require().Equal(true, webhookResponse.Webhook["enabled"])
+	webhookID := webhookResponse.Webhook["id"].(string)
+
+	var listResponse struct {
+		Webhooks []struct {
+			ID string `json:"id"`
+		} `json:"Webhooks"`
+	}
+	suite.Require().Equal(http.StatusOK, send(http.MethodGet, "/v1/webhooks", "", &listResponse))
+	suite.Require().Len(listResponse.Webhooks, 1)
+
+	// Test events are delivered straight away
+	var testResponse struct {
+		Delivery struct {
+			Event          string `json:"event"`
+			Status         string `json:"status"`
+			Attempts       int    `json:"attempts"`
+			ResponseStatus int    `json:"response_status"`
+		} `json:"Delivery"`
+	}
+	suite.Require().Equal(http.StatusOK, send(http.MethodPost, "/v1/webhooks/"+webhookID+"/test", "", &testResponse))
+	suite.Require().Equal(webhooks.EventTest, testResponse.Delivery.Event)
+	suite.Require().Equal(webhooks.StatusSucceeded, testResponse.Delivery.Status)
+	suite.Require().Equal(1, testResponse.Delivery.Attempts)
+	suite.Require().Equal(http.StatusOK, testResponse.Delivery.ResponseStatus)
+	suite.Require().Equal(webhooks.EventTest, received().Header.Get("X-Webhook-Event"))
+
+	// Only new posts matching the keywords are queued, once each
+	matching := suite.createPost(feedID, "Tuning Postgres", "https://example.com/webhooks/postgres")
+	other := suite.createPost(feedID, "SQLite in production", "https://example.com/webhooks/sqlite")
+
+	dispatcher := webhooks.New(suite.app.db, true)
+	feed := database.Feed{ID: feedID, Name: "Test Feed for Webhooks"}
+	dispatcher.PostCreated(context.Background(), feed, database.Post{ID: matching, Title: "Tuning Postgres", FeedID: feedID})
+	dispatcher.PostCreated(context.Background(), feed, database.Post{ID: matching, Title: "Tuning Postgres", FeedID: feedID})
+	dispatcher.PostCreated(context.Background(), feed, database.Post{ID: other, Title: "SQLite in production", FeedID: feedID})
+
+	delivered, err := dispatcher.DeliverOnce(context.Background(), 10)
+	suite.Require().NoError(err)
+	suite.Require().Equal(1, delivered)
+
+	var payload webhooks.Payload
+	req := received()
+	suite.Require().Equal(webhooks.EventPostCreated, req.Header.Get("X-Webhook-Event"))
+	suite.Require().NoError(json.Unmarshal(req.Body, &payload))
+	suite.Require().Equal(matching, payload.Post.ID)
+	suite.Require().Equal(feedID, payload.Feed.ID)
+
+	var deliveriesResponse struct {
+		Metadata struct {
+			TotalRecords int `json:"total_records"`
+		} `json:"Metadata"`
+		Deliveries []struct {
+			Status  string          `json:"status"`
+			Event   string          `json:"event"`
+			Payload json.RawMessage `json:"payload"`
+		} `json:"Deliveries"`
+	}
+	suite.Require().Equal(http.StatusOK, send(http.MethodGet, "/v1/webhooks/"+webhookID+"/deliveries", "", &deliveriesResponse))
+	suite.Require().Equal(2, deliveriesResponse.Metadata.TotalRecords)
+	for _, delivery := range deliveriesResponse.Deliveries {
+		suite.Require().Equal(webhooks.StatusSucceeded, delivery.Status)
+		suite.Require().True(json.Valid(delivery.Payload))
+	}
+
+	// Disabled webhooks aren't sent new posts
+	body = fmt.Sprintf(`{"url":"%s","secret":"%s","enabled":false}`, receiver.URL, secret)
+	suite.Require().Equal(http.StatusOK, send(http.MethodPut, "/v1/webhooks/"+webhookID, body, &webhookResponse))
+	suite.Require().Equal(false, webhookResponse.Webhook["enabled"])
+	suite.Require().Nil(webhookResponse.Webhook["feed_id"])
+
+	another := suite.createPost(feedID, "Postgres again", "https://example.com/webhooks/again")
+	dispatcher.PostCreated(context.Background(), feed, database.Post{ID: another, Title: "Postgres again", FeedID: feedID})
+	delivered, err = dispatcher.DeliverOnce(context.Background(), 10)
+	suite.Require().NoError(err)
+	suite.Require().Equal(0, delivered)
+
+	// Chat webhooks are sent formatted messages, and wait to batch new posts
+	body = fmt.Sprintf(`{"url":"%s/_matrix/client/v3/rooms/!room:example.org","secret":"%s","format":"matrix"}`, receiver.URL, secret)
+	suite.Require().Equal(http.StatusUnprocessableEntity, send(http.MethodPost, "/v1/webhooks", body, nil))
+	suite.Require().Equal(http.StatusUnprocessableEntity, send(http.MethodPost, "/v1/webhooks", fmt.Sprintf(`{"url":"%s","secret":"%s","format":"irc"}`, receiver.URL, secret), nil))
+	body = fmt.Sprintf(`{"url":"%s/_matrix/client/v3/rooms/!room:example.org/send/m.room.message","format":"matrix"}`, receiver.URL)
+	suite.Require().Equal(http.StatusUnprocessableEntity, send(http.MethodPost, "/v1/webhooks", body, nil))
+
+	// Slack and Discord webhooks needn't have a secret
+	var discordResponse struct {
+		Webhook map[string]any `json:"Webhook"`
+	}
+	body = fmt.Sprintf(`{"url":"%s","format":"discord","enabled":false}`, receiver.URL)
+	suite.Require().Equal(http.StatusCreated, send(http.MethodPost, "/v1/webhooks", body, &discordResponse))
+	suite.Require().Equal(http.StatusOK, send(http.MethodDelete, "/v1/webhooks/"+discordResponse.Webhook["id"].(string), "", nil))
+
+	body = fmt.Sprintf(`{"url":"%s","secret":"%s","format":"slack","feed_id":"%s"}`, receiver.URL, secret, feedID)
+	suite.Require().Equal(http.StatusCreated, send(http.MethodPost, "/v1/webhooks", body, &webhookResponse))
+	suite.Require().Equal("slack", webhookResponse.Webhook["format"])
+	slackID := webhookResponse.Webhook["id"].(string)
+
+	suite.Require().Equal(http.StatusOK, send(http.MethodPost, "/v1/webhooks/"+slackID+"/test", "", &testResponse))
+	suite.Require().Equal(webhooks.StatusSucceeded, testResponse.Delivery.Status)
+	var slackMessage struct {
+		Text   string            `json:"text"`
+		Blocks []json.RawMessage `json:"blocks"`
+	}
+	suite.Require().NoError(json.Unmarshal(received().Body, &slackMessage))
+	suite.Require().NotEmpty(slackMessage.Text)
+	suite.Require().NotEmpty(slackMessage.Blocks)
+
+	burst := []uuid.UUID{
+		suite.createPost(feedID, "Burst one", "https://example.com/webhooks/burst/1"),
+		suite.createPost(feedID, "Burst two", "https://example.com/webhooks/burst/2"),
+	}
+	for _, postID := range burst {
+		dispatcher.PostCreated(context.Background(), feed, database.Post{ID: postID, Title: "Burst", FeedID: feedID})
+	}
+	delivered, err = dispatcher.DeliverOnce(context.Background(), 10)
+	suite.Require().NoError(err)
+	suite.Require().Equal(0, delivered, "chat deliveries wait for the batch window")
+
+	var pendingResponse struct {
+		Deliveries []struct {
+			Status        string     `json:"status"`
+			NextAttemptAt *time.Time `json:"next_attempt_at"`
+		} `json:"Deliveries"`
+	}
+	suite.Require().Equal(http.StatusOK, send(http.MethodGet, "/v1/webhooks/"+slackID+"/deliveries", "", &pendingResponse))
+	suite.Require().Len(pendingResponse.Deliveries, 3)
+	suite.Require().Equal(webhooks.StatusPending, pendingResponse.Deliveries[0].Status)
+	suite.Require().Equal(*pendingResponse.Deliveries[0].NextAttemptAt, *pendingResponse.Deliveries[1].NextAttemptAt, "a burst is sent together")
+
+	// Other users' webhooks can't be seen
+	suite.Require().Equal(http.StatusNotFound, send(http.MethodGet, "/v1/webhooks/"+uuid.NewString(), "", nil))
+	suite.Require().Equal(http.StatusNotFound, send(http.MethodPost, "/v1/webhooks/"+uuid.NewString()+"/test", "", nil))
+
+	suite.Require().Equal(http.StatusOK, send(http.MethodDelete, "/v1/webhooks/"+webhookID, "", nil))
+	suite.Require().Equal(http.StatusNotFound, send(http.MethodGet, "/v1/webhooks/"+webhookID, "", nil))
+}
+
+// recordingMailer records the emails it's asked to send.
+type recordingMailer struct {
+	sent []any
+}
+
+func (m *recordingMailer) Send(recipient, templateFile string, data any) error {
+	m.sent = append(m.sent, data)
+	return nil
+}
+
+func (suite *APITestSuite) TestDigests() {
+	feedID := suite.createFeed("Test Feed for Digests", "http://example.com/rss/feed29.xml")
+
+	send := func(client *http.Client, method, path, body string, dst any) int {
+		req, err := http.NewRequest(method, suite.server.URL+path, strings.NewReader(body))
+		suite.Require().NoError(err)
+		resp, err := client.Do(req)
+		suite.Require().NoError(err)
+		defer resp.Body.Close()
+		if dst != nil && resp.StatusCode < 300 {
+			suite.Require().NoError(json.NewDecoder(resp.Body).Decode(dst))
+		}
+		return resp.StatusCode
+	}
+	client := suite.authenticatedClient
+
+	// Invalid digests are rejected
+	suite.Require().Equal(http.StatusUnprocessableEntity, send(client, http.MethodPost, "/v1/digests", `{"schedule":"hourly"}`, nil))
+	suite.Require().Equal(http.StatusUnprocessableEntity, send(client, http.MethodPost, "/v1/digests", `{"schedule":"daily","hour":24}`, nil))
+	suite.Require().Equal(http.StatusUnprocessableEntity, send(client, http.MethodPost, "/v1/digests", `{"schedule":"weekly","weekday":"someday"}`, nil))
+	suite.Require().Equal(http.StatusUnprocessableEntity, send(client, http.MethodPost, "/v1/digests", `{"schedule":"daily","timezone":"Mars/Olympus"}`, nil))
+	suite.Require().Equal(http.StatusUnprocessableEntity, send(client, http.MethodPost, "/v1/digests", fmt.Sprintf(`{"schedule":"daily","folder_ids":["%s"]}`, uuid.New()), nil))
+
+	var digestResponse struct {
+		Digest struct {
+			ID         string    `json:"id"`
+			Schedule   string    `json:"schedule"`
+			Hour       int       `json:"hour"`
+			Weekday    string    `json:"weekday"`
+			Timezone   string    `json:"timezone"`
+			FeedIDs    []string  `json:"feed_ids"`
+			Enabled    bool      `json:"enabled"`
+			NextSendAt time.Time `json:"next_send_at"`
+		} `json:"Digest"`
+	}
+	body := fmt.Sprintf(`{"schedule":"weekly","hour":7,"weekday":"Friday","timezone":"Europe/Rome","feed_ids":["%s"]}`, feedID)
+	suite.Require().Equal(http.StatusCreated, send(client, http.MethodPost, "/v1/digests", body, &digestResponse))
+	suite.Require().Equal("weekly", digestResponse.Digest.Schedule)
+	suite.Require().Equal(7, digestResponse.Digest.Hour)
+	suite.Require().Equal("friday", digestResponse.Digest.Weekday)
+	suite.Require().Equal([]string{feedID.String()}, digestResponse.Digest.FeedIDs)
+	suite.Require().True(digestResponse.Digest.Enabled)
+	suite.Require().True(digestResponse.Digest.NextSendAt.After(time.Now()))
+	suite.Require().Equal(time.Friday, digestResponse.Digest.NextSendAt.Weekday())
+	digestID := digestResponse.Digest.ID
+
+	body = `{"schedule":"daily"}`
+	suite.Require().Equal(http.StatusOK, send(client, http.MethodPut, "/v1/digests/"+digestID, body, &digestResponse))
+	suite.Require().Equal("daily", digestResponse.Digest.Schedule)
+	suite.Require().Equal(8, digestResponse.Digest.Hour)
+	suite.Require().Equal("UTC", digestResponse.Digest.Timezone)
+	suite.Require().Empty(digestResponse.Digest.FeedIDs)
+
+	var listResponse struct {
+		Digests []struct {
+			ID string `json:"id"`
+		} `json:"Digests"`
+	}
+	suite.Require().Equal(http.StatusOK, send(client, http.MethodGet, "/v1/digests", "", &listResponse))
+	suite.Require().Len(listResponse.Digests, 1)
+
+	// Due digests are emailed the unread posts of followed feeds
+	suite.createPost(feedID, "Digest post", "https://example.com/digests/post")
+	_, err := suite.tx.Exec("UPDATE digests SET next_send_at = $1 WHERE id = $2", time.Now().UTC().Add(-time.Minute), digestID)
+	suite.Require().NoError(err)
+
+	mailer := &recordingMailer{}
+	sent, err := digests.New(suite.app.db, mailer, "http://localhost").SendOnce(context.Background())
+	suite.Require().NoError(err)
+	suite.Require().Equal(1, sent)
+	suite.Require().Len(mailer.sent, 1)
+	email := mailer.sent[0].(digests.Email)
+	suite.Require().Len(email.Posts, 1)
+	suite.Require().Equal("Digest post", email.Posts[0].Title)
+	suite.Require().Equal("Test Feed for Digests", email.Posts[0].FeedName)
+
+	// Sent digests are rescheduled rather than sent again
+	sent, err = digests.New(suite.app.db, mailer, "http://localhost").SendOnce(context.Background())
+	suite.Require().NoError(err)
+	suite.Require().Equal(0, sent)
+
+	// The unsubscribe link works without logging in, but opening it only
+	// asks for confirmation
+	unsubscribePath := strings.TrimPrefix(email.UnsubscribeURL, "http://localhost")
+	suite.Require().Equal(http.StatusNotFound, send(http.DefaultClient, http.MethodGet, "/v1/unsubscribe/not-a-token", "", nil))
+	suite.Require().Equal(http.StatusNotFound, send(http.DefaultClient, http.MethodPost, "/v1/unsubscribe/not-a-token", "", nil))
+	suite.Require().Equal(http.StatusOK, send(http.DefaultClient, http.MethodGet, unsubscribePath, "", nil))
+
+	var enabledResponse struct {
+		Digests []struct {
+			Enabled bool `json:"enabled"`
+		} `json:"Digests"`
+	}
+	suite.Require().Equal(http.StatusOK, send(client, http.MethodGet, "/v1/digests", "", &enabledResponse))
+	suite.Require().True(enabledResponse.Digests[0].Enabled)
+
+	suite.Require().Equal(http.StatusOK, send(http.DefaultClient, http.MethodPost, unsubscribePath, "List-Unsubscribe=One-Click", nil))
+	suite.Require().Equal(http.StatusOK, send(client, http.MethodGet, "/v1/digests", "", &enabledResponse))
+	suite.Require().False(enabledResponse.Digests[0].Enabled)
+
+	// Other users' digests can't be changed
+	suite.Require().Equal(http.StatusNotFound, send(client, http.MethodPut, "/v1/digests/"+uuid.NewString(), `{"schedule":"daily"}`, nil))
+	suite.Require().Equal(http.StatusNotFound, send(client, http.MethodDelete, "/v1/digests/"+uuid.NewString(), "", nil))
+
+	suite.Require().Equal(http.StatusOK, send(client, http.MethodDelete, "/v1/digests/"+digestID, "", nil))
+	suite.Require().Equal(http.StatusOK, send(client, http.MethodGet, "/v1/digests", "", &listResponse))
+	suite.Require().Empty(listResponse.Digests)
+}
+
+func (suite *APITestSuite) TestAlerts() {
+	feedID := suite.createFeed("Test Feed for Alerts", "http://example.com/rss/feed30.xml")
+
+	send := func(method, path, body string, dst any) int {
+		req, err := http.NewRequest(method, suite.server.URL+path, strings.NewReader(body))
+		suite.Require().NoError(err)
+		resp, err := suite.authenticatedClient.Do(req)
+		suite.Require().NoError(err)
+		defer resp.Body.Close()
+		if dst != nil && resp.StatusCode < 300 {
+			suite.Require().NoError(json.NewDecoder(resp.Body).Decode(dst))
+		}
+		return resp.StatusCode
+	}
+
+	// Invalid alerts are rejected
+	suite.Require().Equal(http.StatusUnprocessableEntity, send(http.MethodPost, "/v1/alerts", `{"phrase":"   "}`, nil))
+	suite.Require().Equal(http.StatusUnprocessableEntity, send(http.MethodPost, "/v1/alerts", fmt.Sprintf(`{"phrase":"%s"}`, strings.Repeat("a", 201)), nil))
+
+	var alertResponse struct {
+		Alert struct {
+			ID      string `json:"id"`
+			Phrase  string `json:"phrase"`
+			Enabled bool   `json:"enabled"`
+		} `json:"Alert"`
+	}
+	suite.Require().Equal(http.StatusCreated, send(http.MethodPost, "/v1/alerts", `{"phrase":"  tuning   postgres "}`, &alertResponse))
+	suite.Require().Equal("tuning postgres", alertResponse.Alert.Phrase)
+	suite.Require().True(alertResponse.Alert.Enabled)
+	alertID := alertResponse.Alert.ID
+
+	var listResponse struct {
+		Alerts []struct {
+			ID         string     `json:"id"`
+			LastSentAt *time.Time `json:"last_sent_at"`
+		} `json:"Alerts"`
+	}
+	suite.Require().Equal(http.StatusOK, send(http.MethodGet, "/v1/alerts", "", &listResponse))
+	suite.Require().Len(listResponse.Alerts, 1)
+
+	// Matching posts are emailed as soon as they're collected, once each
+	mailer := &recordingMailer{}
+	alerter := alerts.New(suite.app.db, mailer)
+	feed := database.Feed{ID: feedID, Name: "Test Feed for Alerts"}
+
+	matching := suite.createPost(feedID, "Tuning Postgres", "https://example.com/alerts/postgres")
+	other := suite.createPost(feedID, "SQLite in production", "https://example.com/alerts/sqlite")
+	alerter.PostCreated(context.Background(), feed, database.Post{ID: matching, Title: "Tuning Postgres", FeedID: feedID})
+	alerter.PostCreated(context.Background(), feed, database.Post{ID: matching, Title: "Tuning Postgres", FeedID: feedID})
+	alerter.PostCreated(context.Background(), feed, database.Post{ID: other, Title: "SQLite in production", FeedID: feedID})
+
+	sent, err := alerter.SendOnce(context.Background())
+	suite.Require().NoError(err)
+	suite.Require().Equal(1, sent)
+	suite.Require().Len(mailer.sent, 1)
+	email := mailer.sent[0].(alerts.Email)
+	suite.Require().Equal("tuning postgres", email.Phrase)
+	suite.Require().Len(email.Posts, 1)
+	suite.Require().Equal("Tuning Postgres", email.Posts[0].Title)
+	suite.Require().Equal("Test Feed for Alerts", email.Posts[0].FeedName)
+
+	suite.Require().Equal(http.StatusOK, send(http.MethodGet, "/v1/alerts", "", &listResponse))
+	suite.Require().NotNil(listResponse.Alerts[0].LastSentAt)
+
+	// Matches within the window of the last email wait for it to end
+	again := suite.createPost(feedID, "Tuning Postgres again", "https://example.com/alerts/again")
+	alerter.PostCreated(context.Background(), feed, database.Post{ID: again, Title: "Tuning Postgres again", FeedID: feedID})
+	sent, err = alerter.SendOnce(context.Background())
+	suite.Require().NoError(err)
+	suite.Require().Equal(0, sent)
+	suite.Require().Len(mailer.sent, 1)
+
+	// Follows with notifications turned off don't alert
+	var followsResponse struct {
+		FeedFollows []struct {
+			ID     uuid.UUID `json:"id"`
+			FeedID uuid.UUID `json:"feedid"`
+		} `json:"feed follows"`
+	}
+	suite.Require().Equal(http.StatusOK, send(http.MethodGet, "/v1/feed_follows", "", &followsResponse))
+	for _, follow := range followsResponse.FeedFollows {
+		if follow.FeedID == feedID {
+			suite.Require().Equal(http.StatusOK, send(http.MethodPatch, fmt.Sprintf("/v1/feed_follows/%s", follow.ID), `{"notify":false}`, nil))
+		}
+	}
+
+	quiet := suite.createPost(feedID, "Tuning Postgres quietly", "https://example.com/alerts/quiet")
+	alerter.PostCreated(context.Background(), feed, database.Post{ID: quiet, Title: "Tuning Postgres quietly", FeedID: feedID})
+	var queued int
+	err = suite.tx.QueryRow("SELECT count(*) FROM alert_matches WHERE post_id = $1", quiet).Scan(&queued)
+	suite.Require().NoError(err)
+	suite.Require().Zero(queued)
+
+	// Alerts can be replaced and disabled
+	suite.Require().Equal(http.StatusOK, send(http.MethodPut, "/v1/alerts/"+alertID, `{"phrase":"postgres","enabled":false}`, &alertResponse))
+	suite.Require().False(alertResponse.Alert.Enabled)
+
+	// Other users' alerts can't be changed
+	suite.Require().Equal(http.StatusNotFound, send(http.MethodPut, "/v1/alerts/"+uuid.NewString(), `{"phrase":"postgres"}`, nil))
+	suite.Require().Equal(http.StatusNotFound, send(http.MethodDelete, "/v1/alerts/"+uuid.NewString(), "", nil))
+
+	suite.Require().Equal(http.StatusOK, send(http.MethodDelete, "/v1/alerts/"+alertID, "", nil))
+	suite.Require().Equal(http.StatusOK, send(http.MethodGet, "/v1/alerts", "", &listResponse))
+	suite.Require().Empty(listResponse.Alerts)
+}
+
+func (suite *APITestSuite) TestOutputFeeds() {
+	feedID := suite.createFeed("Test Feed for Output Feeds", "http://example.com/rss/feed31.xml")
+	starred := suite.createPost(feedID, "Starred output post", "https://example.com/out/starred")
+	tagged := suite.createPost(feedID, "Tagged output post", "https://example.com/out/tagged")
+
+	send := func(method, path, body string, dst any) int {
+		req, err := http.NewRequest(method, suite.server.URL+path, strings.NewReader(body))
+		suite.Require().NoError(err)
+		resp, err := suite.authenticatedClient.Do(req)
+		suite.Require().NoError(err)
+		defer resp.Body.Close()
+		if dst != nil && resp.StatusCode < 300 {
+			suite.Require().NoError(json.NewDecoder(resp.Body).Decode(dst))
+		}
+		return resp.StatusCode
+	}
+
+	// Output feeds are read without logging in
+	read := func(path string, header http.Header) (*http.Response, []byte) {
+		req, err := http.NewRequest(http.MethodGet, suite.server.URL+path, nil)
+		suite.Require().NoError(err)
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		resp, err := http.DefaultClient.Do(req)
+		suite.Require().NoError(err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		suite.Require().NoError(err)
+		return resp, body
+	}
+
+	suite.Require().Equal(http.StatusOK, send(http.MethodPut, fmt.Sprintf("/v1/posts/%s/star", starred), "", nil))
+	suite.Require().Equal(http.StatusOK, send(http.MethodPost, fmt.Sprintf("/v1/posts/%s/tags", tagged), `{"tags":["golang"]}`, nil))
+
+	// Invalid output feeds are rejected
+	suite.Require().Equal(http.StatusUnprocessableEntity, send(http.MethodPost, "/v1/output_feeds", `{"name":"River","source":"everything"}`, nil))
+	suite.Require().Equal(http.StatusUnprocessableEntity, send(http.MethodPost, "/v1/output_feeds", `{"name":"River","source":"tag"}`, nil))
+	suite.Require().Equal(http.StatusUnprocessableEntity, send(http.MethodPost, "/v1/output_feeds", fmt.Sprintf(`{"name":"River","source":"folder","folder_id":"%s"}`, uuid.New()), nil))
+	suite.Require().Equal(http.StatusUnprocessableEntity, send(http.MethodPost, "/v1/output_feeds", fmt.Sprintf(`{"name":"River","source":"saved_search","saved_search_id":"%s"}`, uuid.New()), nil))
+
+	type outputFeed struct {
+		ID     string            `json:"id"`
+		Source string            `json:"source"`
+		Tag    *string           `json:"tag"`
+		URLs   map[string]string `json:"urls"`
+	}
+	// Each response is decoded afresh, as the URL maps would otherwise be shared
+	save := func(method, path, body string, status int) outputFeed {
+		var response struct {
+			OutputFeed outputFeed `json:"OutputFeed"`
+		}
+		suite.Require().Equal(status, send(method, path, body, &response))
+		return response.OutputFeed
+	}
+	all := save(http.MethodPost, "/v1/output_feeds", `{"name":"Everything","source":"all","tag":"ignored"}`, http.StatusCreated)
+	suite.Require().Nil(all.Tag)
+	suite.Require().Len(all.URLs, 3)
+	suite.Require().True(strings.HasSuffix(all.URLs["atom"], ".atom"))
+
+	stars := save(http.MethodPost, "/v1/output_feeds", `{"name":"Starred","source":"starred"}`, http.StatusCreated)
+
+	tag := save(http.MethodPost, "/v1/output_feeds", `{"name":"Go","source":"tag","tag":" Golang "}`, http.StatusCreated)
+	suite.Require().Equal("golang", *tag.Tag)
+
+	var listResponse struct {
+		OutputFeeds []outputFeed `json:"OutputFeeds"`
+	}
+	suite.Require().Equal(http.StatusOK, send(http.MethodGet, "/v1/output_feeds", "", &listResponse))
+	suite.Require().Len(listResponse.OutputFeeds, 3)
+
+	// Each format lists the source's posts
+	resp, body := read(all.URLs["atom"], nil)
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+	suite.Require().Equal("application/atom+xml; charset=utf-8", resp.Header.Get("Content-Type"))
+	var atom struct {
+		Title   string `xml:"title"`
+		Entries []struct {
+			Title  string `xml:"title"`
+			Source string `xml:"source>title"`
+		} `xml:"entry"`
+	}
+	suite.Require().NoError(xml.Unmarshal(body, &atom))
+	suite.Require().Equal("Everything", atom.Title)
+	suite.Require().Len(atom.Entries, 2)
+	suite.Require().Equal("Test Feed for Output Feeds", atom.Entries[0].Source)
+
+	resp, body = read(stars.URLs["rss"], nil)
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+	var rss struct {
+		Items []struct {
+			Title string `xml:"title"`
+		} `xml:"channel>item"`
+	}
+	suite.Require().NoError(xml.Unmarshal(body, &rss))
+	suite.Require().Len(rss.Items, 1)
+	suite.Require().Equal("Starred output post", rss.Items[0].Title)
+
+	resp, body = read(tag.URLs["json"], nil)
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+	var jsonFeed struct {
+		Items []struct {
+			Title string `json:"title"`
+		} `json:"items"`
+	}
+	suite.Require().NoError(json.Unmarshal(body, &jsonFeed))
+	suite.Require().Len(jsonFeed.Items, 1)
+	suite.Require().Equal("Tagged output post", jsonFeed.Items[0].Title)
+
+	// Unchanged feeds are answered with 304 Not Modified
+	etag := resp.Header.Get("ETag")
+	suite.Require().NotEmpty(etag)
+	suite.Require().Empty(resp.Header.Get("Last-Modified"))
+	resp, _ = read(tag.URLs["json"], http.Header{"If-None-Match": {etag}})
+	suite.Require().Equal(http.StatusNotModified, resp.StatusCode)
+
+	// Starring doesn't update any post, so only the ETag tells it changed
+	resp, _ = read(stars.URLs["rss"], nil)
+	starredETag := resp.Header.Get("ETag")
+	suite.Require().Equal(http.StatusOK, send(http.MethodDelete, fmt.Sprintf("/v1/posts/%s/star", starred), "", nil))
+	resp, _ = read(stars.URLs["rss"], http.Header{"If-None-Match": {starredETag}})
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+	resp, _ = read(stars.URLs["rss"], http.Header{"If-Modified-Since": {time.Now().UTC().Add(time.Hour).Format(http.TimeFormat)}})
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+	resp, _ = read(tag.URLs["json"], http.Header{"If-None-Match": {`"stale"`}})
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	// Unknown tokens and formats aren't found
+	resp, _ = read("/v1/out/unknown.atom", nil)
+	suite.Require().Equal(http.StatusNotFound, resp.StatusCode)
+	resp, _ = read(strings.TrimSuffix(all.URLs["atom"], ".atom")+".opml", nil)
+	suite.Require().Equal(http.StatusNotFound, resp.StatusCode)
+
+	// Rotating the token revokes the old URLs
+	rotated := save(http.MethodPost, "/v1/output_feeds/"+all.ID+"/token", "", http.StatusOK)
+	suite.Require().NotEqual(all.URLs["atom"], rotated.URLs["atom"])
+	resp, _ = read(all.URLs["atom"], nil)
+	suite.Require().Equal(http.StatusNotFound, resp.StatusCode)
+	resp, _ = read(rotated.URLs["atom"], nil)
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	replaced := save(http.MethodPut, "/v1/output_feeds/"+tag.ID, `{"name":"Everything else","source":"all"}`, http.StatusOK)
+	suite.Require().Equal("all", replaced.Source)
+	suite.Require().Nil(replaced.Tag)
+
+	// Other users' output feeds can't be changed
+	suite.Require().Equal(http.StatusNotFound, send(http.MethodPut, "/v1/output_feeds/"+uuid.NewString(), `{"name":"River","source":"all"}`, nil))
+	suite.Require().Equal(http.StatusNotFound, send(http.MethodPost, "/v1/output_feeds/"+uuid.NewString()+"/token", "", nil))
+
+	suite.Require().Equal(http.StatusOK, send(http.MethodDelete, "/v1/output_feeds/"+stars.ID, "", nil))
+	resp, _ = read(stars.URLs["rss"], nil)
+	suite.Require().Equal(http.StatusNotFound, resp.StatusCode)
+}
+
+func (suite *APITestSuite) TestOPML() {
+	followed := suite.createFeed("Test Feed for OPML", "http://example.com/rss/feed32.xml")
+	unfollowed := suite.createFeed("Test Feed for OPML Unfollowed", "http://example.com/rss/feed33.xml")
+
+	send := func(method, path, contentType string, body io.Reader) *http.Response {
+		req, err := http.NewRequest(method, suite.server.URL+path, body)
+		suite.Require().NoError(err)
+		req.Header.Set("Content-Type", contentType)
+		resp, err := suite.authenticatedClient.Do(req)
+		suite.Require().NoError(err)
+		return resp
+	}
+
+	type result struct {
+		URL    string     `json:"url"`
+		Folder string     `json:"folder"`
+		Status string     `json:"status"`
+		FeedID *uuid.UUID `json:"feed_id"`
+		Error  string     `json:"error"`
+	}
+	importOPML := func(contentType string, body io.Reader) []result {
+		resp := send(http.MethodPost, "/v1/opml", contentType, body)
+		defer resp.Body.Close()
+		suite.Require().Equal(http.StatusOK, resp.StatusCode)
+
+		var response struct {
+			Results []result `json:"Results"`
+		}
+		suite.Require().NoError(json.NewDecoder(resp.Body).Decode(&response))
+		return response.Results
+	}
+
+	resp := send(http.MethodDelete, fmt.Sprintf("/v1/feed_follows/%s", unfollowed), "", nil)
+	resp.Body.Close()
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	results := importOPML("text/x-opml", strings.NewReader(`<?xml version="1.0"?>
+<opml version="2.0">
+  <head><title>Elsewhere</title></head>
+  <body>
+    <outline text="Already followed" xmlUrl="http://example.com/rss/feed32.xml"/>
+    <outline text="Imported">
+      <outline text="Renamed" xmlUrl="HTTP://EXAMPLE.COM/rss/feed33.xml"/>
+      <outline text="Brand new" xmlUrl="http://example.com/rss/feed34.xml"/>
+      <outline text="Nested">
+        <outline text="Deep" xmlUrl="http://example.com/rss/feed35.xml"/>
+      </outline>
+    </outline>
+    <outline text="Other">
+      <outline text="Brand new" xmlUrl="http://example.com/rss/feed34.xml"/>
+      <outline text="Broken" xmlUrl="ftp://example.com/feed.xml"/>
+    </outline>
+  </body>
+</opml>`))
+	suite.Require().Len(results, 6)
+
+	suite.Require().Equal("already_following", results[0].Status)
+	suite.Require().Equal(followed, *results[0].FeedID)
+
+	// Existing feeds are matched by their canonical URL
+	suite.Require().Equal("followed", results[1].Status)
+	suite.Require().Equal(unfollowed, *results[1].FeedID)
+	suite.Require().Equal("Imported", results[1].Folder)
+
+	suite.Require().Equal("created", results[2].Status)
+	suite.Require().Equal("created", results[3].Status)
+	suite.Require().Equal("Nested", results[3].Folder)
+
+	// Feeds listed twice are added to both folders
+	suite.Require().Equal("already_following", results[4].Status)
+	suite.Require().Equal(*results[2].FeedID, *results[4].FeedID)
+
+	suite.Require().Equal("failed", results[5].Status)
+	suite.Require().NotEmpty(results[5].Error)
+	suite.Require().Nil(results[5].FeedID)
+
+	var foldersResponse struct {
+		Folders []struct {
+			Name string `json:"name"`
+		} `json:"Folders"`
+	}
+	resp = send(http.MethodGet, "/v1/folders", "", nil)
+	suite.Require().NoError(json.NewDecoder(resp.Body).Decode(&foldersResponse))
+	resp.Body.Close()
+	suite.Require().Len(foldersResponse.Folders, 3)
+
+	// Exports list follows under their custom titles, in each of their folders
+	resp = send(http.MethodGet, "/v1/opml", "", nil)
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+	suite.Require().Equal("text/x-opml; charset=utf-8", resp.Header.Get("Content-Type"))
+	export, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	suite.Require().NoError(err)
+
+	entries, err := opml.Parse(bytes.NewReader(export))
+	suite.Require().NoError(err)
+	suite.Require().ElementsMatch([]opml.Entry{
+		{Title: "Renamed", URL: "http://example.com/rss/feed33.xml", Folder: "Imported"},
+		{Title: "Brand new", URL: "http://example.com/rss/feed34.xml", Folder: "Imported"},
+		{Title: "Deep", URL: "http://example.com/rss/feed35.xml", Folder: "Nested"},
+		{Title: "Brand new", URL: "http://example.com/rss/feed34.xml", Folder: "Other"},
+		{Title: "Test Feed for OPML", URL: "http://example.com/rss/feed32.xml"},
+	}, entries)
+
+	// Exports can be imported again as a file upload
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	part, err := writer.CreateFormFile("file", "subscriptions.opml")
+	suite.Require().NoError(err)
+	_, err = part.Write(export)
+	suite.Require().NoError(err)
+	suite.Require().NoError(writer.Close())
+
+	results = importOPML(writer.FormDataContentType(), &form)
+	suite.Require().Len(results, 5)
+	for _, result := range results {
+		suite.Require().Equal("already_following", result.Status)
+	}
+
+	resp = send(http.MethodPost, "/v1/opml", "text/x-opml", strings.NewReader("not opml"))
+	resp.Body.Close()
+	suite.Require().Equal(http.StatusBadRequest, resp.StatusCode)
+
+	resp = send(http.MethodPost, "/v1/opml", "text/x-opml", strings.NewReader(`<opml version="2.0"><body><outline text="Empty"/></body></opml>`))
+	resp.Body.Close()
+	suite.Require().Equal(http.StatusUnprocessableEntity, resp.StatusCode)
+
+	var tooMany strings.Builder
+	tooMany.WriteString(`<opml version="2.0"><body>`)
+	for i := 0; i <= 1000; i++ {
+		fmt.Fprintf(&tooMany, `<outline text="Feed" xmlUrl="http://example.com/rss/many%d.xml"/>`, i)
+	}
+	tooMany.WriteString(`</body></opml>`)
+	resp = send(http.MethodPost, "/v1/opml", "text/x-opml", strings.NewReader(tooMany.String()))
+	resp.Body.Close()
+	suite.Require().Equal(http.StatusUnprocessableEntity, resp.StatusCode)
+}
+
+func (suite *APITestSuite) TestGReader() {
+	feedID := suite.createFeed("Test Feed for GReader", "http://example.com/rss/feed36.xml")
+	firstID := suite.createPost(feedID, "First GReader Post", "http://example.com/greader/1")
+	suite.createPost(feedID, "Second GReader Post", "http://example.com/greader/2")
+
+	resp, err := http.PostForm(suite.server.URL+"/accounts/ClientLogin", url.Values{
+		"Email":  {suite.authenticatedUserEmail},
+		"Passwd": {"wrong password"},
+	})
+	suite.Require().NoError(err)
+	resp.Body.Close()
+	suite.Require().Equal(http.StatusUnauthorized, resp.StatusCode)
+
+	resp, err = http.PostForm(suite.server.URL+"/accounts/ClientLogin", url.Values{
+		"Email":  {suite.authenticatedUserEmail},
+		"Passwd": {"password123"},
+	})
+	suite.Require().NoError(err)
+	login, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	var auth string
+	for _, line := range strings.Split(string(login), "\n") {
+		if token, ok := strings.CutPrefix(line, "Auth="); ok {
+			auth = token
+		}
+	}
+	suite.Require().NotEmpty(auth)
+
+	send := func(method, path string, form url.Values) *http.Response {
+		req, err := http.NewRequest(method, suite.server.URL+path, strings.NewReader(form.Encode()))
+		suite.Require().NoError(err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Authorization", "GoogleLogin auth="+auth)
+		resp, err := http.DefaultClient.Do(req)
+		suite.Require().NoError(err)
+		return resp
+	}
+	get := func(path string, dst any) {
+		resp := send(http.MethodGet, path, nil)
+		defer resp.Body.Close()
+		suite.Require().Equal(http.StatusOK, resp.StatusCode, path)
+		suite.Require().NoError(json.NewDecoder(resp.Body).Decode(dst))
+	}
+	post := func(path string, form url.Values) {
+		resp := send(http.MethodPost, path, form)
+		resp.Body.Close()
+		suite.Require().Equal(http.StatusOK, resp.StatusCode, path)
+	}
+
+	resp = send(http.MethodGet, "/reader/api/0/token", nil)
+	resp.Body.Close()
+	suite.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	// Subscribing by URL creates the feed and puts it in the label
+	post("/reader/api/0/subscription/edit", url.Values{
+		"ac": {"subscribe"},
+		"s":  {"feed/http://example.com/rss/feed37.xml"},
+		"t":  {"Subscribed in a client"},
+		"a":  {"user/-/label/Clients"},
+	})
+	post("/reader/api/0/subscription/edit", url.Values{
+		"ac": {"edit"},
+		"s":  {fmt.Sprintf("feed/%s", feedID)},
+		"a":  {"user/-/label/Clients"},
+	})
+
+	var subscriptions struct {
+		Subscriptions []struct {
+			ID         string `json:"id"`
+			Title      string `json:"title"`
+			URL        string `json:"url"`
+			Categories []struct {
+				Label string `json:"label"`
+			} `json:"categories"`
+		} `json:"subscriptions"`
+	}
+	get("/reader/api/0/subscription/list", &subscriptions)
+	suite.Require().Len(subscriptions.Subscriptions, 2)
+	suite.Require().Equal("Subscribed in a client", subscriptions.Subscriptions[0].Title)
+	suite.Require().Equal("http://example.com/rss/feed37.xml", subscriptions.Subscriptions[0].URL)
+	suite.Require().Equal(fmt.Sprintf("feed/%s", feedID), subscriptions.Subscriptions[1].ID)
+	for _, subscription := range subscriptions.Subscriptions {
+		suite.Require().Len(subscription.Categories, 1)
+		suite.Require().Equal("Clients", subscription.Categories[0].Label)
+	}
+
+	post("/reader/api/0/subscription/edit", url.Values{
+		"ac": {"unsubscribe"},
+		"s":  {subscriptions.Subscriptions[0].ID},
+	})
+
+	type contents struct {
+		Items []struct {
+			ID         string   `json:"id"`
+			Title      string   `json:"title"`
+			Categories []string `json:"categories"`
+		} `json:"items"`
+		Continuation string `json:"continuation"`
+	}
+	var page contents
+	get("/reader/api/0/stream/contents/user/-/state/com.google/reading-list?n=1", &page)
+	suite.Require().Len(page.Items, 1)
+	suite.Require().Equal("Second GReader Post", page.Items[0].Title)
+	suite.Require().Contains(page.Items[0].Categories, "user/-/label/Clients")
+	suite.Require().NotEmpty(page.Continuation)
+
+	get("/reader/api/0/stream/contents/user/-/state/com.google/reading-list?n=1&c="+page.Continuation, &page)
+	suite.Require().Len(page.Items, 1)
+	suite.Require().Equal("First GReader Post", page.Items[0].Title)
+	suite.Require().Empty(page.Continuation)
+	firstItem := page.Items[0].ID
+
+	var refs struct {
+		ItemRefs []struct {
+			ID string `json:"id"`
+		} `json:"itemRefs"`
+	}
+	get("/reader/api/0/stream/items/ids?s=user/-/label/Clients&n=10", &refs)
+	suite.Require().Len(refs.ItemRefs, 2)
+
+	// Items are marked read and starred by either form of their ID
+	post("/reader/api/0/edit-tag", url.Values{
+		"i": {firstItem},
+		"a": {"user/-/state/com.google/read", "user/-/state/com.google/starred"},
+	})
+
+	var readAt, starredAt sql.NullTime
+	err = suite.tx.QueryRow("SELECT read_at, starred_at FROM post_states WHERE post_id = $1", firstID).Scan(&readAt, &starredAt)
+	suite.Require().NoError(err)
+	suite.Require().True(readAt.Valid)
+	suite.Require().True(starredAt.Valid)
+
+	get("/reader/api/0/stream/items/ids?s=user/-/state/com.google/starred", &refs)
+	suite.Require().Len(refs.ItemRefs, 1)
+
+	type unreadCounts struct {
+		Max          int64 `json:"max"`
+		UnreadCounts []struct {
+			ID    string `json:"id"`
+			Count int64  `json:"count"`
+		} `json:"unreadcounts"`
+	}
+	var counts unreadCounts
+	get("/reader/api/0/unread-count", &counts)
+	suite.Require().Equal(int64(1), counts.Max)
+
+	get("/reader/api/0/stream/items/ids?s=user/-/state/com.google/reading-list&xt=user/-/state/com.google/read", &refs)
+	suite.Require().Len(refs.ItemRefs, 1)
+
+	post("/reader/api/0/mark-all-as-read", url.Values{
+		"s": {"user/-/label/Clients"},
+	})
+	get("/reader/api/0/unread-count", &counts)
+	suite.Require().Equal(int64(0), counts.Max)
+
+	resp = send(http.MethodPost, "/reader/api/0/edit-tag", url.Values{"i": {"not an item"}})
+	resp.Body.Close()
+	suite.Require().Equal(http.StatusBadRequest, resp.StatusCode)
+
+	resp = send(http.MethodGet, "/reader/api/0/stream/contents/user/-/label/Missing", nil)
+	resp.Body.Close()
+	suite.Require().Equal(http.StatusNotFound, resp.StatusCode)
+
+	// Other clients' tokens aren't accepted as Google Reader logins
+	req, err := http.NewRequest(http.MethodGet, suite.server.URL+"/reader/api/0/token", nil)
+	suite.Require().NoError(err)
+	req.Header.Set("Authorization", "GoogleLogin auth="+suite.authenticatedClient.Transport.(*AuthenticatedTransport).AuthToken)
+	resp, err = http.DefaultClient.Do(req)
+	suite.Require().NoError(err)
+	resp.Body.Close()
+	suite.Require().Equal(http.StatusUnauthorized, resp.StatusCode)
+}
+
 func TestAPISuite(t *testing.T) {
 	suite.Run(t, new(APITestSuite))
 }
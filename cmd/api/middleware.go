@@ -1,9 +1,11 @@
 package main
 
 import (
+	"bufio"
 	"errors"
 	"expvar"
 	"fmt"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
@@ -96,11 +98,29 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 		}
 
 		headerParts := strings.Split(authorizationHeader, " ")
-		if len(headerParts) != 2 || headerParts[0] != "Bearer" {
+		if len(headerParts) != 2 {
 			app.invalidAuthenticationTokenResponse(w, r)
+			return
 		}
 
+		scope := data.ScopeActivation
 		token := headerParts[1]
+		switch headerParts[0] {
+		case "Bearer":
+		case "GoogleLogin":
+			// Google Reader clients send the token from ClientLogin as
+			// "GoogleLogin auth=<token>".
+			var found bool
+			token, found = strings.CutPrefix(token, "auth=")
+			if !found {
+				app.invalidAuthenticationTokenResponse(w, r)
+				return
+			}
+			scope = data.ScopeGReader
+		default:
+			app.invalidAuthenticationTokenResponse(w, r)
+			return
+		}
 
 		v := validator.New()
 		if data.ValidateTokenPlaintext(v, token); !v.Valid() {
@@ -108,9 +128,12 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 			return
 		}
 
-		user, err := data.GetForToken(r.Context(), data.ScopeActivation, token, app.db)
+		user, err := data.GetForToken(r.Context(), scope, token, app.db)
 		if err != nil {
 			switch {
+			case errors.Is(err, data.ErrRecordNotFound) && scope == data.ScopeGReader:
+				// Clients log in again when their token is rejected.
+				app.invalidAuthenticationTokenResponse(w, r)
 			case errors.Is(err, data.ErrRecordNotFound):
 				v.AddError("token", "invalid or expired activation token")
 				app.failedValidationResponse(w, r, v.Errors)
@@ -239,6 +262,16 @@ func (mw *metricsResponseWriter) Unwrap() http.ResponseWriter {
 	return mw.wrapped
 }
 
+// Hijack hands the connection over for protocols like WebSockets, which
+// can't find it through Unwrap.
+func (mw *metricsResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if !mw.headerWritten {
+		mw.statusCode = http.StatusSwitchingProtocols
+		mw.headerWritten = true
+	}
+	return http.NewResponseController(mw.wrapped).Hijack()
+}
+
 func (app *application) metrics(next http.Handler) http.Handler {
 	var (
 		totalRequestsRecieved            = expvar.NewInt("total_requests_recieved")
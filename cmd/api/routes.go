@@ -22,12 +22,108 @@ func (app *application) routes() http.Handler {
 
 	router.HandlerFunc(http.MethodPost, "/v1/feeds", app.requirePermission("feeds:write", app.HandlerFeedsCreate))
 	router.HandlerFunc(http.MethodGet, "/v1/feeds", app.requirePermission("feeds:read", app.HandlerFeedsGet))
+	router.HandlerFunc(http.MethodPut, "/v1/feeds/:feedID/retention", app.requirePermission("feeds:write", app.HandlerFeedRetentionUpdate))
+	router.HandlerFunc(http.MethodGet, "/v1/feeds/:feedID/fetches", app.requirePermission("feeds:read", app.HandlerFeedFetchesGet))
 
 	router.HandlerFunc(http.MethodPost, "/v1/feed_follows", app.requirePermission("feed_follows:write", app.HandlerFeedFollowsCreate))
 	router.HandlerFunc(http.MethodDelete, "/v1/feed_follows/:feedfollowID", app.requirePermission("feed_follows:write", app.HandlerFeedFollowsDelete))
+	router.HandlerFunc(http.MethodPatch, "/v1/feed_follows/:feedfollowID", app.requirePermission("feed_follows:write", app.HandlerFeedFollowsUpdate))
 	router.HandlerFunc(http.MethodGet, "/v1/feed_follows", app.requirePermission("feed_follows:read", app.HandlerFeedFollowsGet))
+	router.HandlerFunc(http.MethodGet, "/v1/feed_follows/counts", app.requirePermission("feed_follows:read", app.HandlerFeedFollowCountsGet))
+	router.HandlerFunc(http.MethodPut, "/v1/feed_follows/:feedfollowID/folders", app.requirePermission("feed_follows:write", app.HandlerFeedFollowFoldersUpdate))
+
+	router.HandlerFunc(http.MethodPost, "/v1/folders", app.requirePermission("feed_follows:write", app.HandlerFoldersCreate))
+	router.HandlerFunc(http.MethodGet, "/v1/folders", app.requirePermission("feed_follows:read", app.HandlerFoldersGet))
+	router.HandlerFunc(http.MethodPut, "/v1/folders/order", app.requirePermission("feed_follows:write", app.HandlerFoldersReorder))
+	router.HandlerFunc(http.MethodPatch, "/v1/folders/:folderID", app.requirePermission("feed_follows:write", app.HandlerFoldersUpdate))
+	router.HandlerFunc(http.MethodDelete, "/v1/folders/:folderID", app.requirePermission("feed_follows:write", app.HandlerFoldersDelete))
+
+	// Importing creates the feeds that don't exist yet.
+	router.HandlerFunc(http.MethodPost, "/v1/opml", app.requirePermission("feeds:write", app.HandlerOPMLImport))
+	router.HandlerFunc(http.MethodGet, "/v1/opml", app.requirePermission("feed_follows:read", app.HandlerOPMLExport))
 
 	router.HandlerFunc(http.MethodGet, "/v1/posts", app.requirePermission("posts:read", app.HandlerPostsGet))
+	router.HandlerFunc(http.MethodPut, "/v1/posts/:postID/read", app.requirePermission("posts:write", app.HandlerPostReadSet))
+	router.HandlerFunc(http.MethodDelete, "/v1/posts/:postID/read", app.requirePermission("posts:write", app.HandlerPostReadDelete))
+	router.HandlerFunc(http.MethodPut, "/v1/posts/:postID/star", app.requirePermission("posts:write", app.HandlerPostStarSet))
+	router.HandlerFunc(http.MethodDelete, "/v1/posts/:postID/star", app.requirePermission("posts:write", app.HandlerPostStarDelete))
+	router.HandlerFunc(http.MethodPost, "/v1/posts/:postID/tags", app.requirePermission("posts:write", app.HandlerPostTagsCreate))
+	router.HandlerFunc(http.MethodDelete, "/v1/posts/:postID/tags/:tag", app.requirePermission("posts:write", app.HandlerPostTagsDelete))
+
+	router.HandlerFunc(http.MethodGet, "/v1/tags", app.requirePermission("posts:read", app.HandlerTagsGet))
+
+	router.HandlerFunc(http.MethodPost, "/v1/saved_searches", app.requirePermission("posts:write", app.HandlerSavedSearchesCreate))
+	router.HandlerFunc(http.MethodGet, "/v1/saved_searches", app.requirePermission("posts:read", app.HandlerSavedSearchesGet))
+	router.HandlerFunc(http.MethodPut, "/v1/saved_searches/:savedSearchID", app.requirePermission("posts:write", app.HandlerSavedSearchesUpdate))
+	router.HandlerFunc(http.MethodDelete, "/v1/saved_searches/:savedSearchID", app.requirePermission("posts:write", app.HandlerSavedSearchesDelete))
+	router.HandlerFunc(http.MethodGet, "/v1/saved_searches/:savedSearchID/posts", app.requirePermission("posts:read", app.HandlerSavedSearchPostsGet))
+
+	router.HandlerFunc(http.MethodPost, "/v1/rules", app.requirePermission("posts:write", app.HandlerRulesCreate))
+	router.HandlerFunc(http.MethodGet, "/v1/rules", app.requirePermission("posts:read", app.HandlerRulesGet))
+	router.HandlerFunc(http.MethodPut, "/v1/rules/:ruleID", app.requirePermission("posts:write", app.HandlerRulesUpdate))
+	router.HandlerFunc(http.MethodDelete, "/v1/rules/:ruleID", app.requirePermission("posts:write", app.HandlerRulesDelete))
+	router.HandlerFunc(http.MethodPost, "/v1/rules/:ruleID/apply", app.requirePermission("posts:write", app.HandlerRulesApply))
+
+	router.HandlerFunc(http.MethodGet, "/v1/stream", app.requirePermission("posts:read", app.HandlerStreamGet))
+	// WebSocket clients may authenticate after connecting, so the handler
+	// checks permissions itself.
+	router.HandlerFunc(http.MethodGet, "/v1/ws", app.HandlerWebSocket)
+
+	router.HandlerFunc(http.MethodGet, "/v1/notifications", app.requirePermission("posts:read", app.HandlerNotificationsGet))
+
+	router.HandlerFunc(http.MethodPost, "/v1/webhooks", app.requirePermission("posts:write", app.HandlerWebhooksCreate))
+	router.HandlerFunc(http.MethodGet, "/v1/webhooks", app.requirePermission("posts:read", app.HandlerWebhooksGet))
+	router.HandlerFunc(http.MethodGet, "/v1/webhooks/:webhookID", app.requirePermission("posts:read", app.HandlerWebhookGet))
+	router.HandlerFunc(http.MethodPut, "/v1/webhooks/:webhookID", app.requirePermission("posts:write", app.HandlerWebhooksUpdate))
+	router.HandlerFunc(http.MethodDelete, "/v1/webhooks/:webhookID", app.requirePermission("posts:write", app.HandlerWebhooksDelete))
+	router.HandlerFunc(http.MethodGet, "/v1/webhooks/:webhookID/deliveries", app.requirePermission("posts:read", app.HandlerWebhookDeliveriesGet))
+	router.HandlerFunc(http.MethodPost, "/v1/webhooks/:webhookID/test", app.requirePermission("posts:write", app.HandlerWebhookTest))
+
+	router.HandlerFunc(http.MethodPost, "/v1/digests", app.requirePermission("posts:write", app.HandlerDigestsCreate))
+	router.HandlerFunc(http.MethodGet, "/v1/digests", app.requirePermission("posts:read", app.HandlerDigestsGet))
+	router.HandlerFunc(http.MethodPut, "/v1/digests/:digestID", app.requirePermission("posts:write", app.HandlerDigestsUpdate))
+	router.HandlerFunc(http.MethodDelete, "/v1/digests/:digestID", app.requirePermission("posts:write", app.HandlerDigestsDelete))
+	// Unsubscribe links are opened from emails, where the token in the link
+	// stands in for logging in. Opening one only asks for confirmation.
+	router.HandlerFunc(http.MethodGet, "/v1/unsubscribe/:token", app.HandlerUnsubscribeConfirm)
+	router.HandlerFunc(http.MethodPost, "/v1/unsubscribe/:token", app.HandlerUnsubscribe)
+
+	router.HandlerFunc(http.MethodPost, "/v1/alerts", app.requirePermission("posts:write", app.HandlerAlertsCreate))
+	router.HandlerFunc(http.MethodGet, "/v1/alerts", app.requirePermission("posts:read", app.HandlerAlertsGet))
+	router.HandlerFunc(http.MethodPut, "/v1/alerts/:alertID", app.requirePermission("posts:write", app.HandlerAlertsUpdate))
+	router.HandlerFunc(http.MethodDelete, "/v1/alerts/:alertID", app.requirePermission("posts:write", app.HandlerAlertsDelete))
+
+	router.HandlerFunc(http.MethodPost, "/v1/output_feeds", app.requirePermission("posts:write", app.HandlerOutputFeedsCreate))
+	router.HandlerFunc(http.MethodGet, "/v1/output_feeds", app.requirePermission("posts:read", app.HandlerOutputFeedsGet))
+	router.HandlerFunc(http.MethodPut, "/v1/output_feeds/:outputFeedID", app.requirePermission("posts:write", app.HandlerOutputFeedsUpdate))
+	router.HandlerFunc(http.MethodDelete, "/v1/output_feeds/:outputFeedID", app.requirePermission("posts:write", app.HandlerOutputFeedsDelete))
+	router.HandlerFunc(http.MethodPost, "/v1/output_feeds/:outputFeedID/token", app.requirePermission("posts:write", app.HandlerOutputFeedTokenRotate))
+	// Feed readers can't log in, so the token in an output feed's URL stands
+	// in for its owner's credentials.
+	router.HandlerFunc(http.MethodGet, "/v1/out/:file", app.HandlerOutputFeedRender)
+
+	router.HandlerFunc(http.MethodPatch, "/v1/post_states", app.requirePermission("posts:write", app.HandlerPostStatesUpdate))
+	router.HandlerFunc(http.MethodPost, "/v1/post_states/mark_read", app.requirePermission("posts:write", app.HandlerPostStatesMarkRead))
+
+	// Google Reader clients log in with the user's password and send the
+	// token they get back in a GoogleLogin Authorization header.
+	router.HandlerFunc(http.MethodGet, "/accounts/ClientLogin", app.HandlerGReaderLogin)
+	router.HandlerFunc(http.MethodPost, "/accounts/ClientLogin", app.HandlerGReaderLogin)
+	router.HandlerFunc(http.MethodGet, "/reader/api/0/token", app.requirePermission("feed_follows:read", app.HandlerGReaderToken))
+	router.HandlerFunc(http.MethodGet, "/reader/api/0/user-info", app.requirePermission("feed_follows:read", app.HandlerGReaderUserInfo))
+	router.HandlerFunc(http.MethodGet, "/reader/api/0/subscription/list", app.requirePermission("feed_follows:read", app.HandlerGReaderSubscriptionsGet))
+	router.HandlerFunc(http.MethodPost, "/reader/api/0/subscription/edit", app.requirePermission("feeds:write", app.HandlerGReaderSubscriptionsEdit))
+	router.HandlerFunc(http.MethodPost, "/reader/api/0/subscription/quickadd", app.requirePermission("feeds:write", app.HandlerGReaderQuickAdd))
+	router.HandlerFunc(http.MethodGet, "/reader/api/0/tag/list", app.requirePermission("feed_follows:read", app.HandlerGReaderTagsGet))
+	router.HandlerFunc(http.MethodPost, "/reader/api/0/rename-tag", app.requirePermission("feed_follows:write", app.HandlerGReaderTagRename))
+	router.HandlerFunc(http.MethodPost, "/reader/api/0/disable-tag", app.requirePermission("feed_follows:write", app.HandlerGReaderTagDisable))
+	router.HandlerFunc(http.MethodGet, "/reader/api/0/unread-count", app.requirePermission("posts:read", app.HandlerGReaderUnreadCounts))
+	router.HandlerFunc(http.MethodGet, "/reader/api/0/stream/items/ids", app.requirePermission("posts:read", app.HandlerGReaderItemIDs))
+	router.HandlerFunc(http.MethodGet, "/reader/api/0/stream/items/contents", app.requirePermission("posts:read", app.HandlerGReaderItemContents))
+	router.HandlerFunc(http.MethodPost, "/reader/api/0/stream/items/contents", app.requirePermission("posts:read", app.HandlerGReaderItemContents))
+	router.HandlerFunc(http.MethodGet, "/reader/api/0/stream/contents/*streamID", app.requirePermission("posts:read", app.HandlerGReaderStreamContents))
+	router.HandlerFunc(http.MethodPost, "/reader/api/0/edit-tag", app.requirePermission("posts:write", app.HandlerGReaderEditTag))
+	router.HandlerFunc(http.MethodPost, "/reader/api/0/mark-all-as-read", app.requirePermission("posts:write", app.HandlerGReaderMarkAllRead))
 
 	router.Handler(http.MethodGet, "/debug/vars", expvar.Handler())
 
@@ -23,6 +23,10 @@ func (app *application) serve() error {
 		ErrorLog:     slog.NewLogLogger(app.logger.Handler(), slog.LevelError),
 	}
 
+	// Streams stay open until the client goes, so they're ended on shutdown
+	// rather than waited for.
+	srv.RegisterOnShutdown(app.stream.Close)
+
 	shutdownError := make(chan error)
 
 	go func() {
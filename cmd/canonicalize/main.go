@@ -0,0 +1,201 @@
+// Command canonicalize rewrites the URLs of feeds and posts saved before
+// migration 010 in their canonical form, which is how they're looked up and
+// kept unique since. Run it once after migrating:
+//
+//	DB=postgres://... go run ./cmd/canonicalize
+//
+// Feeds whose URLs are the same once canonical are merged into the oldest:
+// its follows, rules, saved searches and webhooks are moved over, along with
+// the posts it doesn't have yet. Posts of a feed whose URLs are the same are
+// merged the same way, keeping the oldest. Everything is done in one
+// transaction, so a failed run changes nothing and can be run again.
+package main
+
+import (
+	"database/sql"
+	"log"
+	"os"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/urlnorm"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+// row is a feed or post, in the order they were created.
+type row struct {
+	id    uuid.UUID
+	group uuid.UUID
+	url   string
+}
+
+// plan works out how to make the URLs of rows canonical. Rows in the same
+// group whose URLs are the same once canonical are merged into the first of
+// them, and the rows left whose URLs change are renamed. URLs that can't be
+// canonicalised are left as they are.
+func plan(rows []row) (merges map[uuid.UUID]uuid.UUID, renames map[uuid.UUID]string, skipped []row) {
+	type key struct {
+		group uuid.UUID
+		url   string
+	}
+
+	merges = map[uuid.UUID]uuid.UUID{}
+	renames = map[uuid.UUID]string{}
+	keepers := map[key]uuid.UUID{}
+	for _, r := range rows {
+		canonical, err := urlnorm.Canonical(r.url)
+		if err != nil {
+			skipped = append(skipped, r)
+			continue
+		}
+
+		k := key{group: r.group, url: canonical}
+		if keeper, ok := keepers[k]; ok {
+			merges[r.id] = keeper
+			continue
+		}
+		keepers[k] = r.id
+		if canonical != r.url {
+			renames[r.id] = canonical
+		}
+	}
+
+	// Renamed rows can't collide, as every other row with the same URL in
+	// their group is merged into them first.
+	return merges, renames, skipped
+}
+
+func main() {
+	dbURL := os.Getenv("DB")
+	if dbURL == "" {
+		log.Fatal("DB environment variable is not set")
+	}
+
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Rolling back after committing does nothing.
+	defer tx.Rollback() //#nosec G104
+
+	err = canonicalizeFeeds(tx)
+	if err != nil {
+		log.Fatal("Couldn't canonicalize feeds: ", err)
+	}
+
+	err = canonicalizePosts(tx)
+	if err != nil {
+		log.Fatal("Couldn't canonicalize posts: ", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// load returns the rows of a query selecting an ID, a group and a URL. They
+// are read in full before anything is changed, as a transaction can only run
+// one query at a time.
+func load(tx *sql.Tx, query string) ([]row, error) {
+	result, err := tx.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+
+	var rows []row
+	for result.Next() {
+		var r row
+		err := result.Scan(&r.id, &r.group, &r.url)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, r)
+	}
+	return rows, result.Err()
+}
+
+func canonicalizeFeeds(tx *sql.Tx) error {
+	// Feeds are all in one group, as their URLs are unique across feeds.
+	feeds, err := load(tx, `SELECT id, '00000000-0000-0000-0000-000000000000'::uuid, url FROM feeds ORDER BY created_at, id`)
+	if err != nil {
+		return err
+	}
+
+	merges, renames, skipped := plan(feeds)
+	for _, feed := range skipped {
+		log.Printf("Skipping feed %s, whose URL %q can't be canonicalized", feed.id, feed.url)
+	}
+
+	for duplicate, keeper := range merges {
+		statements := []string{
+			// Users already following the feed kept keep their own follow.
+			`UPDATE feed_follows SET feed_id = $2
+			WHERE feed_id = $1
+			  AND NOT EXISTS (SELECT 1 FROM feed_follows kept WHERE kept.feed_id = $2 AND kept.user_id = feed_follows.user_id)`,
+			`UPDATE posts SET feed_id = $2
+			WHERE feed_id = $1
+			  AND url NOT IN (SELECT url FROM posts WHERE feed_id = $2)`,
+			`UPDATE rules SET feed_id = $2 WHERE feed_id = $1`,
+			`UPDATE saved_searches SET feed_id = $2 WHERE feed_id = $1`,
+			`UPDATE webhooks SET feed_id = $2 WHERE feed_id = $1`,
+			// Everything else of the duplicate's, such as its fetch history,
+			// is deleted with it.
+			`DELETE FROM feeds WHERE id = $1`,
+		}
+		for _, statement := range statements {
+			_, err := tx.Exec(statement, duplicate, keeper)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for id, url := range renames {
+		_, err := tx.Exec(`UPDATE feeds SET url = $2 WHERE id = $1`, id, url)
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Merged %d feeds and canonicalized the URLs of %d", len(merges), len(renames))
+	return nil
+}
+
+func canonicalizePosts(tx *sql.Tx) error {
+	// Posts are loaded after feeds are merged, so they're grouped by the feed
+	// they ended up in.
+	posts, err := load(tx, `SELECT id, feed_id, url FROM posts ORDER BY created_at, id`)
+	if err != nil {
+		return err
+	}
+
+	merges, renames, skipped := plan(posts)
+	for _, post := range skipped {
+		log.Printf("Skipping post %s, whose URL %q can't be canonicalized", post.id, post.url)
+	}
+
+	for duplicate := range merges {
+		// Read states, tags and matches of the duplicate are deleted with it.
+		_, err := tx.Exec(`DELETE FROM posts WHERE id = $1`, duplicate)
+		if err != nil {
+			return err
+		}
+	}
+
+	for id, url := range renames {
+		_, err := tx.Exec(`UPDATE posts SET url = $2 WHERE id = $1`, id, url)
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Merged %d posts and canonicalized the URLs of %d", len(merges), len(renames))
+	return nil
+}
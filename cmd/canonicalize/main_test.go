@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlan(t *testing.T) {
+	feedA, feedB := uuid.New(), uuid.New()
+	rows := []row{
+		{id: uuid.New(), group: feedA, url: "HTTP://Example.com/post/"},
+		{id: uuid.New(), group: feedA, url: "http://example.com/post"},
+		{id: uuid.New(), group: feedA, url: "http://example.com/post?utm_source=feed"},
+		{id: uuid.New(), group: feedB, url: "http://example.com/post"},
+		{id: uuid.New(), group: feedB, url: "https://example.com/other"},
+		{id: uuid.New(), group: feedB, url: "ftp://example.com/file"},
+	}
+
+	merges, renames, skipped := plan(rows)
+
+	// Duplicates in the same group are merged into the oldest, which is
+	// renamed if its URL isn't canonical yet.
+	assert.Equal(t, map[uuid.UUID]uuid.UUID{rows[1].id: rows[0].id, rows[2].id: rows[0].id}, merges)
+	assert.Equal(t, map[uuid.UUID]string{rows[0].id: "http://example.com/post"}, renames)
+	assert.Equal(t, []row{rows[5]}, skipped)
+}
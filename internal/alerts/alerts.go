@@ -0,0 +1,239 @@
+package alerts
+
+import (
+	"context"
+	"database/sql"
+	"html"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	// Window is the least time between two emails for the same alert. Posts
+	// matching an alert within the window of its last email are coalesced
+	// into one email sent when the window ends.
+	Window = 15 * time.Minute
+
+	// MaxPosts is the most posts listed in one alert email.
+	MaxPosts = 20
+
+	// Lease is how long a claimed alert is reserved for the instance that
+	// claimed it.
+	Lease = 5 * time.Minute
+
+	// claimBatchSize is how many due alerts are claimed at a time.
+	claimBatchSize = 20
+)
+
+// Store is the subset of database.Queries alerts are matched and sent
+// through.
+type Store interface {
+	GetAlertsForFeed(ctx context.Context, feedID uuid.UUID) ([]database.Alert, error)
+	QueueAlertMatch(ctx context.Context, arg database.QueueAlertMatchParams) error
+	ClaimDueAlerts(ctx context.Context, arg database.ClaimDueAlertsParams) ([]database.ClaimDueAlertsRow, error)
+	GetAlertMatches(ctx context.Context, arg database.GetAlertMatchesParams) ([]database.GetAlertMatchesRow, error)
+	MarkAlertMatchesSent(ctx context.Context, arg database.MarkAlertMatchesSentParams) error
+	RecordAlertSent(ctx context.Context, arg database.RecordAlertSentParams) error
+}
+
+// Sender sends templated emails, as mailer.Mailer does.
+type Sender interface {
+	Send(recipient, templateFile string, data any) error
+}
+
+// Post is a post as it's listed in an alert email.
+type Post struct {
+	Title       string
+	Url         string
+	FeedName    string
+	PublishedAt time.Time
+}
+
+// Email is the data the alert template is rendered with.
+type Email struct {
+	Name   string
+	Phrase string
+	Posts  []Post
+	Total  int
+	More   int
+}
+
+var tags = regexp.MustCompile(`<[^>]*>`)
+
+// normalize returns text as it's matched against: lowercase plain text with
+// runs of whitespace collapsed to one space.
+func normalize(text string) string {
+	text = html.UnescapeString(tags.ReplaceAllString(text, " "))
+	return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
+
+// NormalizePhrase trims a phrase and collapses the whitespace inside it.
+func NormalizePhrase(phrase string) string {
+	return strings.Join(strings.Fields(phrase), " ")
+}
+
+// Matches reports whether a post has the phrase in its title or description,
+// ignoring case, markup and differences in whitespace.
+func Matches(phrase string, post database.Post) bool {
+	phrase = normalize(phrase)
+	if phrase == "" {
+		return false
+	}
+
+	return strings.Contains(normalize(post.Title), phrase) ||
+		strings.Contains(normalize(post.Description.String), phrase)
+}
+
+// Alerter emails users the new posts that match their alerts.
+type Alerter struct {
+	store  Store
+	mailer Sender
+}
+
+func New(store Store, mailer Sender) *Alerter {
+	return &Alerter{
+		store:  store,
+		mailer: mailer,
+	}
+}
+
+// PostCreated records a newly collected post against every alert of the
+// feed's followers that it matches, so it's emailed as soon as the alert's
+// window allows. It has the signature of a scraper hook.
+func (a *Alerter) PostCreated(ctx context.Context, feed database.Feed, post database.Post) {
+	alerts, err := a.store.GetAlertsForFeed(ctx, feed.ID)
+	if err != nil {
+		log.Printf("Couldn't get alerts for feed %s: %v", feed.Name, err)
+		return
+	}
+
+	for _, alert := range alerts {
+		if !Matches(alert.Phrase, post) {
+			continue
+		}
+
+		err := a.store.QueueAlertMatch(ctx, database.QueueAlertMatchParams{
+			AlertID:       alert.ID,
+			PostID:        post.ID,
+			Now:           time.Now().UTC(),
+			WindowSeconds: int32(Window / time.Second),
+		})
+		if err != nil {
+			log.Printf("Couldn't queue post %s for alert %s: %v", post.ID, alert.ID, err)
+		}
+	}
+}
+
+// Start sends due alerts every interval.
+func (a *Alerter) Start(interval time.Duration) {
+	log.Printf("Sending alerts every %s...", interval)
+	ticker := time.NewTicker(interval)
+
+	for ; ; <-ticker.C {
+		for {
+			sent, err := a.SendOnce(context.Background())
+			if err != nil {
+				log.Println("Couldn't send alerts", err)
+			}
+			if err != nil || sent < claimBatchSize {
+				break
+			}
+		}
+	}
+}
+
+// SendOnce claims a batch of due alerts and emails each its matching posts,
+// and returns how many it claimed. Alerts that can't be sent are retried
+// when the window has passed.
+func (a *Alerter) SendOnce(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+	alerts, err := a.store.ClaimDueAlerts(ctx, database.ClaimDueAlertsParams{
+		LeaseUntil: now.Add(Lease),
+		Now:        now,
+		Lim:        claimBatchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, alert := range alerts {
+		sent, err := a.send(ctx, alert, now)
+		if err != nil {
+			log.Printf("Couldn't send alert %s: %v", alert.ID, err)
+		}
+
+		lastSentAt := sql.NullTime{}
+		if sent {
+			lastSentAt = sql.NullTime{Time: now, Valid: true}
+		}
+
+		err = a.store.RecordAlertSent(ctx, database.RecordAlertSentParams{
+			LastSentAt: lastSentAt,
+			RetryAt:    now.Add(Window),
+			ID:         alert.ID,
+		})
+		if err != nil {
+			log.Printf("Couldn't reschedule alert %s: %v", alert.ID, err)
+		}
+	}
+
+	return len(alerts), nil
+}
+
+// send emails the posts that matched an alert up to now and marks them sent,
+// and reports whether there were any to send. Matches go with their posts,
+// so an alert whose posts were all deleted has none.
+func (a *Alerter) send(ctx context.Context, alert database.ClaimDueAlertsRow, now time.Time) (bool, error) {
+	rows, err := a.store.GetAlertMatches(ctx, database.GetAlertMatchesParams{
+		AlertID: alert.ID,
+		Until:   now,
+		Lim:     MaxPosts,
+	})
+	if err != nil {
+		return false, err
+	}
+	if len(rows) == 0 {
+		return false, nil
+	}
+
+	email := Email{
+		Name:   alert.Name,
+		Phrase: alert.Phrase,
+		Posts:  make([]Post, len(rows)),
+		Total:  int(rows[0].Count),
+		More:   int(rows[0].Count) - len(rows),
+	}
+	for i, row := range rows {
+		publishedAt := row.Post.CreatedAt
+		if row.Post.PublishedAt.Valid {
+			publishedAt = row.Post.PublishedAt.Time
+		}
+		email.Posts[i] = Post{
+			Title:       row.Post.Title,
+			Url:         row.Post.Url,
+			FeedName:    row.FeedName,
+			PublishedAt: publishedAt,
+		}
+	}
+
+	err = a.mailer.Send(alert.Email, "alert.tmpl", email)
+	if err != nil {
+		return false, err
+	}
+
+	err = a.store.MarkAlertMatchesSent(ctx, database.MarkAlertMatchesSentParams{
+		SentAt:  now,
+		AlertID: alert.ID,
+		Until:   now,
+	})
+	if err != nil {
+		return true, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,196 @@
+package alerts
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStore struct {
+	alerts   []database.Alert
+	queued   []database.QueueAlertMatchParams
+	claimed  []database.ClaimDueAlertsRow
+	matches  []database.GetAlertMatchesRow
+	marked   []database.MarkAlertMatchesSentParams
+	recorded []database.RecordAlertSentParams
+}
+
+func (f *fakeStore) GetAlertsForFeed(ctx context.Context, feedID uuid.UUID) ([]database.Alert, error) {
+	return f.alerts, nil
+}
+
+func (f *fakeStore) QueueAlertMatch(ctx context.Context, arg database.QueueAlertMatchParams) error {
+	f.queued = append(f.queued, arg)
+	return nil
+}
+
+func (f *fakeStore) ClaimDueAlerts(ctx context.Context, arg database.ClaimDueAlertsParams) ([]database.ClaimDueAlertsRow, error) {
+	claimed := f.claimed
+	f.claimed = nil
+	return claimed, nil
+}
+
+func (f *fakeStore) GetAlertMatches(ctx context.Context, arg database.GetAlertMatchesParams) ([]database.GetAlertMatchesRow, error) {
+	return f.matches, nil
+}
+
+func (f *fakeStore) MarkAlertMatchesSent(ctx context.Context, arg database.MarkAlertMatchesSentParams) error {
+	f.marked = append(f.marked, arg)
+	return nil
+}
+
+func (f *fakeStore) RecordAlertSent(ctx context.Context, arg database.RecordAlertSentParams) error {
+	f.recorded = append(f.recorded, arg)
+	return nil
+}
+
+type fakeSender struct {
+	recipients []string
+	templates  []string
+	emails     []Email
+	err        error
+}
+
+func (f *fakeSender) Send(recipient, templateFile string, data any) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.recipients = append(f.recipients, recipient)
+	f.templates = append(f.templates, templateFile)
+	f.emails = append(f.emails, data.(Email))
+	return nil
+}
+
+func TestMatches(t *testing.T) {
+	post := database.Post{
+		Title:       "Announcing Go 1.23",
+		Description: sql.NullString{String: "<p>Range over <em>function</em>\n iterators are here.</p>", Valid: true},
+	}
+
+	tests := []struct {
+		phrase string
+		want   bool
+	}{
+		{"go 1.23", true},
+		{"ANNOUNCING", true},
+		{"function iterators", true},
+		{"range over function", true},
+		{"go 1.24", false},
+		{"<em>", false},
+		{"", false},
+		{"  ", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.phrase, func(t *testing.T) {
+			assert.Equal(t, tt.want, Matches(tt.phrase, post))
+		})
+	}
+}
+
+func TestNormalizePhrase(t *testing.T) {
+	assert.Equal(t, "range over func", NormalizePhrase("  range \t over\nfunc "))
+}
+
+func TestPostCreated(t *testing.T) {
+	matching := database.Alert{ID: uuid.New(), Phrase: "postgres"}
+	other := database.Alert{ID: uuid.New(), Phrase: "sqlite"}
+	store := &fakeStore{alerts: []database.Alert{matching, other}}
+
+	post := database.Post{ID: uuid.New(), Title: "Tuning Postgres"}
+	New(store, &fakeSender{}).PostCreated(context.Background(), database.Feed{ID: uuid.New()}, post)
+
+	require.Len(t, store.queued, 1)
+	assert.Equal(t, matching.ID, store.queued[0].AlertID)
+	assert.Equal(t, post.ID, store.queued[0].PostID)
+	assert.Equal(t, int32(Window/time.Second), store.queued[0].WindowSeconds)
+}
+
+func matchRow(count int64, title string) database.GetAlertMatchesRow {
+	return database.GetAlertMatchesRow{
+		Count: count,
+		Post: database.Post{
+			ID:          uuid.New(),
+			CreatedAt:   time.Date(2024, 3, 20, 9, 0, 0, 0, time.UTC),
+			Title:       title,
+			Url:         "https://example.com/" + title,
+			PublishedAt: sql.NullTime{Time: time.Date(2024, 3, 19, 9, 0, 0, 0, time.UTC), Valid: true},
+		},
+		FeedName: "Example",
+	}
+}
+
+func TestSendOnce(t *testing.T) {
+	alert := database.ClaimDueAlertsRow{
+		ID:     uuid.New(),
+		Phrase: "postgres",
+		Email:  "ada@example.com",
+		Name:   "Ada",
+	}
+
+	t.Run("sends matching posts in one email", func(t *testing.T) {
+		store := &fakeStore{
+			claimed: []database.ClaimDueAlertsRow{alert},
+			matches: []database.GetAlertMatchesRow{matchRow(3, "one"), matchRow(3, "two")},
+		}
+		sender := &fakeSender{}
+
+		sent, err := New(store, sender).SendOnce(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, sent)
+
+		require.Len(t, sender.emails, 1)
+		assert.Equal(t, "ada@example.com", sender.recipients[0])
+		assert.Equal(t, "alert.tmpl", sender.templates[0])
+		email := sender.emails[0]
+		assert.Equal(t, "postgres", email.Phrase)
+		assert.Len(t, email.Posts, 2)
+		assert.Equal(t, "one", email.Posts[0].Title)
+		assert.Equal(t, "Example", email.Posts[0].FeedName)
+		assert.Equal(t, time.Date(2024, 3, 19, 9, 0, 0, 0, time.UTC), email.Posts[0].PublishedAt)
+		assert.Equal(t, 3, email.Total)
+		assert.Equal(t, 1, email.More)
+
+		require.Len(t, store.marked, 1)
+		assert.Equal(t, alert.ID, store.marked[0].AlertID)
+
+		require.Len(t, store.recorded, 1)
+		assert.True(t, store.recorded[0].LastSentAt.Valid)
+		assert.Equal(t, Window, store.recorded[0].RetryAt.Sub(store.recorded[0].LastSentAt.Time))
+	})
+
+	t.Run("skips alerts without posts", func(t *testing.T) {
+		store := &fakeStore{claimed: []database.ClaimDueAlertsRow{alert}}
+		sender := &fakeSender{}
+
+		_, err := New(store, sender).SendOnce(context.Background())
+		require.NoError(t, err)
+
+		assert.Empty(t, sender.emails)
+		assert.Empty(t, store.marked)
+		require.Len(t, store.recorded, 1)
+		assert.False(t, store.recorded[0].LastSentAt.Valid)
+	})
+
+	t.Run("retries alerts that can't be sent", func(t *testing.T) {
+		store := &fakeStore{
+			claimed: []database.ClaimDueAlertsRow{alert},
+			matches: []database.GetAlertMatchesRow{matchRow(1, "one")},
+		}
+
+		_, err := New(store, &fakeSender{err: errors.New("smtp down")}).SendOnce(context.Background())
+		require.NoError(t, err)
+
+		assert.Empty(t, store.marked)
+		require.Len(t, store.recorded, 1)
+		assert.False(t, store.recorded[0].LastSentAt.Valid)
+		assert.True(t, store.recorded[0].RetryAt.After(time.Now()))
+	})
+}
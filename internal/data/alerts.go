@@ -0,0 +1,36 @@
+package data
+
+import (
+	"time"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+type Alert struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	Phrase     string     `json:"phrase"`
+	Enabled    bool       `json:"enabled"`
+	LastSentAt *time.Time `json:"last_sent_at"`
+}
+
+func DatabaseAlertToAlert(alert database.Alert) Alert {
+	return Alert{
+		ID:         alert.ID,
+		CreatedAt:  alert.CreatedAt,
+		UpdatedAt:  alert.UpdatedAt,
+		Phrase:     alert.Phrase,
+		Enabled:    alert.Enabled,
+		LastSentAt: nullTimeToTimePtr(alert.LastSentAt),
+	}
+}
+
+func DatabaseAlertsToAlerts(alerts []database.Alert) []Alert {
+	result := make([]Alert, len(alerts))
+	for i, alert := range alerts {
+		result[i] = DatabaseAlertToAlert(alert)
+	}
+	return result
+}
@@ -0,0 +1,80 @@
+package data
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks a post's place in a sorted listing: the sort it was taken
+// from, the post's value for the sort key and its ID. Clients only ever see
+// cursors encoded, and should treat them as opaque.
+type Cursor struct {
+	Sort string     `json:"s"`
+	ID   uuid.UUID  `json:"i"`
+	Text string     `json:"t,omitempty"`
+	Time *time.Time `json:"d,omitempty"`
+	Rank float32    `json:"r,omitempty"`
+	Xid  int64      `json:"x,omitempty"`
+	Seq  int64      `json:"q,omitempty"`
+}
+
+// SortKey splits a sort into the column posts are ordered by and whether the
+// order is descending. Relevance is always highest first.
+func SortKey(sort string) (string, bool) {
+	if sort == "relevance" {
+		return sort, true
+	}
+	return strings.TrimPrefix(sort, "-"), strings.HasPrefix(sort, "-")
+}
+
+// PostCursor returns the cursor for a post in a listing ordered by sort.
+func PostCursor(sort string, post database.GetPostsForUserRow) Cursor {
+	c := Cursor{Sort: sort, ID: post.ID}
+
+	key, _ := SortKey(sort)
+	switch key {
+	case "title":
+		c.Text = post.Title
+	case "published_at":
+		// Posts without a publication date are sorted by when they were
+		// collected.
+		t := post.CreatedAt
+		if post.PublishedAt.Valid {
+			t = post.PublishedAt.Time
+		}
+		c.Time = &t
+	case "relevance":
+		c.Rank = post.Relevance
+	}
+
+	return c
+}
+
+func (c Cursor) Encode() string {
+	js, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+func DecodeCursor(s string) (Cursor, error) {
+	var c Cursor
+
+	js, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+
+	err = json.Unmarshal(js, &c)
+	if err != nil || c.ID == uuid.Nil {
+		return c, ErrInvalidCursor
+	}
+
+	return c, nil
+}
@@ -0,0 +1,49 @@
+package data
+
+import (
+	"strings"
+	"time"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+type Digest struct {
+	ID         uuid.UUID   `json:"id"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+	Schedule   string      `json:"schedule"`
+	Hour       int32       `json:"hour"`
+	Weekday    string      `json:"weekday"`
+	Timezone   string      `json:"timezone"`
+	FeedIDs    []uuid.UUID `json:"feed_ids"`
+	FolderIDs  []uuid.UUID `json:"folder_ids"`
+	Enabled    bool        `json:"enabled"`
+	NextSendAt time.Time   `json:"next_send_at"`
+	LastSentAt *time.Time  `json:"last_sent_at"`
+}
+
+func DatabaseDigestToDigest(digest database.Digest) Digest {
+	return Digest{
+		ID:         digest.ID,
+		CreatedAt:  digest.CreatedAt,
+		UpdatedAt:  digest.UpdatedAt,
+		Schedule:   digest.Schedule,
+		Hour:       digest.Hour,
+		Weekday:    strings.ToLower(time.Weekday(digest.Weekday).String()),
+		Timezone:   digest.Timezone,
+		FeedIDs:    digest.FeedIds,
+		FolderIDs:  digest.FolderIds,
+		Enabled:    digest.Enabled,
+		NextSendAt: digest.NextSendAt,
+		LastSentAt: nullTimeToTimePtr(digest.LastSentAt),
+	}
+}
+
+func DatabaseDigestsToDigests(digests []database.Digest) []Digest {
+	result := make([]Digest, len(digests))
+	for i, digest := range digests {
+		result[i] = DatabaseDigestToDigest(digest)
+	}
+	return result
+}
@@ -0,0 +1,52 @@
+package data
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+type FeedFetch struct {
+	ID         uuid.UUID `json:"id"`
+	FeedID     uuid.UUID `json:"feedid"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	DurationMS int64     `json:"duration_ms"`
+	StatusCode *int32    `json:"status_code"`
+	Bytes      int64     `json:"bytes"`
+	ItemsSeen  int32     `json:"items_seen"`
+	ItemsNew   int32     `json:"items_new"`
+	Error      *string   `json:"error"`
+}
+
+func DatabaseFeedFetchToFeedFetch(fetch database.GetFeedFetchesRow) FeedFetch {
+	return FeedFetch{
+		ID:         fetch.ID,
+		FeedID:     fetch.FeedID,
+		StartedAt:  fetch.StartedAt,
+		FinishedAt: fetch.FinishedAt,
+		DurationMS: fetch.FinishedAt.Sub(fetch.StartedAt).Milliseconds(),
+		StatusCode: nullInt32ToInt32Ptr(fetch.StatusCode),
+		Bytes:      fetch.Bytes,
+		ItemsSeen:  fetch.ItemsSeen,
+		ItemsNew:   fetch.ItemsNew,
+		Error:      nullStringToStringPtr(fetch.Error),
+	}
+}
+
+func DatabaseFeedFetchesToFeedFetches(fetches []database.GetFeedFetchesRow) []FeedFetch {
+	result := make([]FeedFetch, len(fetches))
+	for i, fetch := range fetches {
+		result[i] = DatabaseFeedFetchToFeedFetch(fetch)
+	}
+	return result
+}
+
+func nullInt32ToInt32Ptr(i sql.NullInt32) *int32 {
+	if i.Valid {
+		return &i.Int32
+	}
+	return nil
+}
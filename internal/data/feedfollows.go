@@ -8,11 +8,16 @@ import (
 )
 
 type FeedFollow struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	UserID    uuid.UUID `json:"userid"`
-	FeedID    uuid.UUID `json:"feedid"`
+	ID        uuid.UUID   `json:"id"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+	UserID    uuid.UUID   `json:"userid"`
+	FeedID    uuid.UUID   `json:"feedid"`
+	Title     *string     `json:"title"`
+	Priority  int32       `json:"priority"`
+	Muted     bool        `json:"muted"`
+	Notify    bool        `json:"notify"`
+	FolderIDs []uuid.UUID `json:"folder_ids"`
 }
 
 func DatabaseFeedFollowToFeedFollow(feed_follow database.FeedFollow) FeedFollow {
@@ -22,13 +27,39 @@ func DatabaseFeedFollowToFeedFollow(feed_follow database.FeedFollow) FeedFollow
 		UpdatedAt: feed_follow.UpdatedAt,
 		UserID:    feed_follow.UserID,
 		FeedID:    feed_follow.FeedID,
+		Title:     nullStringToStringPtr(feed_follow.Title),
+		Priority:  feed_follow.Priority,
+		Muted:     feed_follow.Muted,
+		Notify:    feed_follow.Notify,
+		FolderIDs: []uuid.UUID{},
 	}
 }
 
-func DatabaseFeedFollowsToFeedFollows(feedFollows []database.FeedFollow) []FeedFollow {
+func DatabaseFeedFollowsToFeedFollows(feedFollows []database.GetFeedFollowsRow) []FeedFollow {
 	result := make([]FeedFollow, len(feedFollows))
 	for i, feedFollow := range feedFollows {
-		result[i] = DatabaseFeedFollowToFeedFollow(feedFollow)
+		result[i] = DatabaseFeedFollowToFeedFollow(feedFollow.FeedFollow)
+		if feedFollow.FolderIds != nil {
+			result[i].FolderIDs = feedFollow.FolderIds
+		}
+	}
+	return result
+}
+
+type FeedCount struct {
+	FeedID uuid.UUID `json:"feedid"`
+	Total  int64     `json:"total"`
+	Unread int64     `json:"unread"`
+}
+
+func DatabaseFeedCountsToFeedCounts(counts []database.GetFeedFollowCountsRow) []FeedCount {
+	result := make([]FeedCount, len(counts))
+	for i, count := range counts {
+		result[i] = FeedCount{
+			FeedID: count.FeedID,
+			Total:  count.Total,
+			Unread: count.Unread,
+		}
 	}
 	return result
 }
@@ -8,13 +8,15 @@ import (
 )
 
 type Feed struct {
-	ID            uuid.UUID  `json:"id"`
-	CreatedAt     time.Time  `json:"created_at"`
-	UpdatedAt     time.Time  `json:"updated_at"`
-	Name          string     `json:"name"`
-	Url           string     `json:"url"`
-	UserID        uuid.UUID  `json:"userid"`
-	LastFetchedAt *time.Time `json:"last_fetched_at"`
+	ID                uuid.UUID  `json:"id"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	Name              string     `json:"name"`
+	Url               string     `json:"url"`
+	UserID            uuid.UUID  `json:"userid"`
+	LastFetchedAt     *time.Time `json:"last_fetched_at"`
+	RetentionDays     *int32     `json:"retention_days"`
+	RetentionMaxPosts *int32     `json:"retention_max_posts"`
 }
 
 func DatabaseFeedToFeed(feed database.Feed) Feed {
@@ -23,13 +25,15 @@ func DatabaseFeedToFeed(feed database.Feed) Feed {
 		lastFetchedAt = &feed.LastFetchedAt.Time
 	}
 	return Feed{
-		ID:            feed.ID,
-		CreatedAt:     feed.CreatedAt,
-		UpdatedAt:     feed.UpdatedAt,
-		LastFetchedAt: lastFetchedAt,
-		Name:          feed.Name,
-		Url:           feed.Url,
-		UserID:        feed.UserID,
+		ID:                feed.ID,
+		CreatedAt:         feed.CreatedAt,
+		UpdatedAt:         feed.UpdatedAt,
+		LastFetchedAt:     lastFetchedAt,
+		Name:              feed.Name,
+		Url:               feed.Url,
+		UserID:            feed.UserID,
+		RetentionDays:     nullInt32ToInt32Ptr(feed.RetentionDays),
+		RetentionMaxPosts: nullInt32ToInt32Ptr(feed.RetentionMaxPosts),
 	}
 }
 
@@ -0,0 +1,52 @@
+package data
+
+import (
+	"time"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+type Folder struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Name      string    `json:"name"`
+	Position  int32     `json:"position"`
+}
+
+func DatabaseFolderToFolder(folder database.Folder) Folder {
+	return Folder{
+		ID:        folder.ID,
+		CreatedAt: folder.CreatedAt,
+		UpdatedAt: folder.UpdatedAt,
+		Name:      folder.Name,
+		Position:  folder.Position,
+	}
+}
+
+func DatabaseFoldersToFolders(folders []database.Folder) []Folder {
+	result := make([]Folder, len(folders))
+	for i, folder := range folders {
+		result[i] = DatabaseFolderToFolder(folder)
+	}
+	return result
+}
+
+type FolderCount struct {
+	FolderID uuid.UUID `json:"folder_id"`
+	Total    int64     `json:"total"`
+	Unread   int64     `json:"unread"`
+}
+
+func DatabaseFolderCountsToFolderCounts(counts []database.GetFolderCountsRow) []FolderCount {
+	result := make([]FolderCount, len(counts))
+	for i, count := range counts {
+		result[i] = FolderCount{
+			FolderID: count.FolderID,
+			Total:    count.Total,
+			Unread:   count.Unread,
+		}
+	}
+	return result
+}
@@ -0,0 +1,79 @@
+package data
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"time"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/syndication"
+	"github.com/google/uuid"
+)
+
+// Sources of the posts in an output feed.
+const (
+	OutputSourceAll         = "all"
+	OutputSourceFolder      = "folder"
+	OutputSourceStarred     = "starred"
+	OutputSourceTag         = "tag"
+	OutputSourceSavedSearch = "saved_search"
+)
+
+var OutputSources = []string{OutputSourceAll, OutputSourceFolder, OutputSourceStarred, OutputSourceTag, OutputSourceSavedSearch}
+
+// OutputFeed includes the feed's secret URLs, one per format, which anyone
+// who has them can read.
+type OutputFeed struct {
+	ID            uuid.UUID         `json:"id"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+	Name          string            `json:"name"`
+	Source        string            `json:"source"`
+	FolderID      *uuid.UUID        `json:"folder_id"`
+	Tag           *string           `json:"tag"`
+	SavedSearchID *uuid.UUID        `json:"saved_search_id"`
+	URLs          map[string]string `json:"urls"`
+}
+
+// OutputFeedURL returns the address of an output feed in format.
+func OutputFeedURL(baseURL, token, format string) string {
+	return baseURL + "/v1/out/" + token + "." + format
+}
+
+func DatabaseOutputFeedToOutputFeed(feed database.OutputFeed, baseURL string) OutputFeed {
+	urls := make(map[string]string, len(syndication.Formats))
+	for _, format := range syndication.Formats {
+		urls[format] = OutputFeedURL(baseURL, feed.Token, format)
+	}
+
+	return OutputFeed{
+		ID:            feed.ID,
+		CreatedAt:     feed.CreatedAt,
+		UpdatedAt:     feed.UpdatedAt,
+		Name:          feed.Name,
+		Source:        feed.Source,
+		FolderID:      nullUUIDToUUIDPtr(feed.FolderID),
+		Tag:           nullStringToStringPtr(feed.Tag),
+		SavedSearchID: nullUUIDToUUIDPtr(feed.SavedSearchID),
+		URLs:          urls,
+	}
+}
+
+func DatabaseOutputFeedsToOutputFeeds(feeds []database.OutputFeed, baseURL string) []OutputFeed {
+	result := make([]OutputFeed, len(feeds))
+	for i, feed := range feeds {
+		result[i] = DatabaseOutputFeedToOutputFeed(feed, baseURL)
+	}
+	return result
+}
+
+// NewOutputFeedToken returns a random token for an output feed's URLs.
+func NewOutputFeedToken() (string, error) {
+	randomBytes := make([]byte, 20)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", err
+	}
+
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes), nil
+}
@@ -0,0 +1,35 @@
+package data
+
+import (
+	"time"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+// PostState is a user's read, starred and hidden state for a post.
+type PostState struct {
+	PostID    uuid.UUID  `json:"post_id"`
+	ReadAt    *time.Time `json:"read_at"`
+	StarredAt *time.Time `json:"starred_at"`
+	HiddenAt  *time.Time `json:"hidden_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+func DatabasePostStateToPostState(state database.PostState) PostState {
+	return PostState{
+		PostID:    state.PostID,
+		ReadAt:    nullTimeToTimePtr(state.ReadAt),
+		StarredAt: nullTimeToTimePtr(state.StarredAt),
+		HiddenAt:  nullTimeToTimePtr(state.HiddenAt),
+		UpdatedAt: state.UpdatedAt,
+	}
+}
+
+func DatabasePostStatesToPostStates(states []database.PostState) []PostState {
+	result := make([]PostState, len(states))
+	for i, state := range states {
+		result[i] = DatabasePostStateToPostState(state)
+	}
+	return result
+}
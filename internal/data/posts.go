@@ -9,27 +9,49 @@ import (
 )
 
 type Post struct {
-	ID          uuid.UUID  `json:"id"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
-	Title       string     `json:"title"`
-	Url         string     `json:"url"`
-	Description *string    `json:"description"`
-	PublishedAt *time.Time `json:"published_at"`
-	FeedID      uuid.UUID  `json:"feedid"`
-	TotalCount  int64
+	ID             uuid.UUID  `json:"id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	Title          string     `json:"title"`
+	Url            string     `json:"url"`
+	Description    *string    `json:"description"`
+	PublishedAt    *time.Time `json:"published_at"`
+	FeedID         uuid.UUID  `json:"feedid"`
+	Author         *string    `json:"author"`
+	EnclosureURL   *string    `json:"enclosure_url"`
+	EnclosureType  *string    `json:"enclosure_type"`
+	Language       *string    `json:"language"`
+	ClusterID      uuid.UUID  `json:"cluster_id"`
+	DuplicateCount int64      `json:"duplicate_count"`
+	ReadAt         *time.Time `json:"read_at"`
+	StarredAt      *time.Time `json:"starred_at"`
+	HiddenAt       *time.Time `json:"hidden_at"`
+	Tags           []string   `json:"tags"`
+	Snippet        *string    `json:"snippet"`
+	TotalCount     int64
 }
 
 func DatabasePostToPost(post database.GetPostsForUserRow) Post {
 	return Post{
-		ID:          post.ID,
-		CreatedAt:   post.CreatedAt,
-		UpdatedAt:   post.UpdatedAt,
-		Title:       post.Title,
-		Url:         post.Url,
-		Description: nullStringToStringPtr(post.Description),
-		PublishedAt: nullTimeToTimePtr(post.PublishedAt),
-		FeedID:      post.FeedID,
+		ID:             post.ID,
+		CreatedAt:      post.CreatedAt,
+		UpdatedAt:      post.UpdatedAt,
+		Title:          post.Title,
+		Url:            post.Url,
+		Description:    nullStringToStringPtr(post.Description),
+		PublishedAt:    nullTimeToTimePtr(post.PublishedAt),
+		FeedID:         post.FeedID,
+		Author:         nullStringToStringPtr(post.Author),
+		EnclosureURL:   nullStringToStringPtr(post.EnclosureUrl),
+		EnclosureType:  nullStringToStringPtr(post.EnclosureType),
+		Language:       nullStringToStringPtr(post.Language),
+		ClusterID:      post.ClusterID,
+		DuplicateCount: post.DuplicateCount,
+		ReadAt:         nullTimeToTimePtr(post.ReadAt),
+		StarredAt:      nullTimeToTimePtr(post.StarredAt),
+		HiddenAt:       nullTimeToTimePtr(post.HiddenAt),
+		Tags:           post.Tags,
+		Snippet:        nullStringToStringPtr(post.Snippet),
 	}
 }
 
@@ -41,6 +63,29 @@ func DatabasePostsToPosts(posts []database.GetPostsForUserRow) []Post {
 	return result
 }
 
+// DatabaseNewPostToPost converts a post from the new posts stream. Hidden
+// posts never reach the stream, and duplicates aren't counted there.
+func DatabaseNewPostToPost(row database.GetNewPostsForUserRow) Post {
+	return Post{
+		ID:            row.Post.ID,
+		CreatedAt:     row.Post.CreatedAt,
+		UpdatedAt:     row.Post.UpdatedAt,
+		Title:         row.Post.Title,
+		Url:           row.Post.Url,
+		Description:   nullStringToStringPtr(row.Post.Description),
+		PublishedAt:   nullTimeToTimePtr(row.Post.PublishedAt),
+		FeedID:        row.Post.FeedID,
+		Author:        nullStringToStringPtr(row.Post.Author),
+		EnclosureURL:  nullStringToStringPtr(row.Post.EnclosureUrl),
+		EnclosureType: nullStringToStringPtr(row.Post.EnclosureType),
+		Language:      nullStringToStringPtr(row.Post.Language),
+		ClusterID:     row.Post.ClusterID,
+		ReadAt:        nullTimeToTimePtr(row.ReadAt),
+		StarredAt:     nullTimeToTimePtr(row.StarredAt),
+		Tags:          row.Tags,
+	}
+}
+
 func nullTimeToTimePtr(t sql.NullTime) *time.Time {
 	if t.Valid {
 		return &t.Time
@@ -54,3 +99,10 @@ func nullStringToStringPtr(s sql.NullString) *string {
 	}
 	return nil
 }
+
+func nullUUIDToUUIDPtr(id uuid.NullUUID) *uuid.UUID {
+	if id.Valid {
+		return &id.UUID
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package data
+
+import (
+	"time"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/rules"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/validator"
+	"github.com/google/uuid"
+)
+
+type Rule struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Name      string     `json:"name"`
+	FeedID    *uuid.UUID `json:"feed_id"`
+	Field     string     `json:"field"`
+	MatchType string     `json:"match_type"`
+	Pattern   string     `json:"pattern"`
+	Actions   []string   `json:"actions"`
+	Tag       *string    `json:"tag"`
+	Enabled   bool       `json:"enabled"`
+}
+
+func DatabaseRuleToRule(rule database.Rule) Rule {
+	return Rule{
+		ID:        rule.ID,
+		CreatedAt: rule.CreatedAt,
+		UpdatedAt: rule.UpdatedAt,
+		Name:      rule.Name,
+		FeedID:    nullUUIDToUUIDPtr(rule.FeedID),
+		Field:     rule.Field,
+		MatchType: rule.MatchType,
+		Pattern:   rule.Pattern,
+		Actions:   rule.Actions,
+		Tag:       nullStringToStringPtr(rule.Tag),
+		Enabled:   rule.Enabled,
+	}
+}
+
+func DatabaseRulesToRules(rules []database.Rule) []Rule {
+	result := make([]Rule, len(rules))
+	for i, rule := range rules {
+		result[i] = DatabaseRuleToRule(rule)
+	}
+	return result
+}
+
+// ValidateRule checks a rule's matching and actions. The tag must already be
+// normalised, and is required exactly when the tag action is used.
+func ValidateRule(v *validator.Validator, field, matchType, pattern string, actions []string, tag string) {
+	v.Check(validator.PermittedValue(field, rules.Fields...), "Field", "must be one of any, title, description, author or feed")
+	v.Check(validator.PermittedValue(matchType, rules.MatchTypes...), "MatchType", "must be either text or regex")
+	if v.Valid() {
+		_, err := rules.Compile(field, matchType, pattern)
+		v.Check(err == nil, "Pattern", "must be a valid regular expression")
+	}
+
+	seen := make(map[string]bool, len(actions))
+	for _, action := range actions {
+		v.Check(validator.PermittedValue(action, rules.Actions...), "Actions", "must only contain hide, mark_read, star, tag or notify")
+		v.Check(!seen[action], "Actions", "must not contain duplicate values")
+		seen[action] = true
+	}
+
+	v.Check(!seen[rules.ActionTag] || tag != "", "Tag", "must be provided for the tag action")
+	v.Check(seen[rules.ActionTag] || tag == "", "Tag", "must only be provided for the tag action")
+	ValidateTags(v, "Tag", []string{tag})
+}
+
+type Notification struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	PostID    uuid.UUID `json:"post_id"`
+	RuleID    uuid.UUID `json:"rule_id"`
+	Title     string    `json:"title"`
+	Url       string    `json:"url"`
+	FeedID    uuid.UUID `json:"feed_id"`
+}
+
+func DatabaseNotificationsToNotifications(notifications []database.GetNotificationsRow) []Notification {
+	result := make([]Notification, len(notifications))
+	for i, notification := range notifications {
+		result[i] = Notification{
+			ID:        notification.ID,
+			CreatedAt: notification.CreatedAt,
+			PostID:    notification.PostID,
+			RuleID:    notification.RuleID,
+			Title:     notification.Title,
+			Url:       notification.Url,
+			FeedID:    notification.FeedID,
+		}
+	}
+	return result
+}
@@ -0,0 +1,65 @@
+package data
+
+import (
+	"time"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+type SavedSearch struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Name      string     `json:"name"`
+	Query     string     `json:"query"`
+	FeedID    *uuid.UUID `json:"feed_id"`
+	FolderID  *uuid.UUID `json:"folder_id"`
+	Tags      []string   `json:"tags"`
+	TagMode   string     `json:"tag_mode"`
+}
+
+func DatabaseSavedSearchToSavedSearch(search database.SavedSearch) SavedSearch {
+	tagMode := "any"
+	if search.MatchAllTags {
+		tagMode = "all"
+	}
+
+	return SavedSearch{
+		ID:        search.ID,
+		CreatedAt: search.CreatedAt,
+		UpdatedAt: search.UpdatedAt,
+		Name:      search.Name,
+		Query:     search.Query,
+		FeedID:    nullUUIDToUUIDPtr(search.FeedID),
+		FolderID:  nullUUIDToUUIDPtr(search.FolderID),
+		Tags:      search.Tags,
+		TagMode:   tagMode,
+	}
+}
+
+func DatabaseSavedSearchesToSavedSearches(searches []database.SavedSearch) []SavedSearch {
+	result := make([]SavedSearch, len(searches))
+	for i, search := range searches {
+		result[i] = DatabaseSavedSearchToSavedSearch(search)
+	}
+	return result
+}
+
+type SavedSearchCount struct {
+	SavedSearchID uuid.UUID `json:"saved_search_id"`
+	Total         int64     `json:"total"`
+	Unread        int64     `json:"unread"`
+}
+
+func DatabaseSavedSearchCountsToSavedSearchCounts(counts []database.GetSavedSearchCountsRow) []SavedSearchCount {
+	result := make([]SavedSearchCount, len(counts))
+	for i, count := range counts {
+		result[i] = SavedSearchCount{
+			SavedSearchID: count.SavedSearchID,
+			Total:         count.Total,
+			Unread:        count.Unread,
+		}
+	}
+	return result
+}
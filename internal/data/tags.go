@@ -0,0 +1,49 @@
+package data
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/validator"
+)
+
+type Tag struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+func DatabaseTagsToTags(tags []database.GetTagsForUserRow) []Tag {
+	result := make([]Tag, len(tags))
+	for i, tag := range tags {
+		result[i] = Tag{
+			Name:  tag.Name,
+			Count: tag.Count,
+		}
+	}
+	return result
+}
+
+// NormalizeTags trims and lower-cases tag names and drops blanks and
+// duplicates, so "Go", " go" and "GO" are the same tag.
+func NormalizeTags(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		result = append(result, name)
+	}
+	return result
+}
+
+func ValidateTags(v *validator.Validator, key string, names []string) {
+	v.Check(len(names) <= 20, key, "must not contain more than 20 tags")
+	for _, name := range names {
+		v.Check(utf8.RuneCountInString(name) <= 50, key, "must not contain tags longer than 50 characters")
+		v.Check(!strings.Contains(name, ","), key, "must not contain commas")
+	}
+}
@@ -15,6 +15,7 @@ import (
 const (
 	ScopeActivation     = "activation"
 	ScopeAuthentication = "authentication"
+	ScopeGReader        = "greader"
 )
 
 type Token struct {
@@ -0,0 +1,88 @@
+package data
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+// Webhook leaves out the secret, which is only ever sent by the user.
+type Webhook struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Url       string     `json:"url"`
+	Format    string     `json:"format"`
+	FeedID    *uuid.UUID `json:"feed_id"`
+	FolderID  *uuid.UUID `json:"folder_id"`
+	Keywords  []string   `json:"keywords"`
+	Enabled   bool       `json:"enabled"`
+}
+
+func DatabaseWebhookToWebhook(hook database.Webhook) Webhook {
+	return Webhook{
+		ID:        hook.ID,
+		CreatedAt: hook.CreatedAt,
+		UpdatedAt: hook.UpdatedAt,
+		Url:       hook.Url,
+		Format:    hook.Format,
+		FeedID:    nullUUIDToUUIDPtr(hook.FeedID),
+		FolderID:  nullUUIDToUUIDPtr(hook.FolderID),
+		Keywords:  hook.Keywords,
+		Enabled:   hook.Enabled,
+	}
+}
+
+func DatabaseWebhooksToWebhooks(hooks []database.Webhook) []Webhook {
+	result := make([]Webhook, len(hooks))
+	for i, hook := range hooks {
+		result[i] = DatabaseWebhookToWebhook(hook)
+	}
+	return result
+}
+
+type WebhookDelivery struct {
+	ID             uuid.UUID       `json:"id"`
+	CreatedAt      time.Time       `json:"created_at"`
+	PostID         *uuid.UUID      `json:"post_id"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int32           `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at"`
+	LastAttemptAt  *time.Time      `json:"last_attempt_at"`
+	ResponseStatus *int32          `json:"response_status"`
+	LastError      *string         `json:"last_error"`
+}
+
+func DatabaseWebhookDeliveryToWebhookDelivery(delivery database.WebhookDelivery) WebhookDelivery {
+	// Only pending deliveries have another attempt coming.
+	var nextAttemptAt *time.Time
+	if delivery.Status == "pending" {
+		nextAttemptAt = &delivery.NextAttemptAt
+	}
+
+	return WebhookDelivery{
+		ID:             delivery.ID,
+		CreatedAt:      delivery.CreatedAt,
+		PostID:         nullUUIDToUUIDPtr(delivery.PostID),
+		Event:          delivery.Event,
+		Payload:        json.RawMessage(delivery.Payload),
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		NextAttemptAt:  nextAttemptAt,
+		LastAttemptAt:  nullTimeToTimePtr(delivery.LastAttemptAt),
+		ResponseStatus: nullInt32ToInt32Ptr(delivery.ResponseStatus),
+		LastError:      nullStringToStringPtr(delivery.LastError),
+	}
+}
+
+func DatabaseWebhookDeliveriesToWebhookDeliveries(deliveries []database.GetWebhookDeliveriesRow) []WebhookDelivery {
+	result := make([]WebhookDelivery, len(deliveries))
+	for i, delivery := range deliveries {
+		result[i] = DatabaseWebhookDeliveryToWebhookDelivery(delivery.WebhookDelivery)
+	}
+	return result
+}
@@ -7,15 +7,17 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createFeedFollow = `-- name: CreateFeedFollow :one
 INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
 VALUES ($1, $2, $3, $4, $5)
-RETURNING id, created_at, updated_at, user_id, feed_id
+RETURNING id, created_at, updated_at, user_id, feed_id, title, priority, muted, notify
 `
 
 type CreateFeedFollowParams struct {
@@ -41,6 +43,10 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.FeedID,
+		&i.Title,
+		&i.Priority,
+		&i.Muted,
+		&i.Notify,
 	)
 	return i, err
 }
@@ -55,26 +61,139 @@ func (q *Queries) DeleteFeedFollow(ctx context.Context, feedID uuid.UUID) error
 	return err
 }
 
+const deleteFeedFollowsForUser = `-- name: DeleteFeedFollowsForUser :execrows
+DELETE FROM feed_follows
+WHERE feed_id = $1 AND user_id = $2
+`
+
+type DeleteFeedFollowsForUserParams struct {
+	FeedID uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) DeleteFeedFollowsForUser(ctx context.Context, arg DeleteFeedFollowsForUserParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFeedFollowsForUser, arg.FeedID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getFeedFollowCounts = `-- name: GetFeedFollowCounts :many
+SELECT follows.feed_id,
+  count(posts.id) FILTER (WHERE post_states.hidden_at IS NULL) AS total,
+  count(posts.id) FILTER (WHERE post_states.read_at IS NULL AND post_states.hidden_at IS NULL) AS unread
+FROM (
+  SELECT DISTINCT feed_follows.feed_id
+  FROM feed_follows
+  WHERE feed_follows.user_id = $1::uuid
+) AS follows
+LEFT JOIN posts ON posts.feed_id = follows.feed_id
+LEFT JOIN post_states ON post_states.post_id = posts.id
+  AND post_states.user_id = $1::uuid
+  AND (post_states.read_at IS NOT NULL OR post_states.hidden_at IS NOT NULL)
+GROUP BY follows.feed_id
+ORDER BY follows.feed_id
+`
+
+type GetFeedFollowCountsRow struct {
+	FeedID uuid.UUID
+	Total  int64
+	Unread int64
+}
+
+func (q *Queries) GetFeedFollowCounts(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowCountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedFollowCounts, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFeedFollowCountsRow
+	for rows.Next() {
+		var i GetFeedFollowCountsRow
+		if err := rows.Scan(
+			&i.FeedID,
+			&i.Total,
+			&i.Unread,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeedFollowForUser = `-- name: GetFeedFollowForUser :one
+SELECT id, created_at, updated_at, user_id, feed_id, title, priority, muted, notify FROM feed_follows
+WHERE id = $1 AND user_id = $2
+`
+
+type GetFeedFollowForUserParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) GetFeedFollowForUser(ctx context.Context, arg GetFeedFollowForUserParams) (FeedFollow, error) {
+	row := q.db.QueryRowContext(ctx, getFeedFollowForUser, arg.ID, arg.UserID)
+	var i FeedFollow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.FeedID,
+		&i.Title,
+		&i.Priority,
+		&i.Muted,
+		&i.Notify,
+	)
+	return i, err
+}
+
 const getFeedFollows = `-- name: GetFeedFollows :many
-SELECT id, created_at, updated_at, user_id, feed_id FROM feed_follows
+SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id, feed_follows.title, feed_follows.priority, feed_follows.muted, feed_follows.notify,
+  ARRAY(
+    SELECT feed_follow_folders.folder_id
+    FROM feed_follow_folders
+    WHERE feed_follow_folders.feed_follow_id = feed_follows.id
+    ORDER BY feed_follow_folders.folder_id
+  )::uuid[] AS folder_ids
+FROM feed_follows
 WHERE user_id = $1
+ORDER BY feed_follows.priority DESC, feed_follows.created_at
 `
 
-func (q *Queries) GetFeedFollows(ctx context.Context, userID uuid.UUID) ([]FeedFollow, error) {
+type GetFeedFollowsRow struct {
+	FeedFollow FeedFollow
+	FolderIds  []uuid.UUID
+}
+
+func (q *Queries) GetFeedFollows(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsRow, error) {
 	rows, err := q.db.QueryContext(ctx, getFeedFollows, userID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []FeedFollow
+	var items []GetFeedFollowsRow
 	for rows.Next() {
-		var i FeedFollow
+		var i GetFeedFollowsRow
 		if err := rows.Scan(
-			&i.ID,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-			&i.UserID,
-			&i.FeedID,
+			&i.FeedFollow.ID,
+			&i.FeedFollow.CreatedAt,
+			&i.FeedFollow.UpdatedAt,
+			&i.FeedFollow.UserID,
+			&i.FeedFollow.FeedID,
+			&i.FeedFollow.Title,
+			&i.FeedFollow.Priority,
+			&i.FeedFollow.Muted,
+			&i.FeedFollow.Notify,
+			pq.Array(&i.FolderIds),
 		); err != nil {
 			return nil, err
 		}
@@ -88,3 +207,45 @@ func (q *Queries) GetFeedFollows(ctx context.Context, userID uuid.UUID) ([]FeedF
 	}
 	return items, nil
 }
+
+const updateFeedFollow = `-- name: UpdateFeedFollow :one
+UPDATE feed_follows
+SET title = $3, priority = $4, muted = $5, notify = $6, updated_at = $7
+WHERE id = $1 AND user_id = $2
+RETURNING id, created_at, updated_at, user_id, feed_id, title, priority, muted, notify
+`
+
+type UpdateFeedFollowParams struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Title     sql.NullString
+	Priority  int32
+	Muted     bool
+	Notify    bool
+	UpdatedAt time.Time
+}
+
+func (q *Queries) UpdateFeedFollow(ctx context.Context, arg UpdateFeedFollowParams) (FeedFollow, error) {
+	row := q.db.QueryRowContext(ctx, updateFeedFollow,
+		arg.ID,
+		arg.UserID,
+		arg.Title,
+		arg.Priority,
+		arg.Muted,
+		arg.Notify,
+		arg.UpdatedAt,
+	)
+	var i FeedFollow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.FeedID,
+		&i.Title,
+		&i.Priority,
+		&i.Muted,
+		&i.Notify,
+	)
+	return i, err
+}
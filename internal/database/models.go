@@ -11,14 +11,63 @@ import (
 	"github.com/google/uuid"
 )
 
+type Alert struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	Phrase     string
+	Enabled    bool
+	NextSendAt sql.NullTime
+	LastSentAt sql.NullTime
+}
+
+type AlertMatch struct {
+	AlertID   uuid.UUID
+	PostID    uuid.UUID
+	CreatedAt time.Time
+	SentAt    sql.NullTime
+}
+
+type Digest struct {
+	ID               uuid.UUID
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	UserID           uuid.UUID
+	Schedule         string
+	Hour             int32
+	Weekday          int32
+	Timezone         string
+	FeedIds          []uuid.UUID
+	FolderIds        []uuid.UUID
+	Enabled          bool
+	UnsubscribeToken string
+	NextSendAt       time.Time
+	LastSentAt       sql.NullTime
+}
+
 type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
+	ID                uuid.UUID
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	Name              string
+	Url               string
+	UserID            uuid.UUID
+	LastFetchedAt     sql.NullTime
+	RetentionDays     sql.NullInt32
+	RetentionMaxPosts sql.NullInt32
+}
+
+type FeedFetch struct {
+	ID         uuid.UUID
+	FeedID     uuid.UUID
+	StartedAt  time.Time
+	FinishedAt time.Time
+	StatusCode sql.NullInt32
+	Bytes      int64
+	ItemsSeen  int32
+	ItemsNew   int32
+	Error      sql.NullString
 }
 
 type FeedFollow struct {
@@ -27,6 +76,45 @@ type FeedFollow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	Title     sql.NullString
+	Priority  int32
+	Muted     bool
+	Notify    bool
+}
+
+type FeedFollowFolder struct {
+	FeedFollowID uuid.UUID
+	FolderID     uuid.UUID
+}
+
+type Folder struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Name      string
+	Position  int32
+}
+
+type Notification struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	RuleID    uuid.UUID
+}
+
+type OutputFeed struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	UserID        uuid.UUID
+	Name          string
+	Source        string
+	FolderID      uuid.NullUUID
+	Tag           sql.NullString
+	SavedSearchID uuid.NullUUID
+	Token         string
 }
 
 type Permission struct {
@@ -35,14 +123,83 @@ type Permission struct {
 }
 
 type Post struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description sql.NullString
-	PublishedAt sql.NullTime
-	FeedID      uuid.UUID
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Title         string
+	Url           string
+	Description   sql.NullString
+	PublishedAt   sql.NullTime
+	FeedID        uuid.UUID
+	Simhash       sql.NullInt64
+	ClusterID     uuid.UUID
+	Author        sql.NullString
+	Content       sql.NullString
+	EnclosureUrl  sql.NullString
+	EnclosureType sql.NullString
+	Language      sql.NullString
+	ItemID        int64
+	CreatedXid    int64
+	StreamSeq     int64
+	SimhashBands  []int32
+}
+
+type PostState struct {
+	UserID     uuid.UUID
+	PostID     uuid.UUID
+	ReadAt     sql.NullTime
+	UpdatedAt  time.Time
+	StarredAt  sql.NullTime
+	HiddenAt   sql.NullTime
+	ChangedXid int64
+	ChangeSeq  int64
+}
+
+type PostTag struct {
+	TagID     uuid.UUID
+	PostID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type PrunedPost struct {
+	FeedID   uuid.UUID
+	Url      string
+	PrunedAt time.Time
+}
+
+type Rule struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Name      string
+	FeedID    uuid.NullUUID
+	Field     string
+	MatchType string
+	Pattern   string
+	Actions   []string
+	Tag       sql.NullString
+	Enabled   bool
+}
+
+type SavedSearch struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	UserID       uuid.UUID
+	Name         string
+	Query        string
+	FeedID       uuid.NullUUID
+	FolderID     uuid.NullUUID
+	Tags         []string
+	MatchAllTags bool
+}
+
+type Tag struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	Name      string
 }
 
 type Token struct {
@@ -67,3 +224,32 @@ type UsersPermission struct {
 	UserID        uuid.UUID
 	PermissionsID uuid.UUID
 }
+
+type Webhook struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	Url       string
+	Secret    string
+	FeedID    uuid.NullUUID
+	FolderID  uuid.NullUUID
+	Keywords  []string
+	Enabled   bool
+	Format    string
+}
+
+type WebhookDelivery struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	WebhookID      uuid.UUID
+	PostID         uuid.NullUUID
+	Event          string
+	Payload        string
+	Status         string
+	Attempts       int32
+	NextAttemptAt  time.Time
+	LastAttemptAt  sql.NullTime
+	ResponseStatus sql.NullInt32
+	LastError      sql.NullString
+}
@@ -11,23 +11,88 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const countPostsForUser = `-- name: CountPostsForUser :one
+SELECT CASE WHEN $1::boolean THEN count(DISTINCT posts.cluster_id) ELSE count(*) END
+FROM filter_posts(
+  $2::uuid, $3::text, $4::uuid[], $5::uuid, $6::text, $7::boolean,
+  $8::boolean, $9::text[], $10::boolean,
+  $11::timestamp, $12::timestamp, $13::timestamp,
+  $14::text, $15::boolean, $16::text
+) AS posts
+`
+
+type CountPostsForUserParams struct {
+	Collapse        bool
+	UserID          uuid.UUID
+	Search          string
+	FeedIds         []uuid.UUID
+	FolderID        uuid.UUID
+	Status          string
+	Starred         bool
+	IncludeHidden   bool
+	Tags            []string
+	MatchAllTags    bool
+	PublishedAfter  sql.NullTime
+	PublishedBefore sql.NullTime
+	CreatedAfter    sql.NullTime
+	Author          string
+	HasEnclosure    sql.NullBool
+	Language        string
+}
+
+func (q *Queries) CountPostsForUser(ctx context.Context, arg CountPostsForUserParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countPostsForUser,
+		arg.Collapse,
+		arg.UserID,
+		arg.Search,
+		pq.Array(arg.FeedIds),
+		arg.FolderID,
+		arg.Status,
+		arg.Starred,
+		arg.IncludeHidden,
+		pq.Array(arg.Tags),
+		arg.MatchAllTags,
+		arg.PublishedAfter,
+		arg.PublishedBefore,
+		arg.CreatedAfter,
+		arg.Author,
+		arg.HasEnclosure,
+		arg.Language,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createPost = `-- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, simhash, cluster_id, author, content, enclosure_url, enclosure_type, language)
+SELECT $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15
+WHERE NOT EXISTS (
+  SELECT 1 FROM pruned_posts
+  WHERE pruned_posts.feed_id = $8 AND pruned_posts.url = $5
+)
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, simhash, cluster_id, author, content, enclosure_url, enclosure_type, language, item_id, created_xid, stream_seq, simhash_bands
 `
 
 type CreatePostParams struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description sql.NullString
-	PublishedAt sql.NullTime
-	FeedID      uuid.UUID
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Title         string
+	Url           string
+	Description   sql.NullString
+	PublishedAt   sql.NullTime
+	FeedID        uuid.UUID
+	Simhash       sql.NullInt64
+	ClusterID     uuid.UUID
+	Author        sql.NullString
+	Content       sql.NullString
+	EnclosureUrl  sql.NullString
+	EnclosureType sql.NullString
+	Language      sql.NullString
 }
 
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
@@ -40,6 +105,13 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		arg.Description,
 		arg.PublishedAt,
 		arg.FeedID,
+		arg.Simhash,
+		arg.ClusterID,
+		arg.Author,
+		arg.Content,
+		arg.EnclosureUrl,
+		arg.EnclosureType,
+		arg.Language,
 	)
 	var i Post
 	err := row.Scan(
@@ -51,61 +123,322 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.Simhash,
+		&i.ClusterID,
+		&i.Author,
+		&i.Content,
+		&i.EnclosureUrl,
+		&i.EnclosureType,
+		&i.Language,
+		&i.ItemID,
+		&i.CreatedXid,
+		&i.StreamSeq,
+		pq.Array(&i.SimhashBands),
 	)
 	return i, err
 }
 
-const getPostsForUser = `-- name: GetPostsForUser :many
-SELECT count(*) OVER(), posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id
+const findPostClusterBySimhash = `-- name: FindPostClusterBySimhash :one
+SELECT cluster_id
+FROM posts
+-- The bands narrow the posts down through posts_simhash_bands_idx, before
+-- the distance of each is checked.
+WHERE simhash_bands && simhash_bands($1::bigint)
+  AND created_at > $2::timestamp
+  AND bit_count((simhash # $1::bigint)::bit(64)) <= $3::integer
+ORDER BY created_at ASC
+LIMIT 1
+`
+
+type FindPostClusterBySimhashParams struct {
+	Simhash     int64
+	Since       time.Time
+	MaxDistance int32
+}
+
+func (q *Queries) FindPostClusterBySimhash(ctx context.Context, arg FindPostClusterBySimhashParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, findPostClusterBySimhash, arg.Simhash, arg.Since, arg.MaxDistance)
+	var cluster_id uuid.UUID
+	err := row.Scan(&cluster_id)
+	return cluster_id, err
+}
+
+const findPostClusterByURL = `-- name: FindPostClusterByURL :one
+SELECT cluster_id
+FROM posts
+WHERE url = $1
+ORDER BY created_at ASC
+LIMIT 1
+`
+
+func (q *Queries) FindPostClusterByURL(ctx context.Context, url string) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, findPostClusterByURL, url)
+	var cluster_id uuid.UUID
+	err := row.Scan(&cluster_id)
+	return cluster_id, err
+}
+
+const getNewPostsForUser = `-- name: GetNewPostsForUser :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.simhash, posts.cluster_id, posts.author, posts.content, posts.enclosure_url, posts.enclosure_type, posts.language, posts.item_id, posts.created_xid, posts.stream_seq, posts.simhash_bands, post_states.read_at, post_states.starred_at,
+  ARRAY(
+    SELECT tags.name
+    FROM post_tags
+    JOIN tags ON tags.id = post_tags.tag_id
+    WHERE post_tags.post_id = posts.id AND tags.user_id = $1::uuid
+    ORDER BY tags.name
+  )::text[] AS tags
 FROM posts
-JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
-WHERE feed_follows.user_id = $1::uuid
-  AND (to_tsvector('simple', posts.title) @@ plainto_tsquery('simple', $2::text) OR $2::text = '')
-  AND (posts.feed_id = $3::uuid OR $3::uuid = '00000000-0000-0000-0000-000000000000'::uuid)
-ORDER BY 
-  CASE 
-    WHEN $4 = 'id' THEN posts.id END ASC,
-    CASE 
-    WHEN $4 = 'title' THEN posts.title END ASC,
-    CASE 
-    WHEN $4 = 'published_at' THEN posts.published_at END ASC,
-    CASE 
-    WHEN $4 = '-id' THEN posts.id END DESC,
-    CASE 
-    WHEN $4 = '-title' THEN posts.title END DESC,
-    CASE 
-    WHEN $4 = '-published_at' THEN posts.published_at END DESC,
-  posts.id ASC
-  LIMIT $6::integer OFFSET $5::integer
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = $1::uuid
+WHERE EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.user_id = $1::uuid
+      AND feed_follows.feed_id = posts.feed_id
+      AND NOT feed_follows.muted
+  )
+  AND post_states.hidden_at IS NULL
+  AND (posts.created_xid, posts.stream_seq) > ($2::bigint, $3::bigint)
+  -- Posts wait until every older transaction has ended, as those could still
+  -- commit posts that belong before them. A transaction's own posts are
+  -- final to it.
+  AND (posts.created_xid < pg_snapshot_xmin(pg_current_snapshot())::text::bigint
+    OR posts.created_xid = pg_current_xact_id_if_assigned()::text::bigint)
+ORDER BY posts.created_xid, posts.stream_seq
+LIMIT $4::integer
+`
+
+type GetNewPostsForUserParams struct {
+	UserID   uuid.UUID
+	AfterXid int64
+	AfterSeq int64
+	Lim      int32
+}
+
+type GetNewPostsForUserRow struct {
+	Post      Post
+	ReadAt    sql.NullTime
+	StarredAt sql.NullTime
+	Tags      []string
+}
+
+func (q *Queries) GetNewPostsForUser(ctx context.Context, arg GetNewPostsForUserParams) ([]GetNewPostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getNewPostsForUser,
+		arg.UserID,
+		arg.AfterXid,
+		arg.AfterSeq,
+		arg.Lim,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetNewPostsForUserRow
+	for rows.Next() {
+		var i GetNewPostsForUserRow
+		if err := rows.Scan(
+			&i.Post.ID,
+			&i.Post.CreatedAt,
+			&i.Post.UpdatedAt,
+			&i.Post.Title,
+			&i.Post.Url,
+			&i.Post.Description,
+			&i.Post.PublishedAt,
+			&i.Post.FeedID,
+			&i.Post.Simhash,
+			&i.Post.ClusterID,
+			&i.Post.Author,
+			&i.Post.Content,
+			&i.Post.EnclosureUrl,
+			&i.Post.EnclosureType,
+			&i.Post.Language,
+			&i.Post.ItemID,
+			&i.Post.CreatedXid,
+			&i.Post.StreamSeq,
+			pq.Array(&i.Post.SimhashBands),
+			&i.ReadAt,
+			&i.StarredAt,
+			pq.Array(&i.Tags),
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostStreamOrder = `-- name: GetPostStreamOrder :one
+SELECT created_xid, stream_seq FROM posts
+WHERE id = $1
+`
+
+type GetPostStreamOrderRow struct {
+	CreatedXid int64
+	StreamSeq  int64
+}
+
+func (q *Queries) GetPostStreamOrder(ctx context.Context, id uuid.UUID) (GetPostStreamOrderRow, error) {
+	row := q.db.QueryRowContext(ctx, getPostStreamOrder, id)
+	var i GetPostStreamOrderRow
+	err := row.Scan(
+		&i.CreatedXid,
+		&i.StreamSeq,
+	)
+	return i, err
+}
+
+const getPostsForUser = `-- name: GetPostsForUser :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.cluster_id, posts.author,
+  posts.enclosure_url, posts.enclosure_type, posts.language, posts.item_id,
+  (
+    SELECT count(*)
+    FROM posts AS duplicates
+    JOIN feed_follows AS duplicate_follows ON duplicate_follows.feed_id = duplicates.feed_id
+    WHERE duplicate_follows.user_id = $1::uuid
+      AND duplicates.cluster_id = posts.cluster_id
+      AND duplicates.id <> posts.id
+  ) AS duplicate_count,
+  post_states.read_at, post_states.starred_at, post_states.hidden_at,
+  ARRAY(
+    SELECT tags.name
+    FROM post_tags
+    JOIN tags ON tags.id = post_tags.tag_id
+    WHERE post_tags.post_id = posts.id AND tags.user_id = $1::uuid
+    ORDER BY tags.name
+  )::text[] AS tags,
+  CASE WHEN $2::text <> '' THEN ts_headline(
+    'english',
+    post_search_text(posts.description, posts.content),
+    websearch_to_tsquery('english', $2::text),
+    'MaxFragments=2, MaxWords=30, MinWords=10'
+  ) END AS snippet,
+  (CASE WHEN $2::text <> '' THEN ts_rank_cd(post_search_vector(posts.title, posts.description, posts.content), websearch_to_tsquery('english', $2::text)) ELSE 0 END)::real AS relevance
+FROM filter_posts(
+  $1::uuid, $2::text, $3::uuid[], $4::uuid, $5::text, $6::boolean,
+  $7::boolean, $8::text[], $9::boolean,
+  $10::timestamp, $11::timestamp, $12::timestamp,
+  $13::text, $14::boolean, $15::text
+) AS posts
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = $1::uuid
+-- Collapsed listings show the earliest post of each story that matches.
+WHERE (NOT $16::boolean OR NOT EXISTS (
+    SELECT 1
+    FROM filter_posts(
+      $1::uuid, $2::text, $3::uuid[], $4::uuid, $5::text, $6::boolean,
+      $7::boolean, $8::text[], $9::boolean,
+      $10::timestamp, $11::timestamp, $12::timestamp,
+      $13::text, $14::boolean, $15::text
+    ) AS duplicates
+    WHERE duplicates.cluster_id = posts.cluster_id
+      AND (duplicates.created_at, duplicates.id) < (posts.created_at, posts.id)
+  ))
+  -- Keyset pagination: only posts after the cursor in the sort order. Ties
+  -- are broken by ID in the same direction, so a cursor is a (key, id) pair.
+  AND ($17::uuid = '00000000-0000-0000-0000-000000000000'::uuid OR CASE
+    WHEN $18::boolean THEN CASE $19::text
+      WHEN 'title' THEN (posts.title, posts.id) < ($20::text, $17::uuid)
+      WHEN 'published_at' THEN (coalesce(posts.published_at, posts.created_at), posts.id) < ($21::timestamp, $17::uuid)
+      WHEN 'relevance' THEN (ts_rank_cd(post_search_vector(posts.title, posts.description, posts.content), websearch_to_tsquery('english', $2::text)), posts.id) < ($22::real, $17::uuid)
+      ELSE posts.id < $17::uuid
+    END
+    ELSE CASE $19::text
+      WHEN 'title' THEN (posts.title, posts.id) > ($20::text, $17::uuid)
+      WHEN 'published_at' THEN (coalesce(posts.published_at, posts.created_at), posts.id) > ($21::timestamp, $17::uuid)
+      WHEN 'relevance' THEN (ts_rank_cd(post_search_vector(posts.title, posts.description, posts.content), websearch_to_tsquery('english', $2::text)), posts.id) > ($22::real, $17::uuid)
+      ELSE posts.id > $17::uuid
+    END
+  END)
+ORDER BY
+  CASE WHEN $19::text = 'title' AND NOT $18::boolean THEN posts.title END ASC,
+  CASE WHEN $19::text = 'title' AND $18::boolean THEN posts.title END DESC,
+  CASE WHEN $19::text = 'published_at' AND NOT $18::boolean THEN coalesce(posts.published_at, posts.created_at) END ASC,
+  CASE WHEN $19::text = 'published_at' AND $18::boolean THEN coalesce(posts.published_at, posts.created_at) END DESC,
+  CASE WHEN $19::text = 'relevance' AND NOT $18::boolean THEN ts_rank_cd(post_search_vector(posts.title, posts.description, posts.content), websearch_to_tsquery('english', $2::text)) END ASC,
+  CASE WHEN $19::text = 'relevance' AND $18::boolean THEN ts_rank_cd(post_search_vector(posts.title, posts.description, posts.content), websearch_to_tsquery('english', $2::text)) END DESC,
+  CASE WHEN NOT $18::boolean THEN posts.id END ASC,
+  CASE WHEN $18::boolean THEN posts.id END DESC
+LIMIT $24::integer OFFSET $23::integer
 `
 
 type GetPostsForUserParams struct {
-	UserID uuid.UUID
-	Title  string
-	FeedID uuid.UUID
-	Sort   interface{}
-	Off    int32
-	Lim    int32
+	UserID          uuid.UUID
+	Search          string
+	FeedIds         []uuid.UUID
+	FolderID        uuid.UUID
+	Status          string
+	Starred         bool
+	IncludeHidden   bool
+	Tags            []string
+	MatchAllTags    bool
+	PublishedAfter  sql.NullTime
+	PublishedBefore sql.NullTime
+	CreatedAfter    sql.NullTime
+	Author          string
+	HasEnclosure    sql.NullBool
+	Language        string
+	Collapse        bool
+	CursorID        uuid.UUID
+	SortDesc        bool
+	SortKey         string
+	CursorText      string
+	CursorTime      time.Time
+	CursorRank      float32
+	Off             int32
+	Lim             int32
 }
 
 type GetPostsForUserRow struct {
-	Count       int64
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description sql.NullString
-	PublishedAt sql.NullTime
-	FeedID      uuid.UUID
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Title          string
+	Url            string
+	Description    sql.NullString
+	PublishedAt    sql.NullTime
+	FeedID         uuid.UUID
+	ClusterID      uuid.UUID
+	Author         sql.NullString
+	EnclosureUrl   sql.NullString
+	EnclosureType  sql.NullString
+	Language       sql.NullString
+	ItemID         int64
+	DuplicateCount int64
+	ReadAt         sql.NullTime
+	StarredAt      sql.NullTime
+	HiddenAt       sql.NullTime
+	Tags           []string
+	Snippet        sql.NullString
+	Relevance      float32
 }
 
 func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error) {
 	rows, err := q.db.QueryContext(ctx, getPostsForUser,
 		arg.UserID,
-		arg.Title,
-		arg.FeedID,
-		arg.Sort,
+		arg.Search,
+		pq.Array(arg.FeedIds),
+		arg.FolderID,
+		arg.Status,
+		arg.Starred,
+		arg.IncludeHidden,
+		pq.Array(arg.Tags),
+		arg.MatchAllTags,
+		arg.PublishedAfter,
+		arg.PublishedBefore,
+		arg.CreatedAfter,
+		arg.Author,
+		arg.HasEnclosure,
+		arg.Language,
+		arg.Collapse,
+		arg.CursorID,
+		arg.SortDesc,
+		arg.SortKey,
+		arg.CursorText,
+		arg.CursorTime,
+		arg.CursorRank,
 		arg.Off,
 		arg.Lim,
 	)
@@ -117,7 +450,6 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	for rows.Next() {
 		var i GetPostsForUserRow
 		if err := rows.Scan(
-			&i.Count,
 			&i.ID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
@@ -126,6 +458,19 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.ClusterID,
+			&i.Author,
+			&i.EnclosureUrl,
+			&i.EnclosureType,
+			&i.Language,
+			&i.ItemID,
+			&i.DuplicateCount,
+			&i.ReadAt,
+			&i.StarredAt,
+			&i.HiddenAt,
+			pq.Array(&i.Tags),
+			&i.Snippet,
+			&i.Relevance,
 		); err != nil {
 			return nil, err
 		}
@@ -139,3 +484,44 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	}
 	return items, nil
 }
+
+const getStreamHorizon = `-- name: GetStreamHorizon :one
+SELECT pg_snapshot_xmin(pg_current_snapshot())::text::bigint AS xid
+`
+
+func (q *Queries) GetStreamHorizon(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getStreamHorizon)
+	var xid int64
+	err := row.Scan(&xid)
+	return xid, err
+}
+
+const isPostVisibleToUser = `-- name: IsPostVisibleToUser :one
+SELECT EXISTS (
+  SELECT 1
+  FROM posts
+  JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+  WHERE posts.id = $1 AND feed_follows.user_id = $2
+)
+`
+
+type IsPostVisibleToUserParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) IsPostVisibleToUser(ctx context.Context, arg IsPostVisibleToUserParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isPostVisibleToUser, arg.ID, arg.UserID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const notifyPostCreated = `-- name: NotifyPostCreated :exec
+SELECT pg_notify('new_posts', '')
+`
+
+func (q *Queries) NotifyPostCreated(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, notifyPostCreated)
+	return err
+}
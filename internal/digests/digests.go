@@ -0,0 +1,242 @@
+package digests
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+
+	// Timezones are embedded, so schedules work on hosts without zoneinfo.
+	_ "time/tzdata"
+)
+
+const (
+	Daily  = "daily"
+	Weekly = "weekly"
+)
+
+var Schedules = []string{Daily, Weekly}
+
+const (
+	// MaxPosts is the most posts listed in one digest.
+	MaxPosts = 50
+
+	// Lease is how long a claimed digest is reserved for the instance that
+	// claimed it.
+	Lease = 10 * time.Minute
+
+	// claimBatchSize is how many due digests are claimed at a time.
+	claimBatchSize = 20
+)
+
+// Store is the subset of database.Queries digests are read and scheduled
+// through.
+type Store interface {
+	ClaimDueDigests(ctx context.Context, arg database.ClaimDueDigestsParams) ([]database.ClaimDueDigestsRow, error)
+	GetDigestPosts(ctx context.Context, arg database.GetDigestPostsParams) ([]database.GetDigestPostsRow, error)
+	RecordDigestSent(ctx context.Context, arg database.RecordDigestSentParams) error
+}
+
+// Sender sends templated emails, as mailer.Mailer does.
+type Sender interface {
+	Send(recipient, templateFile string, data any) error
+}
+
+// Post is a post as it's listed in a digest.
+type Post struct {
+	Title       string
+	Url         string
+	FeedName    string
+	PublishedAt time.Time
+}
+
+// Email is the data the digest template is rendered with.
+type Email struct {
+	Name           string
+	Schedule       string
+	Posts          []Post
+	Total          int
+	More           int
+	UnsubscribeURL string
+}
+
+// Headers lets mail clients offer to unsubscribe with one click, by POSTing
+// to the unsubscribe link.
+func (e Email) Headers() map[string]string {
+	return map[string]string{
+		"List-Unsubscribe":      "<" + e.UnsubscribeURL + ">",
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	}
+}
+
+// Next returns the first time after after that a digest on the schedule is
+// due: hour o'clock in loc, every day or, for weekly digests, on weekday.
+func Next(schedule string, hour int, weekday time.Weekday, loc *time.Location, after time.Time) time.Time {
+	local := after.In(loc)
+	next := time.Date(local.Year(), local.Month(), local.Day(), hour, 0, 0, 0, loc)
+
+	days := 1
+	if schedule == Weekly {
+		days = 7
+		next = next.AddDate(0, 0, (int(weekday)-int(next.Weekday())+7)%7)
+	}
+
+	for !next.After(after) {
+		next = next.AddDate(0, 0, days)
+	}
+	return next.UTC()
+}
+
+// period is how far back a digest reaches when it hasn't been sent since.
+func period(schedule string) time.Duration {
+	if schedule == Weekly {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// Digester emails users the unread posts of their digests when they're due.
+type Digester struct {
+	store   Store
+	mailer  Sender
+	baseURL string
+}
+
+// New returns a Digester linking to the API at baseURL to unsubscribe.
+func New(store Store, mailer Sender, baseURL string) *Digester {
+	return &Digester{
+		store:   store,
+		mailer:  mailer,
+		baseURL: baseURL,
+	}
+}
+
+// Start sends due digests every interval.
+func (d *Digester) Start(interval time.Duration) {
+	log.Printf("Sending digests every %s...", interval)
+	ticker := time.NewTicker(interval)
+
+	for ; ; <-ticker.C {
+		for {
+			sent, err := d.SendOnce(context.Background())
+			if err != nil {
+				log.Println("Couldn't send digests", err)
+			}
+			if err != nil || sent < claimBatchSize {
+				break
+			}
+		}
+	}
+}
+
+// SendOnce claims a batch of due digests and sends them, and returns how
+// many it claimed. Each is rescheduled whether or not it could be sent, so a
+// failing digest is skipped rather than retried until it floods the user.
+func (d *Digester) SendOnce(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+	digests, err := d.store.ClaimDueDigests(ctx, database.ClaimDueDigestsParams{
+		LeaseUntil: now.Add(Lease),
+		Now:        now,
+		Lim:        claimBatchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, digest := range digests {
+		sent, err := d.send(ctx, digest, now)
+		if err != nil {
+			log.Printf("Couldn't send digest %s: %v", digest.ID, err)
+		}
+
+		loc, err := time.LoadLocation(digest.Timezone)
+		if err != nil {
+			loc = time.UTC
+		}
+		next := Next(digest.Schedule, int(digest.Hour), time.Weekday(digest.Weekday), loc, now)
+
+		lastSentAt := sql.NullTime{}
+		if sent {
+			lastSentAt = sql.NullTime{Time: now, Valid: true}
+		}
+
+		err = d.store.RecordDigestSent(ctx, database.RecordDigestSentParams{
+			LastSentAt: lastSentAt,
+			NextSendAt: next,
+			ID:         digest.ID,
+		})
+		if err != nil {
+			log.Printf("Couldn't reschedule digest %s: %v", digest.ID, err)
+		}
+	}
+
+	return len(digests), nil
+}
+
+// send emails a digest of the unread posts collected since it was last sent,
+// and reports whether there were any to send.
+func (d *Digester) send(ctx context.Context, digest database.ClaimDueDigestsRow, now time.Time) (bool, error) {
+	since := now.Add(-period(digest.Schedule))
+	if digest.LastSentAt.Valid && digest.LastSentAt.Time.After(since) {
+		since = digest.LastSentAt.Time
+	}
+
+	rows, err := d.store.GetDigestPosts(ctx, database.GetDigestPostsParams{
+		UserID:    digest.UserID,
+		FeedIds:   digest.FeedIds,
+		FolderIds: digest.FolderIds,
+		Since:     since,
+		Lim:       MaxPosts,
+	})
+	if err != nil {
+		return false, err
+	}
+	if len(rows) == 0 {
+		return false, nil
+	}
+
+	email := Email{
+		Name:           digest.Name,
+		Schedule:       digest.Schedule,
+		Posts:          make([]Post, len(rows)),
+		Total:          int(rows[0].Count),
+		More:           int(rows[0].Count) - len(rows),
+		UnsubscribeURL: fmt.Sprintf("%s/v1/unsubscribe/%s", d.baseURL, digest.UnsubscribeToken),
+	}
+	for i, row := range rows {
+		publishedAt := row.Post.CreatedAt
+		if row.Post.PublishedAt.Valid {
+			publishedAt = row.Post.PublishedAt.Time
+		}
+		email.Posts[i] = Post{
+			Title:       row.Post.Title,
+			Url:         row.Post.Url,
+			FeedName:    row.FeedName,
+			PublishedAt: publishedAt,
+		}
+	}
+
+	err = d.mailer.Send(digest.Email, "digest.tmpl", email)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// NewUnsubscribeToken returns a random token for a digest's unsubscribe
+// link.
+func NewUnsubscribeToken() (string, error) {
+	randomBytes := make([]byte, 16)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", err
+	}
+
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes), nil
+}
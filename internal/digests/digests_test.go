@@ -0,0 +1,202 @@
+package digests
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStore struct {
+	claimed  []database.ClaimDueDigestsRow
+	posts    []database.GetDigestPostsRow
+	queries  []database.GetDigestPostsParams
+	recorded []database.RecordDigestSentParams
+}
+
+func (f *fakeStore) ClaimDueDigests(ctx context.Context, arg database.ClaimDueDigestsParams) ([]database.ClaimDueDigestsRow, error) {
+	claimed := f.claimed
+	f.claimed = nil
+	return claimed, nil
+}
+
+func (f *fakeStore) GetDigestPosts(ctx context.Context, arg database.GetDigestPostsParams) ([]database.GetDigestPostsRow, error) {
+	f.queries = append(f.queries, arg)
+	return f.posts, nil
+}
+
+func (f *fakeStore) RecordDigestSent(ctx context.Context, arg database.RecordDigestSentParams) error {
+	f.recorded = append(f.recorded, arg)
+	return nil
+}
+
+type sentEmail struct {
+	recipient    string
+	templateFile string
+	data         any
+}
+
+type fakeSender struct {
+	sent []sentEmail
+	err  error
+}
+
+func (f *fakeSender) Send(recipient, templateFile string, data any) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.sent = append(f.sent, sentEmail{recipient, templateFile, data})
+	return nil
+}
+
+func TestNext(t *testing.T) {
+	rome, err := time.LoadLocation("Europe/Rome")
+	require.NoError(t, err)
+
+	// Wednesday.
+	after := time.Date(2024, 3, 20, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		schedule string
+		hour     int
+		weekday  time.Weekday
+		loc      *time.Location
+		after    time.Time
+		want     time.Time
+	}{
+		{"daily later today", Daily, 18, time.Monday, time.UTC, after, time.Date(2024, 3, 20, 18, 0, 0, 0, time.UTC)},
+		{"daily tomorrow", Daily, 8, time.Monday, time.UTC, after, time.Date(2024, 3, 21, 8, 0, 0, 0, time.UTC)},
+		{"daily at the due time", Daily, 10, time.Monday, time.UTC, time.Date(2024, 3, 20, 10, 0, 0, 0, time.UTC), time.Date(2024, 3, 21, 10, 0, 0, 0, time.UTC)},
+		{"weekly later this week", Weekly, 8, time.Friday, time.UTC, after, time.Date(2024, 3, 22, 8, 0, 0, 0, time.UTC)},
+		{"weekly next week", Weekly, 8, time.Monday, time.UTC, after, time.Date(2024, 3, 25, 8, 0, 0, 0, time.UTC)},
+		{"weekly later today", Weekly, 18, time.Wednesday, time.UTC, after, time.Date(2024, 3, 20, 18, 0, 0, 0, time.UTC)},
+		{"weekly earlier today", Weekly, 8, time.Wednesday, time.UTC, after, time.Date(2024, 3, 27, 8, 0, 0, 0, time.UTC)},
+		{"timezone", Daily, 8, time.Monday, rome, after, time.Date(2024, 3, 21, 7, 0, 0, 0, time.UTC)},
+		// Rome switches to summer time on March 31st.
+		{"across DST", Daily, 8, time.Monday, rome, time.Date(2024, 3, 30, 12, 0, 0, 0, time.UTC), time.Date(2024, 3, 31, 6, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Next(tt.schedule, tt.hour, tt.weekday, tt.loc, tt.after)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, time.UTC, got.Location())
+		})
+	}
+}
+
+func digestRow(lastSentAt sql.NullTime) database.ClaimDueDigestsRow {
+	return database.ClaimDueDigestsRow{
+		ID:               uuid.New(),
+		UserID:           uuid.New(),
+		Schedule:         Daily,
+		Hour:             8,
+		Timezone:         "Europe/Rome",
+		FeedIds:          []uuid.UUID{uuid.New()},
+		FolderIds:        []uuid.UUID{},
+		Enabled:          true,
+		UnsubscribeToken: "TOKEN",
+		LastSentAt:       lastSentAt,
+		Email:            "ada@example.com",
+		Name:             "Ada",
+	}
+}
+
+func postRow(count int64, title string) database.GetDigestPostsRow {
+	return database.GetDigestPostsRow{
+		Count: count,
+		Post: database.Post{
+			ID:        uuid.New(),
+			CreatedAt: time.Date(2024, 3, 20, 9, 0, 0, 0, time.UTC),
+			Title:     title,
+			Url:       "https://example.com/" + title,
+		},
+		FeedName: "Example",
+	}
+}
+
+func TestSendOnce(t *testing.T) {
+	t.Run("sends unread posts", func(t *testing.T) {
+		digest := digestRow(sql.NullTime{})
+		store := &fakeStore{
+			claimed: []database.ClaimDueDigestsRow{digest},
+			posts:   []database.GetDigestPostsRow{postRow(3, "one"), postRow(3, "two")},
+		}
+		sender := &fakeSender{}
+
+		sent, err := New(store, sender, "https://api.example.com").SendOnce(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, sent)
+
+		require.Len(t, store.queries, 1)
+		assert.Equal(t, digest.UserID, store.queries[0].UserID)
+		assert.Equal(t, digest.FeedIds, store.queries[0].FeedIds)
+		assert.WithinDuration(t, time.Now().Add(-24*time.Hour), store.queries[0].Since, time.Minute)
+
+		require.Len(t, sender.sent, 1)
+		assert.Equal(t, "ada@example.com", sender.sent[0].recipient)
+		assert.Equal(t, "digest.tmpl", sender.sent[0].templateFile)
+		email := sender.sent[0].data.(Email)
+		assert.Equal(t, "Ada", email.Name)
+		assert.Len(t, email.Posts, 2)
+		assert.Equal(t, "one", email.Posts[0].Title)
+		assert.Equal(t, "Example", email.Posts[0].FeedName)
+		assert.Equal(t, 3, email.Total)
+		assert.Equal(t, 1, email.More)
+		assert.Equal(t, "https://api.example.com/v1/unsubscribe/TOKEN", email.UnsubscribeURL)
+		assert.Equal(t, "<https://api.example.com/v1/unsubscribe/TOKEN>", email.Headers()["List-Unsubscribe"])
+		assert.Equal(t, "List-Unsubscribe=One-Click", email.Headers()["List-Unsubscribe-Post"])
+
+		require.Len(t, store.recorded, 1)
+		assert.Equal(t, digest.ID, store.recorded[0].ID)
+		assert.True(t, store.recorded[0].LastSentAt.Valid)
+		assert.True(t, store.recorded[0].NextSendAt.After(time.Now()))
+	})
+
+	t.Run("covers posts since the last digest", func(t *testing.T) {
+		lastSentAt := time.Now().UTC().Add(-2 * time.Hour)
+		store := &fakeStore{
+			claimed: []database.ClaimDueDigestsRow{digestRow(sql.NullTime{Time: lastSentAt, Valid: true})},
+		}
+
+		_, err := New(store, &fakeSender{}, "").SendOnce(context.Background())
+		require.NoError(t, err)
+
+		require.Len(t, store.queries, 1)
+		assert.Equal(t, lastSentAt, store.queries[0].Since)
+	})
+
+	t.Run("skips digests without posts", func(t *testing.T) {
+		store := &fakeStore{claimed: []database.ClaimDueDigestsRow{digestRow(sql.NullTime{})}}
+		sender := &fakeSender{}
+
+		_, err := New(store, sender, "").SendOnce(context.Background())
+		require.NoError(t, err)
+
+		assert.Empty(t, sender.sent)
+		require.Len(t, store.recorded, 1)
+		assert.False(t, store.recorded[0].LastSentAt.Valid)
+		assert.True(t, store.recorded[0].NextSendAt.After(time.Now()))
+	})
+
+	t.Run("reschedules digests that can't be sent", func(t *testing.T) {
+		store := &fakeStore{
+			claimed: []database.ClaimDueDigestsRow{digestRow(sql.NullTime{})},
+			posts:   []database.GetDigestPostsRow{postRow(1, "one")},
+		}
+
+		_, err := New(store, &fakeSender{err: errors.New("smtp down")}, "").SendOnce(context.Background())
+		require.NoError(t, err)
+
+		require.Len(t, store.recorded, 1)
+		assert.False(t, store.recorded[0].LastSentAt.Valid)
+		assert.True(t, store.recorded[0].NextSendAt.After(time.Now()))
+	})
+}
@@ -0,0 +1,149 @@
+// Package greader implements the identifiers of the Google Reader API, which
+// native feed reader apps use to sync with their servers.
+package greader
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// States items can be tagged with, as stream IDs.
+const (
+	StateReadingList = "user/-/state/com.google/reading-list"
+	StateRead        = "user/-/state/com.google/read"
+	StateKeptUnread  = "user/-/state/com.google/kept-unread"
+	StateStarred     = "user/-/state/com.google/starred"
+)
+
+const (
+	feedPrefix  = "feed/"
+	labelPrefix = "user/-/label/"
+	itemPrefix  = "tag:google.com,2005:reader/item/"
+)
+
+// Kinds of streams.
+const (
+	StreamReadingList = "reading-list"
+	StreamRead        = "read"
+	StreamStarred     = "starred"
+	StreamFeed        = "feed"
+	StreamLabel       = "label"
+)
+
+var (
+	ErrInvalidStream = errors.New("invalid stream id")
+	ErrInvalidItem   = errors.New("invalid item id")
+)
+
+// Stream is a parsed stream ID. Feeds are identified by their ID, but
+// clients subscribing to a new feed name it by URL instead.
+type Stream struct {
+	Kind   string
+	FeedID uuid.UUID
+	URL    string
+	Label  string
+}
+
+// Normalize replaces the user named in a state or label ID with the "-"
+// standing for the current user, which is how clients may name them.
+func Normalize(id string) string {
+	parts := strings.SplitN(id, "/", 3)
+	if len(parts) != 3 || parts[0] != "user" {
+		return id
+	}
+	return "user/-/" + parts[2]
+}
+
+// ParseStream parses a stream ID.
+func ParseStream(id string) (Stream, error) {
+	if rest, ok := strings.CutPrefix(id, feedPrefix); ok {
+		if feedID, err := uuid.Parse(rest); err == nil {
+			return Stream{Kind: StreamFeed, FeedID: feedID}, nil
+		}
+		if rest == "" {
+			return Stream{}, ErrInvalidStream
+		}
+		return Stream{Kind: StreamFeed, URL: rest}, nil
+	}
+
+	id = Normalize(id)
+	switch id {
+	case StateReadingList:
+		return Stream{Kind: StreamReadingList}, nil
+	case StateRead:
+		return Stream{Kind: StreamRead}, nil
+	case StateStarred:
+		return Stream{Kind: StreamStarred}, nil
+	}
+
+	if label, ok := strings.CutPrefix(id, labelPrefix); ok && label != "" {
+		return Stream{Kind: StreamLabel, Label: label}, nil
+	}
+	return Stream{}, ErrInvalidStream
+}
+
+// FeedStreamID returns the stream ID of a feed.
+func FeedStreamID(feedID uuid.UUID) string {
+	return feedPrefix + feedID.String()
+}
+
+// LabelStreamID returns the stream ID of a label, which is how folders are
+// named in the API.
+func LabelStreamID(label string) string {
+	return labelPrefix + label
+}
+
+// SiteURL guesses the address of the site a feed belongs to, as the root of
+// the feed's host.
+func SiteURL(feedURL string) string {
+	u, err := url.Parse(feedURL)
+	if err != nil || u.Host == "" {
+		return feedURL
+	}
+	return u.Scheme + "://" + u.Host + "/"
+}
+
+// ParseItemID parses an item ID in either its long form, a tag URI ending in
+// 16 hex digits, or its short form, a signed decimal number.
+func ParseItemID(id string) (int64, error) {
+	if hex, ok := strings.CutPrefix(id, itemPrefix); ok {
+		n, err := strconv.ParseUint(hex, 16, 64)
+		if err != nil {
+			return 0, ErrInvalidItem
+		}
+		return int64(n), nil //#nosec G115
+	}
+
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidItem
+	}
+	return n, nil
+}
+
+// LongItemID returns the long form of an item ID.
+func LongItemID(id int64) string {
+	return fmt.Sprintf("%s%016x", itemPrefix, uint64(id)) //#nosec G115
+}
+
+// ShortItemID returns the short form of an item ID.
+func ShortItemID(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
+
+// Usec returns t in microseconds since the epoch, as the API's timestamps
+// are given.
+func Usec(t time.Time) string {
+	return strconv.FormatInt(t.UnixMicro(), 10)
+}
+
+// Msec returns t in milliseconds since the epoch.
+func Msec(t time.Time) string {
+	return strconv.FormatInt(t.UnixMilli(), 10)
+}
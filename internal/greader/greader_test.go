@@ -0,0 +1,64 @@
+package greader
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStream(t *testing.T) {
+	feedID := uuid.New()
+
+	tests := []struct {
+		id   string
+		want Stream
+	}{
+		{"user/-/state/com.google/reading-list", Stream{Kind: StreamReadingList}},
+		{"user/1234/state/com.google/starred", Stream{Kind: StreamStarred}},
+		{"user/-/state/com.google/read", Stream{Kind: StreamRead}},
+		{"user/-/label/Go / Rust", Stream{Kind: StreamLabel, Label: "Go / Rust"}},
+		{FeedStreamID(feedID), Stream{Kind: StreamFeed, FeedID: feedID}},
+		{"feed/https://example.com/rss", Stream{Kind: StreamFeed, URL: "https://example.com/rss"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			stream, err := ParseStream(tt.id)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, stream)
+		})
+	}
+
+	for _, id := range []string{"", "feed/", "user/-/label/", "user/-/state/com.google/like", "pop/-/label/Go"} {
+		_, err := ParseStream(id)
+		assert.ErrorIs(t, err, ErrInvalidStream, id)
+	}
+}
+
+func TestItemIDs(t *testing.T) {
+	for _, id := range []int64{1, 26, 1 << 40, -2} {
+		long, err := ParseItemID(LongItemID(id))
+		require.NoError(t, err)
+		assert.Equal(t, id, long)
+
+		short, err := ParseItemID(ShortItemID(id))
+		require.NoError(t, err)
+		assert.Equal(t, id, short)
+	}
+
+	assert.Equal(t, "tag:google.com,2005:reader/item/000000000000001a", LongItemID(26))
+	assert.Equal(t, "-2", ShortItemID(-2))
+	assert.Equal(t, "tag:google.com,2005:reader/item/fffffffffffffffe", LongItemID(-2))
+
+	for _, id := range []string{"", "1a", "tag:google.com,2005:reader/item/xyz"} {
+		_, err := ParseItemID(id)
+		assert.ErrorIs(t, err, ErrInvalidItem, id)
+	}
+}
+
+func TestSiteURL(t *testing.T) {
+	assert.Equal(t, "https://example.com/", SiteURL("https://example.com/blog/rss.xml"))
+	assert.Equal(t, "not a url", SiteURL("not a url"))
+}
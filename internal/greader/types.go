@@ -0,0 +1,70 @@
+package greader
+
+// Category is a label a subscription is filed under.
+type Category struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+}
+
+// Subscription is a followed feed.
+type Subscription struct {
+	ID         string     `json:"id"`
+	Title      string     `json:"title"`
+	Categories []Category `json:"categories"`
+	URL        string     `json:"url"`
+	HTMLURL    string     `json:"htmlUrl"`
+	IconURL    string     `json:"iconUrl"`
+}
+
+// Tag is a state or label items and subscriptions can be tagged with.
+type Tag struct {
+	ID   string `json:"id"`
+	Type string `json:"type,omitempty"`
+}
+
+// ItemRef is an item as listed by its ID.
+type ItemRef struct {
+	ID              string   `json:"id"`
+	DirectStreamIDs []string `json:"directStreamIds"`
+	TimestampUsec   string   `json:"timestampUsec"`
+}
+
+// Item is an item with its contents.
+type Item struct {
+	ID            string   `json:"id"`
+	CrawlTimeMsec string   `json:"crawlTimeMsec"`
+	TimestampUsec string   `json:"timestampUsec"`
+	Published     int64    `json:"published"`
+	Updated       int64    `json:"updated"`
+	Title         string   `json:"title"`
+	Canonical     []Link   `json:"canonical"`
+	Alternate     []Link   `json:"alternate"`
+	Summary       Content  `json:"summary"`
+	Author        string   `json:"author,omitempty"`
+	Categories    []string `json:"categories"`
+	Origin        Origin   `json:"origin"`
+	Enclosure     []Link   `json:"enclosure,omitempty"`
+}
+
+type Link struct {
+	Href string `json:"href"`
+	Type string `json:"type,omitempty"`
+}
+
+type Content struct {
+	Direction string `json:"direction"`
+	Content   string `json:"content"`
+}
+
+// Origin is the subscription an item came from.
+type Origin struct {
+	StreamID string `json:"streamId"`
+	Title    string `json:"title"`
+	HTMLURL  string `json:"htmlUrl"`
+}
+
+// UnreadCount is the number of unread items in a stream.
+type UnreadCount struct {
+	ID    string `json:"id"`
+	Count int64  `json:"count"`
+}
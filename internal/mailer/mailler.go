@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"embed"
 	"html/template"
+	texttemplate "text/template"
 	"time"
 
 	"github.com/go-mail/mail/v2"
@@ -27,26 +28,14 @@ func New(host string, port int, username, password, sender string) Mailer {
 	}
 }
 
-func (m Mailer) Send(recipient, templateFile string, data any) error {
-	tmpl, err := template.New("email").ParseFS(templateFS, "templates/"+templateFile)
-	if err != nil {
-		return err
-	}
-
-	subject := new(bytes.Buffer)
-	err = tmpl.ExecuteTemplate(subject, "subject", data)
-	if err != nil {
-		return err
-	}
-
-	plainBody := new(bytes.Buffer)
-	err = tmpl.ExecuteTemplate(plainBody, "plainBody", data)
-	if err != nil {
-		return err
-	}
+// Headerer is implemented by template data whose emails need headers of
+// their own, such as List-Unsubscribe.
+type Headerer interface {
+	Headers() map[string]string
+}
 
-	htmlBody := new(bytes.Buffer)
-	err = tmpl.ExecuteTemplate(htmlBody, "htmlBody", data)
+func (m Mailer) Send(recipient, templateFile string, data any) error {
+	subject, plainBody, htmlBody, err := render(templateFile, data)
 	if err != nil {
 		return err
 	}
@@ -54,9 +43,14 @@ func (m Mailer) Send(recipient, templateFile string, data any) error {
 	msg := mail.NewMessage()
 	msg.SetHeader("To", recipient)
 	msg.SetHeader("From", m.sender)
-	msg.SetHeader("Subject", subject.String())
-	msg.SetBody("text/plain", plainBody.String())
-	msg.AddAlternative("text/html", htmlBody.String())
+	msg.SetHeader("Subject", subject)
+	if h, ok := data.(Headerer); ok {
+		for name, value := range h.Headers() {
+			msg.SetHeader(name, value)
+		}
+	}
+	msg.SetBody("text/plain", plainBody)
+	msg.AddAlternative("text/html", htmlBody)
 
 	for i := 1; i <= 3; i++ {
 		err = m.dialer.DialAndSend(msg)
@@ -68,3 +62,38 @@ func (m Mailer) Send(recipient, templateFile string, data any) error {
 
 	return err
 }
+
+// render executes a template file's subject, plainBody and htmlBody. Only the
+// HTML body is escaped as HTML: the subject and plain text body are sent as
+// they are, so escaping them would show entities such as &amp; to readers.
+func render(templateFile string, data any) (subject, plainBody, htmlBody string, err error) {
+	textTmpl, err := texttemplate.New("email").ParseFS(templateFS, "templates/"+templateFile)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	htmlTmpl, err := template.New("email").ParseFS(templateFS, "templates/"+templateFile)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	subjectBuf := new(bytes.Buffer)
+	err = textTmpl.ExecuteTemplate(subjectBuf, "subject", data)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	plainBodyBuf := new(bytes.Buffer)
+	err = textTmpl.ExecuteTemplate(plainBodyBuf, "plainBody", data)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	htmlBodyBuf := new(bytes.Buffer)
+	err = htmlTmpl.ExecuteTemplate(htmlBodyBuf, "htmlBody", data)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	return subjectBuf.String(), plainBodyBuf.String(), htmlBodyBuf.String(), nil
+}
@@ -0,0 +1,38 @@
+package mailer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/alerts"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/digests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	posts := []alerts.Post{{
+		Title:       `AT&T's "big" outage`,
+		Url:         "https://example.com/?a=1&b=2",
+		FeedName:    "Q&A",
+		PublishedAt: time.Date(2024, 1, 2, 3, 4, 0, 0, time.UTC),
+	}}
+
+	t.Run("Alert", func(t *testing.T) {
+		subject, plainBody, htmlBody, err := render("alert.tmpl", alerts.Email{Phrase: "Q&A", Posts: posts, Total: 1})
+		require.NoError(t, err)
+		assert.Equal(t, `AT&T's "big" outage matching "Q&A"`, subject)
+		assert.Contains(t, plainBody, `AT&T's "big" outage`)
+		assert.Contains(t, plainBody, "https://example.com/?a=1&b=2")
+		assert.Contains(t, htmlBody, "AT&amp;T&#39;s &#34;big&#34; outage")
+	})
+
+	t.Run("Digest", func(t *testing.T) {
+		digestPosts := []digests.Post{{Title: posts[0].Title, Url: posts[0].Url, FeedName: posts[0].FeedName, PublishedAt: posts[0].PublishedAt}}
+		subject, plainBody, htmlBody, err := render("digest.tmpl", digests.Email{Schedule: "daily", Posts: digestPosts, Total: 1})
+		require.NoError(t, err)
+		assert.NotContains(t, subject, "&amp;")
+		assert.Contains(t, plainBody, `AT&T's "big" outage`)
+		assert.Contains(t, htmlBody, "AT&amp;T&#39;s &#34;big&#34; outage")
+	})
+}
@@ -0,0 +1,104 @@
+package netguard
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateAddress is returned when a host resolves to an address that isn't
+// on the public internet.
+var ErrPrivateAddress = errors.New("address not allowed")
+
+var (
+	ErrRequestTimeout = errors.New("request timed out")
+	ErrRequestFailed  = errors.New("request failed")
+)
+
+// nonPublicPrefixes are the ranges, besides those netip.Addr reports as
+// private, loopback, link-local or unspecified, that aren't on the public
+// internet.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+}
+
+// Transport returns a copy of http.DefaultTransport that only connects to
+// public addresses, unless allowPrivate is set. It's for requests to URLs
+// users choose, such as feeds and webhooks, which could otherwise be used to
+// reach the server's own network.
+func Transport(allowPrivate bool, timeout time.Duration) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if allowPrivate {
+		return transport
+	}
+
+	// The address is checked as it's dialled, after DNS resolution, so a host
+	// can't resolve to a public address when validated and a private one when
+	// requested, and redirects are checked too.
+	dialer := &net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil || !IsPublicAddr(addrPort.Addr()) {
+				return ErrPrivateAddress
+			}
+			return nil
+		},
+	}
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+// Redact returns what can be shown to users of why a request got no
+// response. Errors are kept coarse, as anything more, such as whether a
+// connection was refused, would tell them what's reachable from the server.
+func Redact(err error) error {
+	switch {
+	case errors.Is(err, ErrPrivateAddress):
+		return ErrPrivateAddress
+	case errors.Is(err, context.DeadlineExceeded) || os.IsTimeout(err):
+		return ErrRequestTimeout
+	default:
+		return ErrRequestFailed
+	}
+}
+
+// IsPublicAddr reports whether an address is on the public internet.
+func IsPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsPublicHost reports whether a URL's host could be a public address. Names
+// other than localhost are only checked once they're resolved, when dialled.
+func IsPublicHost(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return IsPublicAddr(addr)
+	}
+	return true
+}
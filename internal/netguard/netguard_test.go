@@ -0,0 +1,63 @@
+package netguard
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsPublicHost(t *testing.T) {
+	for host, public := range map[string]bool{
+		"example.com":     true,
+		"93.184.215.14":   true,
+		"2606:4700::1111": true,
+		"localhost":       false,
+		"api.localhost.":  false,
+		"127.0.0.1":       false,
+		"10.1.2.3":        false,
+		"192.168.0.10":    false,
+		"169.254.169.254": false,
+		"100.64.0.1":      false,
+		"0.0.0.0":         false,
+		"::1":             false,
+		"fd00::1":         false,
+		"::ffff:10.0.0.1": false,
+	} {
+		assert.Equal(t, public, IsPublicHost(host), host)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	t.Run("Refuses private addresses", func(t *testing.T) {
+		client := &http.Client{Transport: Transport(false, time.Second)}
+		_, err := client.Get(server.URL)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, ErrPrivateAddress)
+		assert.Equal(t, ErrPrivateAddress, Redact(err))
+	})
+
+	t.Run("Allows private addresses when asked", func(t *testing.T) {
+		client := &http.Client{Transport: Transport(true, time.Second)}
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+}
+
+func TestRedact(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	<-ctx.Done()
+
+	assert.Equal(t, ErrRequestTimeout, Redact(ctx.Err()))
+	assert.Equal(t, ErrRequestFailed, Redact(assert.AnError))
+}
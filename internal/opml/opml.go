@@ -0,0 +1,123 @@
+// Package opml reads and writes the OPML subscription lists feed readers use
+// to import and export their feeds.
+package opml
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+var ErrNotOPML = errors.New("file is not an OPML document")
+
+// Entry is a feed in a subscription list. Folder is the name of the outline
+// the feed is listed under, if any.
+type Entry struct {
+	Title  string
+	URL    string
+	Folder string
+}
+
+type document struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    head     `xml:"head"`
+	Body    body     `xml:"body"`
+}
+
+type head struct {
+	Title       string `xml:"title"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+type body struct {
+	Outlines []outline `xml:"outline"`
+}
+
+type outline struct {
+	Text     string    `xml:"text,attr"`
+	Title    string    `xml:"title,attr,omitempty"`
+	Type     string    `xml:"type,attr,omitempty"`
+	XMLURL   string    `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string    `xml:"htmlUrl,attr,omitempty"`
+	Outlines []outline `xml:"outline"`
+}
+
+func (o outline) name() string {
+	if name := strings.TrimSpace(o.Text); name != "" {
+		return name
+	}
+	return strings.TrimSpace(o.Title)
+}
+
+// Parse returns the feeds listed in an OPML document, in the order they're
+// listed. Folders can't be nested, so a feed in a nested outline is put in
+// the folder of the outline directly around it.
+func Parse(r io.Reader) ([]Entry, error) {
+	var doc document
+	err := xml.NewDecoder(r).Decode(&doc)
+	if err != nil {
+		// Errors reading r, such as the body being too large, are passed on.
+		var syntaxError *xml.SyntaxError
+		var unmarshalError xml.UnmarshalError
+		if errors.As(err, &syntaxError) || errors.As(err, &unmarshalError) || errors.Is(err, io.EOF) {
+			return nil, ErrNotOPML
+		}
+		return nil, err
+	}
+
+	var entries []Entry
+	var walk func(outlines []outline, folder string)
+	walk = func(outlines []outline, folder string) {
+		for _, o := range outlines {
+			url := strings.TrimSpace(o.XMLURL)
+			if url == "" {
+				walk(o.Outlines, o.name())
+				continue
+			}
+
+			title := o.name()
+			if title == "" {
+				title = url
+			}
+			entries = append(entries, Entry{Title: title, URL: url, Folder: folder})
+		}
+	}
+	walk(doc.Body.Outlines, "")
+
+	return entries, nil
+}
+
+// Render returns an OPML document listing the entries, with each folder's
+// feeds grouped under an outline placed where its first feed appears.
+func Render(title string, created time.Time, entries []Entry) ([]byte, error) {
+	doc := document{
+		Version: "2.0",
+		Head:    head{Title: title, DateCreated: created.UTC().Format(time.RFC1123Z)},
+	}
+
+	folders := map[string]int{}
+	for _, entry := range entries {
+		feed := outline{Text: entry.Title, Title: entry.Title, Type: "rss", XMLURL: entry.URL}
+		if entry.Folder == "" {
+			doc.Body.Outlines = append(doc.Body.Outlines, feed)
+			continue
+		}
+
+		i, ok := folders[entry.Folder]
+		if !ok {
+			i = len(doc.Body.Outlines)
+			folders[entry.Folder] = i
+			doc.Body.Outlines = append(doc.Body.Outlines, outline{Text: entry.Folder, Title: entry.Folder})
+		}
+		doc.Body.Outlines[i].Outlines = append(doc.Body.Outlines[i].Outlines, feed)
+	}
+
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
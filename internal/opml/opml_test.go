@@ -0,0 +1,61 @@
+package opml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const export = `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="1.0">
+  <head><title>Subscriptions</title></head>
+  <body>
+    <outline text="Go Blog" title="The Go Blog" type="rss" xmlUrl="https://go.dev/blog/feed.atom"/>
+    <outline text="Databases">
+      <outline title="Postgres Weekly" type="rss" xmlUrl=" https://postgresweekly.com/rss "/>
+      <outline text="Archive">
+        <outline type="rss" xmlUrl="https://example.com/old.xml"/>
+      </outline>
+    </outline>
+    <outline text="Empty folder"/>
+  </body>
+</opml>`
+
+func TestParse(t *testing.T) {
+	entries, err := Parse(strings.NewReader(export))
+	require.NoError(t, err)
+
+	assert.Equal(t, []Entry{
+		{Title: "Go Blog", URL: "https://go.dev/blog/feed.atom"},
+		{Title: "Postgres Weekly", URL: "https://postgresweekly.com/rss", Folder: "Databases"},
+		{Title: "https://example.com/old.xml", URL: "https://example.com/old.xml", Folder: "Archive"},
+	}, entries)
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, body := range []string{"", "not xml", "<rss><channel/></rss>"} {
+		_, err := Parse(strings.NewReader(body))
+		assert.ErrorIs(t, err, ErrNotOPML, body)
+	}
+}
+
+func TestRender(t *testing.T) {
+	entries := []Entry{
+		{Title: "Postgres Weekly", URL: "https://postgresweekly.com/rss", Folder: "Databases"},
+		{Title: "Go & friends", URL: "https://go.dev/blog/feed.atom"},
+		{Title: "Use The Index", URL: "https://use-the-index-luke.com/blog/feed", Folder: "Databases"},
+	}
+
+	body, err := Render("Subscriptions", time.Date(2024, 3, 20, 9, 0, 0, 0, time.UTC), entries)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "<dateCreated>Wed, 20 Mar 2024 09:00:00 +0000</dateCreated>")
+
+	// Rendered documents parse back to the same entries, grouped by folder.
+	parsed, err := Parse(bytes.NewReader(body))
+	require.NoError(t, err)
+	assert.Equal(t, []Entry{entries[0], entries[2], entries[1]}, parsed)
+}
@@ -0,0 +1,82 @@
+package retention
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+)
+
+// Store is the subset of database.Queries the pruner needs.
+type Store interface {
+	PrunePosts(ctx context.Context, arg database.PrunePostsParams) (int64, error)
+	DeletePrunedPostsBefore(ctx context.Context, prunedAt time.Time) (int64, error)
+}
+
+// Policy is the default retention applied to feeds without their own
+// override. A zero value for either limit means posts are never pruned on
+// that basis.
+type Policy struct {
+	MaxAgeDays int
+	MaxPosts   int
+}
+
+type Pruner struct {
+	store  Store
+	policy Policy
+	pruned atomic.Int64
+}
+
+func New(store Store, policy Policy) *Pruner {
+	return &Pruner{
+		store:  store,
+		policy: policy,
+	}
+}
+
+// Start prunes posts every interval. Records of pruned posts, which stop the
+// scraper from collecting them again, are kept for tombstoneRetention.
+func (p *Pruner) Start(interval, tombstoneRetention time.Duration) {
+	log.Printf("Pruning posts every %s...", interval)
+	ticker := time.NewTicker(interval)
+
+	for ; ; <-ticker.C {
+		_, err := p.PruneOnce(context.Background())
+		if err != nil {
+			log.Println("Couldn't prune posts", err)
+			continue
+		}
+
+		_, err = p.store.DeletePrunedPostsBefore(context.Background(), time.Now().UTC().Add(-tombstoneRetention))
+		if err != nil {
+			log.Println("Couldn't delete pruned post records", err)
+		}
+	}
+}
+
+// PruneOnce removes every post that falls outside its feed's retention policy
+// and returns how many were removed.
+func (p *Pruner) PruneOnce(ctx context.Context) (int64, error) {
+	pruned, err := p.store.PrunePosts(ctx, database.PrunePostsParams{
+		DefaultDays:     int32(p.policy.MaxAgeDays), //#nosec G115
+		DefaultMaxPosts: int32(p.policy.MaxPosts),   //#nosec G115
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	p.pruned.Add(pruned)
+	if pruned > 0 {
+		log.Printf("Pruned %v posts", pruned)
+	}
+
+	return pruned, nil
+}
+
+// Pruned returns the total number of posts removed since the pruner was
+// created.
+func (p *Pruner) Pruned() int64 {
+	return p.pruned.Load()
+}
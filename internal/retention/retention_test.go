@@ -0,0 +1,63 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStore struct {
+	calls  []database.PrunePostsParams
+	pruned int64
+	err    error
+}
+
+func (f *fakeStore) PrunePosts(ctx context.Context, arg database.PrunePostsParams) (int64, error) {
+	f.calls = append(f.calls, arg)
+	return f.pruned, f.err
+}
+
+func (f *fakeStore) DeletePrunedPostsBefore(ctx context.Context, prunedAt time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestPruneOnce(t *testing.T) {
+	t.Run("Passes the default policy", func(t *testing.T) {
+		store := &fakeStore{pruned: 3}
+		p := New(store, Policy{MaxAgeDays: 90, MaxPosts: 500})
+
+		pruned, err := p.PruneOnce(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), pruned)
+
+		require.Len(t, store.calls, 1)
+		assert.Equal(t, int32(90), store.calls[0].DefaultDays)
+		assert.Equal(t, int32(500), store.calls[0].DefaultMaxPosts)
+	})
+
+	t.Run("Counts removed posts", func(t *testing.T) {
+		store := &fakeStore{pruned: 4}
+		p := New(store, Policy{})
+
+		_, err := p.PruneOnce(context.Background())
+		require.NoError(t, err)
+		_, err = p.PruneOnce(context.Background())
+		require.NoError(t, err)
+
+		assert.Equal(t, int64(8), p.Pruned())
+	})
+
+	t.Run("Errors are not counted", func(t *testing.T) {
+		store := &fakeStore{pruned: 4, err: errors.New("connection refused")}
+		p := New(store, Policy{})
+
+		_, err := p.PruneOnce(context.Background())
+		assert.Error(t, err)
+		assert.Equal(t, int64(0), p.Pruned())
+	})
+}
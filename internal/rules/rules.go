@@ -0,0 +1,271 @@
+package rules
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+// Fields a rule can match against. FieldFeed matches the feed's name or the
+// follower's own title for it.
+const (
+	FieldAny         = "any"
+	FieldTitle       = "title"
+	FieldDescription = "description"
+	FieldAuthor      = "author"
+	FieldFeed        = "feed"
+)
+
+const (
+	MatchText  = "text"
+	MatchRegex = "regex"
+)
+
+const (
+	ActionHide     = "hide"
+	ActionMarkRead = "mark_read"
+	ActionStar     = "star"
+	ActionTag      = "tag"
+	ActionNotify   = "notify"
+)
+
+var (
+	Fields     = []string{FieldAny, FieldTitle, FieldDescription, FieldAuthor, FieldFeed}
+	MatchTypes = []string{MatchText, MatchRegex}
+	Actions    = []string{ActionHide, ActionMarkRead, ActionStar, ActionTag, ActionNotify}
+)
+
+// Post is the text of a post that rules are matched against.
+type Post struct {
+	Title       string
+	Description string
+	Author      string
+	FeedName    string
+	FollowTitle string
+}
+
+// Matcher reports whether posts match a rule's pattern. Matching ignores
+// case.
+type Matcher struct {
+	field string
+	text  string
+	re    *regexp.Regexp
+}
+
+func Compile(field, matchType, pattern string) (*Matcher, error) {
+	m := &Matcher{field: field}
+
+	switch matchType {
+	case MatchText:
+		m.text = strings.ToLower(pattern)
+	case MatchRegex:
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return nil, err
+		}
+		m.re = re
+	default:
+		return nil, fmt.Errorf("unknown match type %q", matchType)
+	}
+
+	return m, nil
+}
+
+func (m *Matcher) Match(p Post) bool {
+	var values []string
+	switch m.field {
+	case FieldTitle:
+		values = []string{p.Title}
+	case FieldDescription:
+		values = []string{p.Description}
+	case FieldAuthor:
+		values = []string{p.Author}
+	case FieldFeed:
+		values = []string{p.FeedName, p.FollowTitle}
+	default:
+		values = []string{p.Title, p.Description, p.Author}
+	}
+
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		if m.re != nil && m.re.MatchString(value) {
+			return true
+		}
+		if m.re == nil && strings.Contains(strings.ToLower(value), m.text) {
+			return true
+		}
+	}
+	return false
+}
+
+// Store is the subset of database.Queries rules are read from and applied
+// through.
+type Store interface {
+	GetRulesForFeed(ctx context.Context, feedID uuid.UUID) ([]database.GetRulesForFeedRow, error)
+	GetPostsToMatch(ctx context.Context, arg database.GetPostsToMatchParams) ([]database.GetPostsToMatchRow, error)
+	SetPostHidden(ctx context.Context, arg database.SetPostHiddenParams) (int64, error)
+	SetPostsReadState(ctx context.Context, arg database.SetPostsReadStateParams) (int64, error)
+	SetPostStarred(ctx context.Context, arg database.SetPostStarredParams) (int64, error)
+	AddPostTags(ctx context.Context, arg database.AddPostTagsParams) (int64, error)
+	CreateNotification(ctx context.Context, arg database.CreateNotificationParams) error
+}
+
+// Engine evaluates users' rules against posts and carries out their actions.
+type Engine struct {
+	store Store
+}
+
+func New(store Store) *Engine {
+	return &Engine{store: store}
+}
+
+// PostCreated applies the rules of every follower of feed to a newly
+// collected post. It has the signature of a scraper hook.
+func (e *Engine) PostCreated(ctx context.Context, feed database.Feed, post database.Post) {
+	rules, err := e.store.GetRulesForFeed(ctx, feed.ID)
+	if err != nil {
+		log.Printf("Couldn't get rules for feed %s: %v", feed.Name, err)
+		return
+	}
+
+	for _, row := range rules {
+		m, err := Compile(row.Rule.Field, row.Rule.MatchType, row.Rule.Pattern)
+		if err != nil {
+			log.Printf("Couldn't compile rule %s: %v", row.Rule.ID, err)
+			continue
+		}
+
+		p := Post{
+			Title:       post.Title,
+			Description: post.Description.String,
+			Author:      post.Author.String,
+			FeedName:    feed.Name,
+			FollowTitle: row.FollowTitle.String,
+		}
+		if !m.Match(p) {
+			continue
+		}
+
+		err = e.apply(ctx, row.Rule, post.ID, row.Notify)
+		if err != nil {
+			log.Printf("Couldn't apply rule %s to post %s: %v", row.Rule.ID, post.ID, err)
+		}
+	}
+}
+
+// matchBatchSize is how many posts are read at a time when a rule is applied
+// to existing posts.
+const matchBatchSize = 500
+
+// Apply runs a rule against every post already in its owner's followed feeds
+// and returns how many matched. Notifications are only sent for new posts,
+// so the notify action is skipped.
+func (e *Engine) Apply(ctx context.Context, rule database.Rule) (int, error) {
+	m, err := Compile(rule.Field, rule.MatchType, rule.Pattern)
+	if err != nil {
+		return 0, err
+	}
+
+	matched := 0
+	after := uuid.Nil
+	for {
+		posts, err := e.store.GetPostsToMatch(ctx, database.GetPostsToMatchParams{
+			UserID: rule.UserID,
+			FeedID: rule.FeedID,
+			After:  after,
+			Lim:    matchBatchSize,
+		})
+		if err != nil {
+			return matched, err
+		}
+
+		for _, row := range posts {
+			p := Post{
+				Title:       row.Post.Title,
+				Description: row.Post.Description.String,
+				Author:      row.Post.Author.String,
+				FeedName:    row.FeedName,
+				FollowTitle: row.FollowTitle.String,
+			}
+			if !m.Match(p) {
+				continue
+			}
+
+			matched++
+			err = e.apply(ctx, rule, row.Post.ID, false)
+			if err != nil {
+				return matched, err
+			}
+		}
+
+		if len(posts) < matchBatchSize {
+			return matched, nil
+		}
+		after = posts[len(posts)-1].Post.ID
+	}
+}
+
+// apply carries out a rule's actions on a post for the rule's owner.
+func (e *Engine) apply(ctx context.Context, rule database.Rule, postID uuid.UUID, notify bool) error {
+	now := time.Now().UTC()
+
+	for _, action := range rule.Actions {
+		var err error
+		switch action {
+		case ActionHide:
+			_, err = e.store.SetPostHidden(ctx, database.SetPostHiddenParams{
+				UserID:    rule.UserID,
+				HiddenAt:  sql.NullTime{Time: now, Valid: true},
+				UpdatedAt: now,
+				PostID:    postID,
+			})
+		case ActionMarkRead:
+			_, err = e.store.SetPostsReadState(ctx, database.SetPostsReadStateParams{
+				UserID:    rule.UserID,
+				ReadAt:    sql.NullTime{Time: now, Valid: true},
+				UpdatedAt: now,
+				PostIds:   []uuid.UUID{postID},
+			})
+		case ActionStar:
+			_, err = e.store.SetPostStarred(ctx, database.SetPostStarredParams{
+				UserID:    rule.UserID,
+				StarredAt: sql.NullTime{Time: now, Valid: true},
+				UpdatedAt: now,
+				PostID:    postID,
+			})
+		case ActionTag:
+			if rule.Tag.Valid {
+				_, err = e.store.AddPostTags(ctx, database.AddPostTagsParams{
+					CreatedAt: now,
+					UserID:    rule.UserID,
+					Names:     []string{rule.Tag.String},
+					PostID:    postID,
+				})
+			}
+		case ActionNotify:
+			if notify {
+				err = e.store.CreateNotification(ctx, database.CreateNotificationParams{
+					ID:        uuid.New(),
+					CreatedAt: now,
+					UserID:    rule.UserID,
+					PostID:    postID,
+					RuleID:    rule.ID,
+				})
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", action, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,199 @@
+package rules
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"testing"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStore struct {
+	rules         []database.GetRulesForFeedRow
+	posts         []database.GetPostsToMatchRow
+	hidden        []uuid.UUID
+	read          []uuid.UUID
+	starred       []uuid.UUID
+	tagged        map[uuid.UUID][]string
+	notifications []uuid.UUID
+}
+
+func (f *fakeStore) GetRulesForFeed(ctx context.Context, feedID uuid.UUID) ([]database.GetRulesForFeedRow, error) {
+	return f.rules, nil
+}
+
+func (f *fakeStore) GetPostsToMatch(ctx context.Context, arg database.GetPostsToMatchParams) ([]database.GetPostsToMatchRow, error) {
+	var result []database.GetPostsToMatchRow
+	for _, row := range f.posts {
+		if row.Post.ID.String() > arg.After.String() && len(result) < int(arg.Lim) {
+			result = append(result, row)
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeStore) SetPostHidden(ctx context.Context, arg database.SetPostHiddenParams) (int64, error) {
+	f.hidden = append(f.hidden, arg.PostID)
+	return 1, nil
+}
+
+func (f *fakeStore) SetPostsReadState(ctx context.Context, arg database.SetPostsReadStateParams) (int64, error) {
+	f.read = append(f.read, arg.PostIds...)
+	return int64(len(arg.PostIds)), nil
+}
+
+func (f *fakeStore) SetPostStarred(ctx context.Context, arg database.SetPostStarredParams) (int64, error) {
+	f.starred = append(f.starred, arg.PostID)
+	return 1, nil
+}
+
+func (f *fakeStore) AddPostTags(ctx context.Context, arg database.AddPostTagsParams) (int64, error) {
+	if f.tagged == nil {
+		f.tagged = make(map[uuid.UUID][]string)
+	}
+	f.tagged[arg.PostID] = append(f.tagged[arg.PostID], arg.Names...)
+	return int64(len(arg.Names)), nil
+}
+
+func (f *fakeStore) CreateNotification(ctx context.Context, arg database.CreateNotificationParams) error {
+	f.notifications = append(f.notifications, arg.PostID)
+	return nil
+}
+
+func TestMatcher(t *testing.T) {
+	post := Post{
+		Title:       "Sponsored: Buy Our Product",
+		Description: "<p>Release notes for Go 1.23</p>",
+		Author:      "Jane Doe",
+		FeedName:    "Example Blog",
+		FollowTitle: "My Favourite",
+	}
+
+	tests := []struct {
+		name      string
+		field     string
+		matchType string
+		pattern   string
+		want      bool
+	}{
+		{"Text ignores case", FieldTitle, MatchText, "sponsored", true},
+		{"Text only checks its field", FieldTitle, MatchText, "release notes", false},
+		{"Any checks every field", FieldAny, MatchText, "release notes", true},
+		{"Any doesn't check the feed", FieldAny, MatchText, "example blog", false},
+		{"Author", FieldAuthor, MatchText, "jane", true},
+		{"Feed name", FieldFeed, MatchText, "example", true},
+		{"Follow title", FieldFeed, MatchText, "favourite", true},
+		{"Regex", FieldDescription, MatchRegex, `go 1\.\d+`, true},
+		{"Regex anchors", FieldTitle, MatchRegex, `^buy`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Compile(tt.field, tt.matchType, tt.pattern)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, m.Match(post))
+		})
+	}
+
+	t.Run("Invalid regex", func(t *testing.T) {
+		_, err := Compile(FieldTitle, MatchRegex, "(unclosed")
+		assert.Error(t, err)
+	})
+
+	t.Run("Unknown match type", func(t *testing.T) {
+		_, err := Compile(FieldTitle, "glob", "*")
+		assert.Error(t, err)
+	})
+}
+
+func TestPostCreated(t *testing.T) {
+	feed := database.Feed{ID: uuid.New(), Name: "Example Blog"}
+	post := database.Post{ID: uuid.New(), Title: "Sponsored post", FeedID: feed.ID}
+
+	rule := func(pattern string, actions ...string) database.Rule {
+		return database.Rule{
+			ID:        uuid.New(),
+			UserID:    uuid.New(),
+			Field:     FieldTitle,
+			MatchType: MatchText,
+			Pattern:   pattern,
+			Actions:   actions,
+			Tag:       sql.NullString{String: "ads", Valid: true},
+			Enabled:   true,
+		}
+	}
+
+	t.Run("Matching rules are applied", func(t *testing.T) {
+		store := &fakeStore{rules: []database.GetRulesForFeedRow{
+			{Rule: rule("sponsored", ActionHide, ActionMarkRead, ActionTag), Notify: true},
+			{Rule: rule("giveaway", ActionStar), Notify: true},
+		}}
+
+		New(store).PostCreated(context.Background(), feed, post)
+
+		assert.Equal(t, []uuid.UUID{post.ID}, store.hidden)
+		assert.Equal(t, []uuid.UUID{post.ID}, store.read)
+		assert.Empty(t, store.starred)
+		assert.Equal(t, []string{"ads"}, store.tagged[post.ID])
+	})
+
+	t.Run("Notifications respect the follow setting", func(t *testing.T) {
+		store := &fakeStore{rules: []database.GetRulesForFeedRow{
+			{Rule: rule("sponsored", ActionNotify), Notify: true},
+			{Rule: rule("sponsored", ActionNotify), Notify: false},
+		}}
+
+		New(store).PostCreated(context.Background(), feed, post)
+
+		assert.Equal(t, []uuid.UUID{post.ID}, store.notifications)
+	})
+
+	t.Run("Invalid rules are skipped", func(t *testing.T) {
+		broken := rule("(", ActionStar)
+		broken.MatchType = MatchRegex
+		store := &fakeStore{rules: []database.GetRulesForFeedRow{
+			{Rule: broken},
+			{Rule: rule("sponsored", ActionStar)},
+		}}
+
+		New(store).PostCreated(context.Background(), feed, post)
+
+		assert.Equal(t, []uuid.UUID{post.ID}, store.starred)
+	})
+}
+
+func TestApply(t *testing.T) {
+	store := &fakeStore{}
+	for i := 0; i < matchBatchSize+10; i++ {
+		title := "Weekly links"
+		if i%2 == 0 {
+			title = "Sponsored post"
+		}
+		store.posts = append(store.posts, database.GetPostsToMatchRow{
+			Post:   database.Post{ID: uuid.New(), Title: title},
+			Notify: true,
+		})
+	}
+	// The store pages by ID, so keep the posts in ID order.
+	sort.Slice(store.posts, func(i, j int) bool {
+		return store.posts[i].Post.ID.String() < store.posts[j].Post.ID.String()
+	})
+
+	matched, err := New(store).Apply(context.Background(), database.Rule{
+		ID:        uuid.New(),
+		UserID:    uuid.New(),
+		Field:     FieldTitle,
+		MatchType: MatchText,
+		Pattern:   "sponsored",
+		Actions:   []string{ActionHide, ActionNotify},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, (matchBatchSize+10)/2, matched)
+	assert.Len(t, store.hidden, matched, "Posts past the first batch should be matched")
+	assert.Empty(t, store.notifications, "Existing posts shouldn't send notifications")
+}
@@ -0,0 +1,74 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/netguard"
+)
+
+// Fetcher retrieves the raw document for a feed URL.
+type Fetcher interface {
+	Fetch(ctx context.Context, feedURL string) ([]byte, FetchInfo, error)
+}
+
+// FetchInfo describes the HTTP side of a fetch, and is populated as far as the
+// request got even when Fetch returns an error.
+type FetchInfo struct {
+	StatusCode int
+	Bytes      int64
+}
+
+// HTTPFetcher fetches feeds over HTTP, treating any non-2xx response as an
+// error.
+type HTTPFetcher struct {
+	Client *http.Client
+}
+
+// NewHTTPFetcher returns an HTTPFetcher that only fetches feeds from public
+// addresses, unless allowPrivate is set.
+func NewHTTPFetcher(timeout time.Duration, allowPrivate bool) HTTPFetcher {
+	return HTTPFetcher{
+		Client: &http.Client{
+			Transport: netguard.Transport(allowPrivate, timeout),
+			Timeout:   timeout,
+		},
+	}
+}
+
+func (f HTTPFetcher) Fetch(ctx context.Context, feedURL string) ([]byte, FetchInfo, error) {
+	var info FetchInfo
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
+	if err != nil {
+		return nil, info, err
+	}
+
+	// Errors are recorded in the feed's fetch history, where anyone who
+	// follows it can read them, so they're redacted like webhooks'.
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		log.Printf("Couldn't fetch %s: %v", feedURL, err)
+		return nil, info, netguard.Redact(err)
+	}
+	defer resp.Body.Close()
+
+	info.StatusCode = resp.StatusCode
+
+	dat, err := io.ReadAll(resp.Body)
+	info.Bytes = int64(len(dat))
+	if err != nil {
+		log.Printf("Couldn't read %s: %v", feedURL, err)
+		return nil, info, netguard.Redact(err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, info, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return dat, info, nil
+}
@@ -0,0 +1,57 @@
+package scraper
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// Parser turns a fetched feed document into its items.
+type Parser interface {
+	Parse(body []byte) (*RSSFeed, error)
+}
+
+type RSSFeed struct {
+	Channel struct {
+		Title       string    `xml:"title"`
+		Link        string    `xml:"link"`
+		Description string    `xml:"description"`
+		Language    string    `xml:"language"`
+		Item        []RSSItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+type RSSItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	PubDate     string `xml:"pubDate"`
+	Author      string `xml:"author"`
+	Creator     string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Content     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Enclosure   struct {
+		URL  string `xml:"url,attr"`
+		Type string `xml:"type,attr"`
+	} `xml:"enclosure"`
+}
+
+// AuthorName returns the item's author, preferring the Dublin Core creator
+// many feeds use in place of RSS's e-mail address form.
+func (item RSSItem) AuthorName() string {
+	if item.Creator != "" {
+		return strings.TrimSpace(item.Creator)
+	}
+	return strings.TrimSpace(item.Author)
+}
+
+// RSSParser parses RSS 2.0 documents.
+type RSSParser struct{}
+
+func (RSSParser) Parse(body []byte) (*RSSFeed, error) {
+	var rssFeed RSSFeed
+	err := xml.Unmarshal(body, &rssFeed)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rssFeed, nil
+}
@@ -3,53 +3,138 @@ package scraper
 import (
 	"context"
 	"database/sql"
-	"encoding/xml"
-	"io"
+	"errors"
+	"fmt"
 	"log"
-	"net/http"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/data"
 	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/simhash"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/urlnorm"
 	"github.com/google/uuid"
 )
 
-func StartScraping(db *database.Queries, concurrency int, timeBetweenRequest time.Duration) {
+// Store is the subset of database.Queries the scraper reads feeds from and
+// writes posts and fetch history to.
+type Store interface {
+	GetNextFeedsToFetch(ctx context.Context, limit int32) ([]database.Feed, error)
+	MarkFeedFetched(ctx context.Context, id uuid.UUID) error
+	CreatePost(ctx context.Context, arg database.CreatePostParams) (database.Post, error)
+	FindPostClusterByURL(ctx context.Context, url string) (uuid.UUID, error)
+	FindPostClusterBySimhash(ctx context.Context, arg database.FindPostClusterBySimhashParams) (uuid.UUID, error)
+	CreateFeedFetch(ctx context.Context, arg database.CreateFeedFetchParams) (database.FeedFetch, error)
+	DeleteFeedFetchesBefore(ctx context.Context, startedAt time.Time) (int64, error)
+}
+
+// Hook is called for every post the scraper stores, with the feed it came
+// from.
+type Hook func(ctx context.Context, feed database.Feed, post database.Post)
+
+// clusterWindow is how far back new posts are compared against existing ones
+// when looking for near-duplicates.
+const clusterWindow = 7 * 24 * time.Hour
+
+type Scraper struct {
+	store   Store
+	fetcher Fetcher
+	parser  Parser
+	hooks   []Hook
+}
+
+func New(store Store, fetcher Fetcher, parser Parser) *Scraper {
+	return &Scraper{
+		store:   store,
+		fetcher: fetcher,
+		parser:  parser,
+	}
+}
+
+// AddHook registers a hook to run after each new post is stored. Hooks run on
+// the goroutine collecting the feed, so slow work should be handed off.
+func (s *Scraper) AddHook(hook Hook) {
+	s.hooks = append(s.hooks, hook)
+}
+
+func (s *Scraper) Start(concurrency int, timeBetweenRequest, fetchRetention time.Duration) {
 	log.Printf("Collecting feeds every %s on %v goroutines...", timeBetweenRequest, concurrency)
 	ticker := time.NewTicker(timeBetweenRequest)
 
 	for ; ; <-ticker.C {
-		feeds, err := db.GetNextFeedsToFetch(context.Background(), int32(concurrency)) //#nosec G115
+		s.ScrapeOnce(context.Background(), concurrency)
+
+		pruned, err := s.store.DeleteFeedFetchesBefore(context.Background(), time.Now().UTC().Add(-fetchRetention))
 		if err != nil {
-			log.Println("Couldn't get next feeds to fetch", err)
+			log.Println("Couldn't prune feed fetch history", err)
 			continue
 		}
-		log.Printf("Found %v feeds to fetch!", len(feeds))
-
-		wg := &sync.WaitGroup{}
-		for _, feed := range feeds {
-			wg.Add(1)
-			go ScrapeFeed(db, wg, feed)
+		if pruned > 0 {
+			log.Printf("Pruned %v feed fetches older than %s", pruned, fetchRetention)
 		}
-		wg.Wait()
 	}
 }
 
-func ScrapeFeed(db *database.Queries, wg *sync.WaitGroup, feed database.Feed) {
-	defer wg.Done()
-	err := db.MarkFeedFetched(context.Background(), feed.ID)
+// ScrapeOnce fetches the next batch of due feeds concurrently and returns once
+// all of them have been processed.
+func (s *Scraper) ScrapeOnce(ctx context.Context, concurrency int) {
+	feeds, err := s.store.GetNextFeedsToFetch(ctx, int32(concurrency)) //#nosec G115
+	if err != nil {
+		log.Println("Couldn't get next feeds to fetch", err)
+		return
+	}
+	log.Printf("Found %v feeds to fetch!", len(feeds))
+
+	wg := &sync.WaitGroup{}
+	for _, feed := range feeds {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.ScrapeFeed(ctx, feed)
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *Scraper) ScrapeFeed(ctx context.Context, feed database.Feed) {
+	err := s.store.MarkFeedFetched(ctx, feed.ID)
 	if err != nil {
 		log.Printf("Couldn't mark feed %s fetched: %v", feed.Name, err)
 		return
 	}
 
-	feedData, err := FetchFeed(feed.Url)
+	fetch := database.CreateFeedFetchParams{
+		ID:        uuid.New(),
+		FeedID:    feed.ID,
+		StartedAt: time.Now().UTC(),
+	}
+	defer s.recordFetch(ctx, &fetch)
+
+	body, info, err := s.fetcher.Fetch(ctx, feed.Url)
+	if info.StatusCode != 0 {
+		fetch.StatusCode = sql.NullInt32{Int32: int32(info.StatusCode), Valid: true} //#nosec G115
+	}
+	fetch.Bytes = info.Bytes
 	if err != nil {
+		fetch.Error = sql.NullString{String: err.Error(), Valid: true}
 		log.Printf("Couldn't collect feed %s: %v", feed.Name, err)
 		return
 	}
 
+	feedData, err := s.parser.Parse(body)
+	if err != nil {
+		fetch.Error = sql.NullString{String: fmt.Sprintf("couldn't parse feed: %v", err), Valid: true}
+		log.Printf("Couldn't parse feed %s: %v", feed.Name, err)
+		return
+	}
+	fetch.ItemsSeen = int32(len(feedData.Channel.Item)) //#nosec G115
+
+	language := sql.NullString{}
+	if lang := strings.ToLower(strings.TrimSpace(feedData.Channel.Language)); lang != "" {
+		language = sql.NullString{String: lang, Valid: true}
+	}
+
 	for _, item := range feedData.Channel.Item {
 		publishedAt := sql.NullTime{}
 		if t, err := time.Parse(time.RFC1123Z, item.PubDate); err == nil {
@@ -59,8 +144,37 @@ func ScrapeFeed(db *database.Queries, wg *sync.WaitGroup, feed database.Feed) {
 			}
 		}
 
-		_, err = db.CreatePost(context.Background(), database.CreatePostParams{
-			ID:        uuid.New(),
+		// Links are stored in canonical form so the same article is recognised
+		// across feeds; fall back to the raw link if it can't be resolved.
+		link, err := urlnorm.Resolve(feed.Url, item.Link)
+		if err != nil {
+			link = item.Link
+		}
+
+		postID := uuid.New()
+		fp := fingerprint(item.Title + " " + item.Description)
+
+		clusterID, err := s.findCluster(ctx, link, fp)
+		if err != nil {
+			log.Printf("Couldn't find cluster for post %s: %v", link, err)
+		}
+		if clusterID == uuid.Nil {
+			clusterID = postID
+		}
+
+		author := sql.NullString{}
+		if name := item.AuthorName(); name != "" {
+			author = sql.NullString{String: name, Valid: true}
+		}
+
+		enclosureURL, enclosureType := sql.NullString{}, sql.NullString{}
+		if u := strings.TrimSpace(item.Enclosure.URL); u != "" {
+			enclosureURL = sql.NullString{String: u, Valid: true}
+			enclosureType = sql.NullString{String: item.Enclosure.Type, Valid: item.Enclosure.Type != ""}
+		}
+
+		post, err := s.store.CreatePost(ctx, database.CreatePostParams{
+			ID:        postID,
 			CreatedAt: time.Now().UTC(),
 			UpdatedAt: time.Now().UTC(),
 			FeedID:    feed.ID,
@@ -69,57 +183,89 @@ func ScrapeFeed(db *database.Queries, wg *sync.WaitGroup, feed database.Feed) {
 				String: item.Description,
 				Valid:  true,
 			},
-			Url:         item.Link,
+			Url:         link,
 			PublishedAt: publishedAt,
+			Simhash:     fp,
+			ClusterID:   clusterID,
+			Author:      author,
+			Content: sql.NullString{
+				String: item.Content,
+				Valid:  item.Content != "",
+			},
+			EnclosureUrl:  enclosureURL,
+			EnclosureType: enclosureType,
+			Language:      language,
 		})
 		if err != nil {
-			if strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+			// Posts already stored, or removed by retention, aren't new.
+			if data.IsUniqueViolation(err) || errors.Is(err, sql.ErrNoRows) {
 				continue
 			}
+			if !fetch.Error.Valid {
+				fetch.Error = sql.NullString{String: fmt.Sprintf("couldn't create post: %v", err), Valid: true}
+			}
 			log.Printf("Couldn't create post: %v", err)
 			continue
 		}
+		fetch.ItemsNew++
+
+		for _, hook := range s.hooks {
+			hook(ctx, feed, post)
+		}
 	}
 	log.Printf("Feed %s collected, %v posts found", feed.Name, len(feedData.Channel.Item))
 }
 
-type RSSFeed struct {
-	Channel struct {
-		Title       string    `xml:"title"`
-		Link        string    `xml:"link"`
-		Description string    `xml:"description"`
-		Language    string    `xml:"language"`
-		Item        []RSSItem `xml:"item"`
-	} `xml:"channel"`
-}
-
-type RSSItem struct {
-	Title       string `xml:"title"`
-	Link        string `xml:"link"`
-	Description string `xml:"description"`
-	PubDate     string `xml:"pubDate"`
-}
+// findCluster returns the cluster of an existing post that is a copy of link
+// or a near-duplicate of its fingerprint, or uuid.Nil if the post starts a new
+// cluster. Copies of link are looked for first, as their lookup is cheaper.
+func (s *Scraper) findCluster(ctx context.Context, link string, fp sql.NullInt64) (uuid.UUID, error) {
+	clusterID, err := s.store.FindPostClusterByURL(ctx, link)
+	if err == nil {
+		return clusterID, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, err
+	}
 
-func FetchFeed(feedURL string) (*RSSFeed, error) {
-	httpClient := http.Client{
-		Timeout: 10 * time.Second,
+	// Without a fingerprint only exact copies of the URL can match.
+	if !fp.Valid {
+		return uuid.Nil, nil
 	}
-	resp, err := httpClient.Get(feedURL)
+
+	clusterID, err = s.store.FindPostClusterBySimhash(ctx, database.FindPostClusterBySimhashParams{
+		Simhash:     fp.Int64,
+		Since:       time.Now().UTC().Add(-clusterWindow),
+		MaxDistance: simhash.NearDuplicateDistance,
+	})
 	if err != nil {
-		return nil, err
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, nil
+		}
+		return uuid.Nil, err
 	}
-	defer resp.Body.Close()
 
-	dat, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	return clusterID, nil
+}
+
+func fingerprint(text string) sql.NullInt64 {
+	fp, ok := simhash.Fingerprint(text)
+	if !ok {
+		return sql.NullInt64{}
 	}
 
-	var rssFeed RSSFeed
-	err = xml.Unmarshal(dat, &rssFeed)
+	// Postgres has no unsigned integers, so the fingerprint's bits are stored
+	// as-is in a signed BIGINT.
+	return sql.NullInt64{Int64: int64(fp), Valid: true} //#nosec G115
+}
+
+// recordFetch stores the outcome of a single fetch attempt in the feed's
+// fetch history.
+func (s *Scraper) recordFetch(ctx context.Context, fetch *database.CreateFeedFetchParams) {
+	fetch.FinishedAt = time.Now().UTC()
+
+	_, err := s.store.CreateFeedFetch(ctx, *fetch)
 	if err != nil {
-		return nil, err
+		log.Printf("Couldn't record fetch for feed %s: %v", fetch.FeedID, err)
 	}
-
-	return &rssFeed, nil
 }
@@ -0,0 +1,532 @@
+package scraper
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/simhash"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type postKey struct {
+	feedID uuid.UUID
+	url    string
+}
+
+// memoryStore is an in-memory Store that mimics the constraints of the
+// Postgres schema the scraper relies on.
+type memoryStore struct {
+	mu       sync.Mutex
+	feeds    []database.Feed
+	posts    map[postKey]database.Post
+	fetches  []database.CreateFeedFetchParams
+	pruned   map[postKey]bool
+	failURLs map[string]error
+}
+
+func newMemoryStore(feeds ...database.Feed) *memoryStore {
+	return &memoryStore{
+		feeds:    feeds,
+		posts:    make(map[postKey]database.Post),
+		pruned:   make(map[postKey]bool),
+		failURLs: make(map[string]error),
+	}
+}
+
+func (m *memoryStore) GetNextFeedsToFetch(ctx context.Context, limit int32) ([]database.Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	feeds := append([]database.Feed(nil), m.feeds...)
+	sort.SliceStable(feeds, func(i, j int) bool {
+		if feeds[i].LastFetchedAt.Valid != feeds[j].LastFetchedAt.Valid {
+			return !feeds[i].LastFetchedAt.Valid
+		}
+		return feeds[i].LastFetchedAt.Time.Before(feeds[j].LastFetchedAt.Time)
+	})
+	if int(limit) < len(feeds) {
+		feeds = feeds[:limit]
+	}
+	return feeds, nil
+}
+
+func (m *memoryStore) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.feeds {
+		if m.feeds[i].ID == id {
+			m.feeds[i].LastFetchedAt.Time = time.Now()
+			m.feeds[i].LastFetchedAt.Valid = true
+			return nil
+		}
+	}
+	return errors.New("feed not found")
+}
+
+func (m *memoryStore) CreatePost(ctx context.Context, arg database.CreatePostParams) (database.Post, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err, ok := m.failURLs[arg.Url]; ok {
+		return database.Post{}, err
+	}
+	key := postKey{feedID: arg.FeedID, url: arg.Url}
+	if m.pruned[key] {
+		return database.Post{}, sql.ErrNoRows
+	}
+	if _, exists := m.posts[key]; exists {
+		return database.Post{}, &pq.Error{Code: "23505", Message: `duplicate key value violates unique constraint "posts_feed_id_url_key"`}
+	}
+
+	post := database.Post{
+		ID:            arg.ID,
+		CreatedAt:     arg.CreatedAt,
+		UpdatedAt:     arg.UpdatedAt,
+		Title:         arg.Title,
+		Url:           arg.Url,
+		Description:   arg.Description,
+		PublishedAt:   arg.PublishedAt,
+		FeedID:        arg.FeedID,
+		Simhash:       arg.Simhash,
+		ClusterID:     arg.ClusterID,
+		Author:        arg.Author,
+		Content:       arg.Content,
+		EnclosureUrl:  arg.EnclosureUrl,
+		EnclosureType: arg.EnclosureType,
+		Language:      arg.Language,
+	}
+	m.posts[key] = post
+	return post, nil
+}
+
+func (m *memoryStore) FindPostClusterByURL(ctx context.Context, url string) (uuid.UUID, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.firstCluster(func(post database.Post) bool {
+		return post.Url == url
+	})
+}
+
+func (m *memoryStore) FindPostClusterBySimhash(ctx context.Context, arg database.FindPostClusterBySimhashParams) (uuid.UUID, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.firstCluster(func(post database.Post) bool {
+		return post.Simhash.Valid && post.CreatedAt.After(arg.Since) &&
+			simhash.Distance(uint64(post.Simhash.Int64), uint64(arg.Simhash)) <= int(arg.MaxDistance)
+	})
+}
+
+// firstCluster returns the cluster of the earliest post matching match.
+func (m *memoryStore) firstCluster(match func(post database.Post) bool) (uuid.UUID, error) {
+	var first *database.Post
+	for _, post := range m.posts {
+		if match(post) && (first == nil || post.CreatedAt.Before(first.CreatedAt)) {
+			first = &post
+		}
+	}
+
+	if first == nil {
+		return uuid.Nil, sql.ErrNoRows
+	}
+	return first.ClusterID, nil
+}
+
+func (m *memoryStore) CreateFeedFetch(ctx context.Context, arg database.CreateFeedFetchParams) (database.FeedFetch, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.fetches = append(m.fetches, arg)
+	return database.FeedFetch(arg), nil
+}
+
+func (m *memoryStore) DeleteFeedFetchesBefore(ctx context.Context, startedAt time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var kept []database.CreateFeedFetchParams
+	for _, fetch := range m.fetches {
+		if !fetch.StartedAt.Before(startedAt) {
+			kept = append(kept, fetch)
+		}
+	}
+	pruned := int64(len(m.fetches) - len(kept))
+	m.fetches = kept
+	return pruned, nil
+}
+
+func (m *memoryStore) postByURL(feedID uuid.UUID, url string) (database.Post, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	post, ok := m.posts[postKey{feedID: feedID, url: url}]
+	return post, ok
+}
+
+func (m *memoryStore) lastFetch(t *testing.T) database.CreateFeedFetchParams {
+	t.Helper()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	require.NotEmpty(t, m.fetches, "expected a fetch to be recorded")
+	return m.fetches[len(m.fetches)-1]
+}
+
+func rssDocument(links ...string) string {
+	var items strings.Builder
+	for i, link := range links {
+		fmt.Fprintf(&items, `<item><title>Post %d</title><link>%s</link><description>Body %d</description><pubDate>Mon, 02 Jan 2006 15:04:05 -0700</pubDate></item>`, i+1, link, i+1)
+	}
+	return `<?xml version="1.0"?><rss version="2.0"><channel><title>Test</title>` + items.String() + `</channel></rss>`
+}
+
+func newFeedServer(t *testing.T, status int, body string) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newFeed(url string) database.Feed {
+	return database.Feed{
+		ID:   uuid.New(),
+		Name: "Test Feed",
+		Url:  url,
+	}
+}
+
+func TestScrapeFeed(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		body := rssDocument("https://example.com/a", "https://example.com/b")
+		srv := newFeedServer(t, http.StatusOK, body)
+		feed := newFeed(srv.URL)
+		store := newMemoryStore(feed)
+
+		New(store, NewHTTPFetcher(time.Second, true), RSSParser{}).ScrapeFeed(context.Background(), feed)
+
+		assert.Len(t, store.posts, 2)
+		post, ok := store.postByURL(feed.ID, "https://example.com/a")
+		require.True(t, ok)
+		assert.Equal(t, "Post 1", post.Title)
+		assert.Equal(t, feed.ID, post.FeedID)
+		assert.True(t, post.PublishedAt.Valid)
+
+		fetch := store.lastFetch(t)
+		assert.Equal(t, feed.ID, fetch.FeedID)
+		assert.Equal(t, int32(http.StatusOK), fetch.StatusCode.Int32)
+		assert.Equal(t, int64(len(body)), fetch.Bytes)
+		assert.Equal(t, int32(2), fetch.ItemsSeen)
+		assert.Equal(t, int32(2), fetch.ItemsNew)
+		assert.False(t, fetch.Error.Valid)
+		assert.False(t, fetch.FinishedAt.Before(fetch.StartedAt))
+	})
+
+	t.Run("Hooks see new posts", func(t *testing.T) {
+		srv := newFeedServer(t, http.StatusOK, rssDocument("https://example.com/a", "https://example.com/b"))
+		feed := newFeed(srv.URL)
+		store := newMemoryStore(feed)
+		s := New(store, NewHTTPFetcher(time.Second, true), RSSParser{})
+
+		var seen []string
+		s.AddHook(func(ctx context.Context, hookFeed database.Feed, post database.Post) {
+			assert.Equal(t, feed.ID, hookFeed.ID)
+			seen = append(seen, post.Url)
+		})
+
+		s.ScrapeFeed(context.Background(), feed)
+		s.ScrapeFeed(context.Background(), feed)
+
+		assert.Equal(t, []string{"https://example.com/a", "https://example.com/b"}, seen, "hooks should only run for new posts")
+	})
+
+	t.Run("Authors are stored", func(t *testing.T) {
+		body := `<?xml version="1.0"?><rss version="2.0" xmlns:dc="http://purl.org/dc/elements/1.1/"><channel><title>Test</title>` +
+			`<item><title>One</title><link>https://example.com/one</link><author>jane@example.com (Jane)</author></item>` +
+			`<item><title>Two</title><link>https://example.com/two</link><author>x@example.com</author><dc:creator> Joe Bloggs </dc:creator></item>` +
+			`<item><title>Three</title><link>https://example.com/three</link></item>` +
+			`</channel></rss>`
+		srv := newFeedServer(t, http.StatusOK, body)
+		feed := newFeed(srv.URL)
+		store := newMemoryStore(feed)
+
+		New(store, NewHTTPFetcher(time.Second, true), RSSParser{}).ScrapeFeed(context.Background(), feed)
+
+		post, ok := store.postByURL(feed.ID, "https://example.com/one")
+		require.True(t, ok)
+		assert.Equal(t, "jane@example.com (Jane)", post.Author.String)
+
+		post, ok = store.postByURL(feed.ID, "https://example.com/two")
+		require.True(t, ok)
+		assert.Equal(t, "Joe Bloggs", post.Author.String)
+
+		post, ok = store.postByURL(feed.ID, "https://example.com/three")
+		require.True(t, ok)
+		assert.False(t, post.Author.Valid)
+	})
+
+	t.Run("Content is stored", func(t *testing.T) {
+		body := `<?xml version="1.0"?><rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/"><channel><title>Test</title>` +
+			`<item><title>One</title><link>https://example.com/one</link><content:encoded><![CDATA[<p>Full text</p>]]></content:encoded></item>` +
+			`<item><title>Two</title><link>https://example.com/two</link></item>` +
+			`</channel></rss>`
+		srv := newFeedServer(t, http.StatusOK, body)
+		feed := newFeed(srv.URL)
+		store := newMemoryStore(feed)
+
+		New(store, NewHTTPFetcher(time.Second, true), RSSParser{}).ScrapeFeed(context.Background(), feed)
+
+		post, ok := store.postByURL(feed.ID, "https://example.com/one")
+		require.True(t, ok)
+		assert.Equal(t, "<p>Full text</p>", post.Content.String)
+
+		post, ok = store.postByURL(feed.ID, "https://example.com/two")
+		require.True(t, ok)
+		assert.False(t, post.Content.Valid)
+	})
+
+	t.Run("Enclosures and language are stored", func(t *testing.T) {
+		body := `<?xml version="1.0"?><rss version="2.0"><channel><title>Test</title><language>en-GB</language>` +
+			`<item><title>Episode</title><link>https://example.com/episode</link><enclosure url="https://example.com/episode.mp3" type="audio/mpeg" length="1024"/></item>` +
+			`<item><title>Article</title><link>https://example.com/article</link></item>` +
+			`</channel></rss>`
+		srv := newFeedServer(t, http.StatusOK, body)
+		feed := newFeed(srv.URL)
+		store := newMemoryStore(feed)
+
+		New(store, NewHTTPFetcher(time.Second, true), RSSParser{}).ScrapeFeed(context.Background(), feed)
+
+		post, ok := store.postByURL(feed.ID, "https://example.com/episode")
+		require.True(t, ok)
+		assert.Equal(t, "https://example.com/episode.mp3", post.EnclosureUrl.String)
+		assert.Equal(t, "audio/mpeg", post.EnclosureType.String)
+		assert.Equal(t, "en-gb", post.Language.String)
+
+		post, ok = store.postByURL(feed.ID, "https://example.com/article")
+		require.True(t, ok)
+		assert.False(t, post.EnclosureUrl.Valid)
+		assert.Equal(t, "en-gb", post.Language.String)
+	})
+
+	t.Run("Duplicates are skipped", func(t *testing.T) {
+		srv := newFeedServer(t, http.StatusOK, rssDocument("https://example.com/a", "https://example.com/a", "https://example.com/b"))
+		feed := newFeed(srv.URL)
+		store := newMemoryStore(feed)
+		s := New(store, NewHTTPFetcher(time.Second, true), RSSParser{})
+
+		s.ScrapeFeed(context.Background(), feed)
+
+		fetch := store.lastFetch(t)
+		assert.Equal(t, int32(3), fetch.ItemsSeen)
+		assert.Equal(t, int32(2), fetch.ItemsNew)
+		assert.False(t, fetch.Error.Valid, "duplicates should not be reported as errors")
+
+		s.ScrapeFeed(context.Background(), feed)
+
+		assert.Len(t, store.posts, 2)
+		fetch = store.lastFetch(t)
+		assert.Equal(t, int32(3), fetch.ItemsSeen)
+		assert.Equal(t, int32(0), fetch.ItemsNew)
+		assert.False(t, fetch.Error.Valid)
+	})
+
+	t.Run("Pruned posts are not collected again", func(t *testing.T) {
+		srv := newFeedServer(t, http.StatusOK, rssDocument("https://example.com/a", "https://example.com/b"))
+		feed := newFeed(srv.URL)
+		store := newMemoryStore(feed)
+		store.pruned[postKey{feedID: feed.ID, url: "https://example.com/a"}] = true
+
+		New(store, NewHTTPFetcher(time.Second, true), RSSParser{}).ScrapeFeed(context.Background(), feed)
+
+		assert.Len(t, store.posts, 1)
+		_, ok := store.postByURL(feed.ID, "https://example.com/a")
+		assert.False(t, ok)
+
+		fetch := store.lastFetch(t)
+		assert.Equal(t, int32(2), fetch.ItemsSeen)
+		assert.Equal(t, int32(1), fetch.ItemsNew)
+		assert.False(t, fetch.Error.Valid)
+	})
+
+	t.Run("Links are canonicalised", func(t *testing.T) {
+		srv := newFeedServer(t, http.StatusOK, rssDocument("HTTPS://Example.com/a/?utm_source=rss", "https://example.com/a", "/b#comments"))
+		feed := newFeed(srv.URL + "/feed.xml")
+		store := newMemoryStore(feed)
+
+		New(store, NewHTTPFetcher(time.Second, true), RSSParser{}).ScrapeFeed(context.Background(), feed)
+
+		assert.Len(t, store.posts, 2)
+		_, ok := store.postByURL(feed.ID, "https://example.com/a")
+		assert.True(t, ok, "tracking parameters and trailing slashes should be removed")
+		_, ok = store.postByURL(feed.ID, srv.URL+"/b")
+		assert.True(t, ok, "relative links should be resolved against the feed url")
+
+		fetch := store.lastFetch(t)
+		assert.Equal(t, int32(3), fetch.ItemsSeen)
+		assert.Equal(t, int32(2), fetch.ItemsNew)
+	})
+
+	t.Run("Same link in another feed", func(t *testing.T) {
+		srv := newFeedServer(t, http.StatusOK, rssDocument("https://example.com/a"))
+		feedA := newFeed(srv.URL)
+		feedB := newFeed(srv.URL + "/other")
+		store := newMemoryStore(feedA, feedB)
+		s := New(store, NewHTTPFetcher(time.Second, true), RSSParser{})
+
+		s.ScrapeFeed(context.Background(), feedA)
+		s.ScrapeFeed(context.Background(), feedB)
+
+		_, ok := store.postByURL(feedA.ID, "https://example.com/a")
+		assert.True(t, ok)
+		postB, ok := store.postByURL(feedB.ID, "https://example.com/a")
+		assert.True(t, ok, "uniqueness is per feed, so syndicated posts reach every feed")
+
+		postA, _ := store.postByURL(feedA.ID, "https://example.com/a")
+		assert.Equal(t, postA.ID, postA.ClusterID, "the first copy starts a new cluster")
+		assert.Equal(t, postA.ClusterID, postB.ClusterID, "copies of the same url share a cluster")
+	})
+
+	t.Run("Near-duplicates are clustered", func(t *testing.T) {
+		const (
+			story    = "The Federal Reserve on Wednesday raised its benchmark rate by 0.25 percentage points, the tenth increase since March last year, as officials continued their fight against stubborn inflation."
+			reworded = "The Federal Reserve on Wednesday raised its benchmark rate by 0.25 percentage points, the tenth increase since March of last year, as officials continued their fight against stubborn inflation."
+			other    = "Apple on Monday announced a refreshed MacBook Pro lineup powered by its new M3 family of processors."
+		)
+		item := func(title, link, description string) string {
+			return fmt.Sprintf(`<item><title>%s</title><link>%s</link><description>%s</description></item>`, title, link, description)
+		}
+
+		srvA := newFeedServer(t, http.StatusOK, `<rss><channel>`+item("Fed raises interest rates", "https://news.example.com/fed", story)+`</channel></rss>`)
+		srvB := newFeedServer(t, http.StatusOK, `<rss><channel>`+
+			item("BREAKING: Fed raises interest rates", "https://wire.example.com/fed-hike", reworded)+
+			item("New MacBook Pro", "https://wire.example.com/macbook", other)+
+			`</channel></rss>`)
+		feedA, feedB := newFeed(srvA.URL), newFeed(srvB.URL)
+		store := newMemoryStore(feedA, feedB)
+		s := New(store, NewHTTPFetcher(time.Second, true), RSSParser{})
+
+		s.ScrapeFeed(context.Background(), feedA)
+		s.ScrapeFeed(context.Background(), feedB)
+
+		original, _ := store.postByURL(feedA.ID, "https://news.example.com/fed")
+		copied, _ := store.postByURL(feedB.ID, "https://wire.example.com/fed-hike")
+		unrelated, _ := store.postByURL(feedB.ID, "https://wire.example.com/macbook")
+
+		assert.True(t, original.Simhash.Valid)
+		assert.Equal(t, original.ClusterID, copied.ClusterID)
+		assert.Equal(t, unrelated.ID, unrelated.ClusterID)
+	})
+
+	t.Run("Partial failure", func(t *testing.T) {
+		srv := newFeedServer(t, http.StatusOK, rssDocument("https://example.com/a", "https://example.com/b", "https://example.com/c"))
+		feed := newFeed(srv.URL)
+		store := newMemoryStore(feed)
+		store.failURLs["https://example.com/b"] = errors.New("connection reset")
+
+		New(store, NewHTTPFetcher(time.Second, true), RSSParser{}).ScrapeFeed(context.Background(), feed)
+
+		assert.Len(t, store.posts, 2)
+		_, ok := store.postByURL(feed.ID, "https://example.com/c")
+		assert.True(t, ok, "a failed item should not stop the rest of the feed")
+
+		fetch := store.lastFetch(t)
+		assert.Equal(t, int32(3), fetch.ItemsSeen)
+		assert.Equal(t, int32(2), fetch.ItemsNew)
+		assert.True(t, fetch.Error.Valid)
+		assert.Contains(t, fetch.Error.String, "connection reset")
+	})
+
+	t.Run("HTTP error", func(t *testing.T) {
+		srv := newFeedServer(t, http.StatusBadGateway, "bad gateway")
+		feed := newFeed(srv.URL)
+		store := newMemoryStore(feed)
+
+		New(store, NewHTTPFetcher(time.Second, true), RSSParser{}).ScrapeFeed(context.Background(), feed)
+
+		assert.Empty(t, store.posts)
+
+		fetch := store.lastFetch(t)
+		assert.Equal(t, int32(http.StatusBadGateway), fetch.StatusCode.Int32)
+		assert.Equal(t, int64(len("bad gateway")), fetch.Bytes)
+		assert.True(t, fetch.Error.Valid)
+		assert.Contains(t, fetch.Error.String, "502")
+	})
+
+	t.Run("Unreachable feed", func(t *testing.T) {
+		srv := newFeedServer(t, http.StatusOK, "")
+		feed := newFeed(srv.URL)
+		srv.Close()
+		store := newMemoryStore(feed)
+
+		New(store, NewHTTPFetcher(time.Second, true), RSSParser{}).ScrapeFeed(context.Background(), feed)
+
+		fetch := store.lastFetch(t)
+		assert.False(t, fetch.StatusCode.Valid)
+		assert.Equal(t, "request failed", fetch.Error.String)
+	})
+
+	t.Run("Private address", func(t *testing.T) {
+		srv := newFeedServer(t, http.StatusOK, rssDocument("https://example.com/a"))
+		feed := newFeed(srv.URL)
+		store := newMemoryStore(feed)
+
+		New(store, NewHTTPFetcher(time.Second, false), RSSParser{}).ScrapeFeed(context.Background(), feed)
+
+		assert.Empty(t, store.posts)
+
+		fetch := store.lastFetch(t)
+		assert.False(t, fetch.StatusCode.Valid)
+		assert.Equal(t, "address not allowed", fetch.Error.String)
+	})
+
+	t.Run("Malformed document", func(t *testing.T) {
+		srv := newFeedServer(t, http.StatusOK, "<rss><channel><item>")
+		feed := newFeed(srv.URL)
+		store := newMemoryStore(feed)
+
+		New(store, NewHTTPFetcher(time.Second, true), RSSParser{}).ScrapeFeed(context.Background(), feed)
+
+		assert.Empty(t, store.posts)
+
+		fetch := store.lastFetch(t)
+		assert.Equal(t, int32(http.StatusOK), fetch.StatusCode.Int32)
+		assert.True(t, fetch.Error.Valid)
+		assert.Contains(t, fetch.Error.String, "couldn't parse feed")
+	})
+}
+
+func TestScrapeOnce(t *testing.T) {
+	srvA := newFeedServer(t, http.StatusOK, rssDocument("https://example.com/a1", "https://example.com/a2"))
+	srvB := newFeedServer(t, http.StatusOK, rssDocument("https://example.com/b1"))
+	srvC := newFeedServer(t, http.StatusInternalServerError, "")
+
+	store := newMemoryStore(newFeed(srvA.URL), newFeed(srvB.URL), newFeed(srvC.URL))
+
+	New(store, NewHTTPFetcher(time.Second, true), RSSParser{}).ScrapeOnce(context.Background(), 10)
+
+	assert.Len(t, store.posts, 3)
+	assert.Len(t, store.fetches, 3)
+	for _, feed := range store.feeds {
+		assert.True(t, feed.LastFetchedAt.Valid, "every due feed should be marked fetched")
+	}
+}
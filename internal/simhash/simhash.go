@@ -0,0 +1,74 @@
+package simhash
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// MinTokens is the smallest number of words Fingerprint will fingerprint;
+// anything shorter produces fingerprints too unstable to compare.
+const MinTokens = 4
+
+// NearDuplicateDistance is the largest Distance at which two fingerprints are
+// considered the same story. Unrelated texts average a distance of 32. It
+// must stay below 8, the number of bands posts are indexed by in
+// simhash_bands, for the index to find every near-duplicate.
+const NearDuplicateDistance = 7
+
+var tagRX = regexp.MustCompile(`<[^>]*>`)
+
+// Fingerprint computes a 64-bit SimHash over the words and word pairs of text,
+// ignoring case, punctuation and HTML tags. Texts that differ only slightly
+// produce fingerprints a small Hamming distance apart. ok is false when text
+// has fewer than MinTokens words.
+func Fingerprint(text string) (fingerprint uint64, ok bool) {
+	tokens := tokenize(text)
+	if len(tokens) < MinTokens {
+		return 0, false
+	}
+
+	var weights [64]int
+	add := func(feature string) {
+		h := fnv.New64a()
+		h.Write([]byte(feature)) //#nosec G104
+		sum := h.Sum64()
+		for i := 0; i < 64; i++ {
+			if sum&(1<<uint(i)) != 0 {
+				weights[i]++
+			} else {
+				weights[i]--
+			}
+		}
+	}
+
+	for i, token := range tokens {
+		add(token)
+		if i > 0 {
+			add(tokens[i-1] + " " + token)
+		}
+	}
+
+	for i := 0; i < 64; i++ {
+		if weights[i] > 0 {
+			fingerprint |= 1 << uint(i)
+		}
+	}
+
+	return fingerprint, true
+}
+
+// Distance returns the number of differing bits between two fingerprints.
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+func tokenize(text string) []string {
+	text = strings.ToLower(tagRX.ReplaceAllString(text, " "))
+
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
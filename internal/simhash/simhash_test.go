@@ -0,0 +1,70 @@
+package simhash
+
+import (
+	"testing"
+)
+
+const story = "Fed raises interest rates by a quarter point to fight inflation. The Federal Reserve on Wednesday raised its benchmark rate by 0.25 percentage points, the tenth increase since March last year, as officials continued their fight against stubborn inflation."
+
+func TestFingerprint(t *testing.T) {
+	tests := map[string]struct {
+		a, b          string
+		nearDuplicate bool
+	}{
+		"Identical text": {
+			a:             story,
+			b:             story,
+			nearDuplicate: true,
+		},
+		"Case, punctuation and markup": {
+			a:             story,
+			b:             "<p>FED RAISES INTEREST RATES BY A QUARTER POINT TO FIGHT INFLATION</p> <p>The Federal Reserve, on Wednesday, raised its benchmark rate by 0.25 percentage points; the tenth increase since March last year, as officials continued their fight against stubborn inflation!</p>",
+			nearDuplicate: true,
+		},
+		"Reworded headline": {
+			a:             story,
+			b:             "Fed raises rates by quarter point to fight inflation. The Federal Reserve on Wednesday raised its benchmark rate by 0.25 percentage points, the tenth increase since March last year, as officials continued their fight against stubborn inflation.",
+			nearDuplicate: true,
+		},
+		"Republished with prefix and credit": {
+			a:             story,
+			b:             "BREAKING: Fed raises interest rates by a quarter point to fight inflation - The Federal Reserve on Wednesday raised its benchmark rate by 0.25 percentage points, the tenth increase since March last year, as officials continued their fight against stubborn inflation. (Reuters)",
+			nearDuplicate: true,
+		},
+		"Different story": {
+			a:             story,
+			b:             "Apple unveils new MacBook Pro with M3 chip. Apple on Monday announced a refreshed MacBook Pro lineup powered by its new M3 family of processors, promising faster graphics and longer battery life.",
+			nearDuplicate: false,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			a, ok := Fingerprint(tc.a)
+			if !ok {
+				t.Fatal("expected a fingerprint for a")
+			}
+			b, ok := Fingerprint(tc.b)
+			if !ok {
+				t.Fatal("expected a fingerprint for b")
+			}
+
+			distance := Distance(a, b)
+			if got := distance <= NearDuplicateDistance; got != tc.nearDuplicate {
+				t.Errorf("distance %d: near duplicate = %v, want %v", distance, got, tc.nearDuplicate)
+			}
+		})
+	}
+}
+
+func TestFingerprintShortText(t *testing.T) {
+	if _, ok := Fingerprint("Hello, world"); ok {
+		t.Error("expected no fingerprint for text shorter than MinTokens words")
+	}
+}
+
+func TestDistance(t *testing.T) {
+	if got := Distance(0b1011, 0b0110); got != 3 {
+		t.Errorf("Distance = %d, want 3", got)
+	}
+}
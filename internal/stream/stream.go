@@ -0,0 +1,209 @@
+package stream
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	// PostsChannel is the Postgres channel new posts are announced on. It
+	// must match the channel NotifyPostCreated notifies.
+	PostsChannel = "new_posts"
+
+	// StatesChannel is the Postgres channel a trigger on post_states
+	// announces changes on, with the ID of the user whose states changed.
+	StatesChannel = "post_states"
+)
+
+// Notifier is the subset of database.Queries the publisher needs.
+type Notifier interface {
+	NotifyPostCreated(ctx context.Context) error
+}
+
+// Publisher announces the posts the scraper stores to every instance
+// listening on PostsChannel.
+type Publisher struct {
+	store Notifier
+}
+
+func NewPublisher(store Notifier) *Publisher {
+	return &Publisher{store: store}
+}
+
+// PostCreated is a scraper hook. It should be added after any hooks that
+// change the post, such as rules, so streams see the post as they left it.
+func (p *Publisher) PostCreated(ctx context.Context, feed database.Feed, post database.Post) {
+	err := p.store.NotifyPostCreated(ctx)
+	if err != nil {
+		log.Println("Couldn't announce new post", post.ID, err)
+	}
+}
+
+// Listen opens a connection listening on PostsChannel and StatesChannel. The
+// listener reconnects by itself whenever the connection is lost.
+func Listen(dbURL string) (*pq.Listener, error) {
+	listener := pq.NewListener(dbURL, 10*time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Println("Post stream listener:", err)
+		}
+	})
+
+	for _, channel := range []string{PostsChannel, StatesChannel} {
+		err := listener.Listen(channel)
+		if err != nil {
+			listener.Close() //#nosec G104
+			return nil, err
+		}
+	}
+
+	return listener, nil
+}
+
+// Broker fans notifications out to every open stream on this instance.
+// Notifications carry no posts: they only tell subscribers there may be
+// something new to read, so subscribers that are busy miss nothing by
+// skipping some.
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[*Subscription]struct{}
+	closed      bool
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscription receives on C when new posts may be available, and on States
+// when its user's post states may have changed. Both are closed when the
+// broker is.
+type Subscription struct {
+	C      <-chan struct{}
+	States <-chan struct{}
+	c      chan struct{}
+	states chan struct{}
+	userID uuid.UUID
+	broker *Broker
+}
+
+// Subscribe adds a subscriber for a user, which must be closed once it's no
+// longer read.
+func (b *Broker) Subscribe(userID uuid.UUID) *Subscription {
+	c := make(chan struct{}, 1)
+	states := make(chan struct{}, 1)
+	s := &Subscription{C: c, States: states, c: c, states: states, userID: userID, broker: b}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		s.close()
+		return s
+	}
+	b.subscribers[s] = struct{}{}
+
+	return s
+}
+
+// Close removes the subscription from its broker.
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+
+	if _, ok := s.broker.subscribers[s]; ok {
+		delete(s.broker.subscribers, s)
+		s.close()
+	}
+}
+
+func (s *Subscription) close() {
+	close(s.c)
+	close(s.states)
+}
+
+// wake signals c unless an earlier signal is still unread, so a burst of
+// notifications wakes a subscriber only once.
+func wake(c chan struct{}) {
+	select {
+	case c <- struct{}{}:
+	default:
+	}
+}
+
+// Publish tells every subscriber there may be new posts.
+func (b *Broker) Publish() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for s := range b.subscribers {
+		wake(s.c)
+	}
+}
+
+// PublishStates tells a user's subscribers their post states may have
+// changed.
+func (b *Broker) PublishStates(userID uuid.UUID) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for s := range b.subscribers {
+		if s.userID == userID {
+			wake(s.states)
+		}
+	}
+}
+
+// Run publishes every notification received until notifications is closed.
+// pq sends a nil notification after reconnecting, when anything may have
+// been announced while the listener was away, so then every subscriber is
+// told about everything.
+func (b *Broker) Run(notifications <-chan *pq.Notification) {
+	for n := range notifications {
+		switch {
+		case n == nil:
+			b.mu.Lock()
+			for s := range b.subscribers {
+				wake(s.c)
+				wake(s.states)
+			}
+			b.mu.Unlock()
+		case n.Channel == PostsChannel:
+			b.Publish()
+		case n.Channel == StatesChannel:
+			userID, err := uuid.Parse(n.Extra)
+			if err != nil {
+				log.Println("Ignoring post state notification for", n.Extra)
+				continue
+			}
+			b.PublishStates(userID)
+		}
+	}
+}
+
+// Subscribers returns the number of open subscriptions.
+func (b *Broker) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.subscribers)
+}
+
+// Close ends every subscription, and any made later, so streams can finish
+// when the server shuts down.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for s := range b.subscribers {
+		delete(b.subscribers, s)
+		s.close()
+	}
+}
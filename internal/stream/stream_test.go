@@ -0,0 +1,168 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeNotifier struct {
+	calls int
+	err   error
+}
+
+func (f *fakeNotifier) NotifyPostCreated(ctx context.Context) error {
+	f.calls++
+	return f.err
+}
+
+func received(c <-chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestPublisher(t *testing.T) {
+	t.Run("Announces every post", func(t *testing.T) {
+		store := &fakeNotifier{}
+		p := NewPublisher(store)
+
+		p.PostCreated(context.Background(), database.Feed{}, database.Post{})
+		p.PostCreated(context.Background(), database.Feed{}, database.Post{})
+
+		assert.Equal(t, 2, store.calls)
+	})
+
+	t.Run("Errors are only logged", func(t *testing.T) {
+		store := &fakeNotifier{err: errors.New("connection refused")}
+		p := NewPublisher(store)
+
+		assert.NotPanics(t, func() {
+			p.PostCreated(context.Background(), database.Feed{}, database.Post{})
+		})
+	})
+}
+
+func TestBroker(t *testing.T) {
+	t.Run("Wakes every subscriber", func(t *testing.T) {
+		b := NewBroker()
+		first := b.Subscribe(uuid.New())
+		defer first.Close()
+		second := b.Subscribe(uuid.New())
+		defer second.Close()
+
+		b.Publish()
+
+		assert.True(t, received(first.C))
+		assert.True(t, received(second.C))
+	})
+
+	t.Run("Bursts wake a subscriber once", func(t *testing.T) {
+		b := NewBroker()
+		s := b.Subscribe(uuid.New())
+		defer s.Close()
+
+		b.Publish()
+		b.Publish()
+		b.Publish()
+
+		assert.True(t, received(s.C))
+		assert.False(t, received(s.C))
+	})
+
+	t.Run("State changes only wake their user", func(t *testing.T) {
+		b := NewBroker()
+		userID := uuid.New()
+		first := b.Subscribe(userID)
+		defer first.Close()
+		second := b.Subscribe(userID)
+		defer second.Close()
+		other := b.Subscribe(uuid.New())
+		defer other.Close()
+
+		b.PublishStates(userID)
+
+		assert.True(t, received(first.States))
+		assert.True(t, received(second.States))
+		assert.False(t, received(other.States))
+		assert.False(t, received(first.C))
+	})
+
+	t.Run("Closed subscriptions are removed", func(t *testing.T) {
+		b := NewBroker()
+		s := b.Subscribe(uuid.New())
+		require.Equal(t, 1, b.Subscribers())
+
+		s.Close()
+		s.Close()
+
+		assert.Equal(t, 0, b.Subscribers())
+		assert.NotPanics(t, b.Publish)
+	})
+
+	t.Run("Closing the broker ends subscriptions", func(t *testing.T) {
+		b := NewBroker()
+		s := b.Subscribe(uuid.New())
+
+		b.Close()
+		_, ok := <-s.C
+		assert.False(t, ok)
+		assert.NotPanics(t, s.Close)
+
+		late := b.Subscribe(uuid.New())
+		_, ok = <-late.C
+		assert.False(t, ok)
+		assert.Equal(t, 0, b.Subscribers())
+	})
+
+	t.Run("Run publishes notifications and reconnects", func(t *testing.T) {
+		b := NewBroker()
+		userID := uuid.New()
+		s := b.Subscribe(userID)
+		defer s.Close()
+
+		notifications := make(chan *pq.Notification)
+		done := make(chan struct{})
+		go func() {
+			b.Run(notifications)
+			close(done)
+		}()
+
+		notifications <- &pq.Notification{Channel: PostsChannel}
+		select {
+		case <-s.C:
+		case <-time.After(time.Second):
+			t.Fatal("subscriber wasn't woken by a notification")
+		}
+
+		notifications <- nil
+		for _, c := range []<-chan struct{}{s.C, s.States} {
+			select {
+			case <-c:
+			case <-time.After(time.Second):
+				t.Fatal("subscriber wasn't woken after reconnecting")
+			}
+		}
+
+		notifications <- &pq.Notification{Channel: StatesChannel, Extra: "not-a-user"}
+		notifications <- &pq.Notification{Channel: StatesChannel, Extra: userID.String()}
+		select {
+		case <-s.States:
+		case <-time.After(time.Second):
+			t.Fatal("subscriber wasn't woken by a state change")
+		}
+
+		close(notifications)
+		<-done
+	})
+}
@@ -0,0 +1,308 @@
+// Package syndication renders lists of posts as RSS 2.0, Atom and JSON Feed
+// documents.
+package syndication
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"time"
+)
+
+// Formats feeds can be rendered in, named by their file extensions.
+const (
+	FormatRSS  = "rss"
+	FormatAtom = "atom"
+	FormatJSON = "json"
+)
+
+var Formats = []string{FormatRSS, FormatAtom, FormatJSON}
+
+// ContentTypes are the media types of each format.
+var ContentTypes = map[string]string{
+	FormatRSS:  "application/rss+xml; charset=utf-8",
+	FormatAtom: "application/atom+xml; charset=utf-8",
+	FormatJSON: "application/feed+json; charset=utf-8",
+}
+
+// Feed is a list of posts to render. ID is a URI identifying the feed for as
+// long as it exists, and SelfURL is the address it's rendered at.
+type Feed struct {
+	ID          string
+	Title       string
+	Description string
+	SelfURL     string
+	Updated     time.Time
+	Items       []Item
+}
+
+// Item is a post in a feed. Source is the name of the feed it was collected
+// from, and SourceURL that feed's address.
+type Item struct {
+	ID            string
+	Title         string
+	Url           string
+	Summary       string
+	Author        string
+	Source        string
+	SourceURL     string
+	Published     time.Time
+	Updated       time.Time
+	EnclosureUrl  string
+	EnclosureType string
+}
+
+// Render returns the feed as a document in format.
+func Render(format string, feed Feed) ([]byte, error) {
+	switch format {
+	case FormatRSS:
+		return renderXML(rss(feed))
+	case FormatAtom:
+		return renderXML(atom(feed))
+	case FormatJSON:
+		return json.MarshalIndent(jsonFeed(feed), "", "  ")
+	default:
+		return nil, fmt.Errorf("unknown feed format %q", format)
+	}
+}
+
+// enclosureType returns the media type of an item's enclosure, which every
+// format requires, even when the post didn't give one.
+func enclosureType(item Item) string {
+	if item.EnclosureType == "" {
+		return "application/octet-stream"
+	}
+	return item.EnclosureType
+}
+
+func renderXML(v any) ([]byte, error) {
+	body, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	DCNS    string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	AtomLink      atomLink  `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link"`
+	GUID        rssGUID       `xml:"guid"`
+	Description string        `xml:"description,omitempty"`
+	Creator     string        `xml:"dc:creator,omitempty"`
+	PubDate     string        `xml:"pubDate"`
+	Source      *rssSource    `xml:"source,omitempty"`
+	Enclosure   *rssEnclosure `xml:"enclosure,omitempty"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssSource struct {
+	Url   string `xml:"url,attr"`
+	Value string `xml:",chardata"`
+}
+
+// rssEnclosure leaves the length at 0, as RSS advises when it's unknown.
+type rssEnclosure struct {
+	Url    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length int    `xml:"length,attr"`
+}
+
+func rss(feed Feed) rssDocument {
+	doc := rssDocument{
+		Version: "2.0",
+		AtomNS:  "http://www.w3.org/2005/Atom",
+		DCNS:    "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:         feed.Title,
+			Link:          feed.SelfURL,
+			Description:   feed.Description,
+			AtomLink:      atomLink{Href: feed.SelfURL, Rel: "self", Type: "application/rss+xml"},
+			LastBuildDate: feed.Updated.UTC().Format(time.RFC1123Z),
+		},
+	}
+
+	for _, item := range feed.Items {
+		entry := rssItem{
+			Title:       item.Title,
+			Link:        item.Url,
+			GUID:        rssGUID{Value: item.ID},
+			Description: item.Summary,
+			Creator:     item.Author,
+			PubDate:     item.Published.UTC().Format(time.RFC1123Z),
+		}
+		if item.Source != "" && item.SourceURL != "" {
+			entry.Source = &rssSource{Url: item.SourceURL, Value: item.Source}
+		}
+		if item.EnclosureUrl != "" {
+			entry.Enclosure = &rssEnclosure{Url: item.EnclosureUrl, Type: enclosureType(item)}
+		}
+		doc.Channel.Items = append(doc.Channel.Items, entry)
+	}
+
+	return doc
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Author  atomPerson  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Links     []atomLink  `xml:"link"`
+	Published string      `xml:"published"`
+	Updated   string      `xml:"updated"`
+	Author    *atomPerson `xml:"author,omitempty"`
+	Summary   *atomText   `xml:"summary,omitempty"`
+	Source    *atomSource `xml:"source,omitempty"`
+}
+
+type atomSource struct {
+	Title string    `xml:"title"`
+	Link  *atomLink `xml:"link,omitempty"`
+}
+
+func atom(feed Feed) atomFeed {
+	doc := atomFeed{
+		ID:      feed.ID,
+		Title:   feed.Title,
+		Updated: feed.Updated.UTC().Format(time.RFC3339),
+		Link:    atomLink{Href: feed.SelfURL, Rel: "self", Type: "application/atom+xml"},
+		// Entries without an author of their own are credited to the feed's.
+		Author: atomPerson{Name: feed.Title},
+	}
+
+	for _, item := range feed.Items {
+		entry := atomEntry{
+			ID:        item.ID,
+			Title:     item.Title,
+			Links:     []atomLink{{Href: item.Url, Rel: "alternate"}},
+			Published: item.Published.UTC().Format(time.RFC3339),
+			Updated:   item.Updated.UTC().Format(time.RFC3339),
+		}
+		if item.Author != "" {
+			entry.Author = &atomPerson{Name: item.Author}
+		}
+		if item.Summary != "" {
+			entry.Summary = &atomText{Type: "html", Value: item.Summary}
+		}
+		if item.Source != "" {
+			entry.Source = &atomSource{Title: item.Source}
+			if item.SourceURL != "" {
+				entry.Source.Link = &atomLink{Href: item.SourceURL}
+			}
+		}
+		if item.EnclosureUrl != "" {
+			entry.Links = append(entry.Links, atomLink{Href: item.EnclosureUrl, Rel: "enclosure", Type: enclosureType(item)})
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+
+	return doc
+}
+
+type jsonFeedDocument struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	Description string         `json:"description,omitempty"`
+	FeedURL     string         `json:"feed_url"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            string               `json:"id"`
+	Url           string               `json:"url"`
+	Title         string               `json:"title"`
+	ContentHTML   string               `json:"content_html"`
+	DatePublished time.Time            `json:"date_published"`
+	DateModified  time.Time            `json:"date_modified"`
+	Authors       []jsonFeedAuthor     `json:"authors,omitempty"`
+	Attachments   []jsonFeedAttachment `json:"attachments,omitempty"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+	Url  string `json:"url,omitempty"`
+}
+
+type jsonFeedAttachment struct {
+	Url      string `json:"url"`
+	MimeType string `json:"mime_type"`
+}
+
+func jsonFeed(feed Feed) jsonFeedDocument {
+	doc := jsonFeedDocument{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       feed.Title,
+		Description: feed.Description,
+		FeedURL:     feed.SelfURL,
+		Items:       []jsonFeedItem{},
+	}
+
+	for _, item := range feed.Items {
+		entry := jsonFeedItem{
+			ID:            item.ID,
+			Url:           item.Url,
+			Title:         item.Title,
+			ContentHTML:   item.Summary,
+			DatePublished: item.Published.UTC(),
+			DateModified:  item.Updated.UTC(),
+		}
+		// JSON Feed has no field for the source, so posts without an author
+		// are credited to their feed.
+		switch {
+		case item.Author != "":
+			entry.Authors = []jsonFeedAuthor{{Name: item.Author}}
+		case item.Source != "":
+			entry.Authors = []jsonFeedAuthor{{Name: item.Source, Url: item.SourceURL}}
+		}
+		if item.EnclosureUrl != "" {
+			entry.Attachments = []jsonFeedAttachment{{Url: item.EnclosureUrl, MimeType: enclosureType(item)}}
+		}
+		doc.Items = append(doc.Items, entry)
+	}
+
+	return doc
+}
@@ -0,0 +1,183 @@
+package syndication
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	published = time.Date(2024, 3, 20, 9, 0, 0, 0, time.UTC)
+	updated   = time.Date(2024, 3, 21, 9, 0, 0, 0, time.UTC)
+)
+
+func testFeed() Feed {
+	return Feed{
+		ID:          "urn:uuid:feed",
+		Title:       "Ada's <river>",
+		Description: "Everything Ada follows",
+		SelfURL:     "https://api.example.com/v1/out/token.rss",
+		Updated:     updated,
+		Items: []Item{
+			{
+				ID:            "urn:uuid:one",
+				Title:         "Tuning Postgres & friends",
+				Url:           "https://example.com/one",
+				Summary:       "<p>Indexes</p>",
+				Author:        "Grace",
+				Source:        "Example",
+				SourceURL:     "https://example.com/rss",
+				Published:     published,
+				Updated:       updated,
+				EnclosureUrl:  "https://example.com/one.mp3",
+				EnclosureType: "audio/mpeg",
+			},
+			{
+				ID:        "urn:uuid:two",
+				Title:     "Two",
+				Url:       "https://example.com/two",
+				Source:    "Example",
+				Published: published,
+				Updated:   published,
+			},
+		},
+	}
+}
+
+func TestRenderRSS(t *testing.T) {
+	body, err := Render(FormatRSS, testFeed())
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(body), xml.Header))
+
+	var doc struct {
+		Version string `xml:"version,attr"`
+		Channel struct {
+			Title         string `xml:"title"`
+			LastBuildDate string `xml:"lastBuildDate"`
+			Items         []struct {
+				Title       string `xml:"title"`
+				Link        string `xml:"link"`
+				GUID        string `xml:"guid"`
+				Description string `xml:"description"`
+				Creator     string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+				PubDate     string `xml:"pubDate"`
+				Source      string `xml:"source"`
+				Enclosure   struct {
+					Url  string `xml:"url,attr"`
+					Type string `xml:"type,attr"`
+				} `xml:"enclosure"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	require.NoError(t, xml.Unmarshal(body, &doc))
+
+	assert.Equal(t, "2.0", doc.Version)
+	assert.Equal(t, "Ada's <river>", doc.Channel.Title)
+	assert.Equal(t, "Thu, 21 Mar 2024 09:00:00 +0000", doc.Channel.LastBuildDate)
+	require.Len(t, doc.Channel.Items, 2)
+
+	item := doc.Channel.Items[0]
+	assert.Equal(t, "Tuning Postgres & friends", item.Title)
+	assert.Equal(t, "https://example.com/one", item.Link)
+	assert.Equal(t, "urn:uuid:one", item.GUID)
+	assert.Equal(t, "<p>Indexes</p>", item.Description)
+	assert.Equal(t, "Grace", item.Creator)
+	assert.Equal(t, "Wed, 20 Mar 2024 09:00:00 +0000", item.PubDate)
+	assert.Equal(t, "Example", item.Source)
+	assert.Equal(t, "https://example.com/one.mp3", item.Enclosure.Url)
+	assert.Equal(t, "audio/mpeg", item.Enclosure.Type)
+
+	// RSS sources need the feed's URL.
+	assert.Empty(t, doc.Channel.Items[1].Source)
+}
+
+func TestRenderAtom(t *testing.T) {
+	body, err := Render(FormatAtom, testFeed())
+	require.NoError(t, err)
+
+	var doc struct {
+		XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+		ID      string   `xml:"id"`
+		Updated string   `xml:"updated"`
+		Entries []struct {
+			ID    string `xml:"id"`
+			Title string `xml:"title"`
+			Links []struct {
+				Href string `xml:"href,attr"`
+				Rel  string `xml:"rel,attr"`
+			} `xml:"link"`
+			Published string `xml:"published"`
+			Updated   string `xml:"updated"`
+			Author    string `xml:"author>name"`
+			Summary   string `xml:"summary"`
+			Source    string `xml:"source>title"`
+		} `xml:"entry"`
+	}
+	require.NoError(t, xml.Unmarshal(body, &doc))
+
+	assert.Equal(t, "urn:uuid:feed", doc.ID)
+	assert.Equal(t, "2024-03-21T09:00:00Z", doc.Updated)
+	require.Len(t, doc.Entries, 2)
+
+	entry := doc.Entries[0]
+	assert.Equal(t, "urn:uuid:one", entry.ID)
+	assert.Equal(t, "2024-03-20T09:00:00Z", entry.Published)
+	assert.Equal(t, "2024-03-21T09:00:00Z", entry.Updated)
+	assert.Equal(t, "Grace", entry.Author)
+	assert.Equal(t, "<p>Indexes</p>", entry.Summary)
+	assert.Equal(t, "Example", entry.Source)
+	require.Len(t, entry.Links, 2)
+	assert.Equal(t, "alternate", entry.Links[0].Rel)
+	assert.Equal(t, "enclosure", entry.Links[1].Rel)
+
+	assert.Empty(t, doc.Entries[1].Author)
+}
+
+func TestRenderJSON(t *testing.T) {
+	body, err := Render(FormatJSON, testFeed())
+	require.NoError(t, err)
+
+	var doc struct {
+		Version string `json:"version"`
+		FeedURL string `json:"feed_url"`
+		Items   []struct {
+			ID            string    `json:"id"`
+			Url           string    `json:"url"`
+			ContentHTML   string    `json:"content_html"`
+			DatePublished time.Time `json:"date_published"`
+			Authors       []struct {
+				Name string `json:"name"`
+			} `json:"authors"`
+			Attachments []struct {
+				MimeType string `json:"mime_type"`
+			} `json:"attachments"`
+		} `json:"items"`
+	}
+	require.NoError(t, json.Unmarshal(body, &doc))
+
+	assert.Equal(t, "https://jsonfeed.org/version/1.1", doc.Version)
+	assert.Equal(t, "https://api.example.com/v1/out/token.rss", doc.FeedURL)
+	require.Len(t, doc.Items, 2)
+	assert.Equal(t, "urn:uuid:one", doc.Items[0].ID)
+	assert.Equal(t, "<p>Indexes</p>", doc.Items[0].ContentHTML)
+	assert.Equal(t, published, doc.Items[0].DatePublished)
+	assert.Equal(t, "Grace", doc.Items[0].Authors[0].Name)
+	assert.Equal(t, "audio/mpeg", doc.Items[0].Attachments[0].MimeType)
+
+	// Posts without an author are credited to their feed.
+	assert.Equal(t, "Example", doc.Items[1].Authors[0].Name)
+}
+
+func TestRenderEmpty(t *testing.T) {
+	body, err := Render(FormatJSON, Feed{Title: "Empty"})
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"items": []`)
+
+	_, err = Render("opml", Feed{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,117 @@
+package urlnorm
+
+import (
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+var ErrUnsupportedURL = errors.New("url must be an absolute http or https url")
+
+// trackingParams are query parameters that only identify where a click came
+// from and never change the resource a URL points at.
+var trackingParams = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"dclid":   true,
+	"msclkid": true,
+	"yclid":   true,
+	"igshid":  true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"_hsenc":  true,
+	"_hsmi":   true,
+	"mkt_tok": true,
+}
+
+// Canonical returns the canonical form of an absolute http(s) URL: the scheme
+// and host are lower-cased, default ports, fragments, tracking parameters and
+// trailing slashes are removed, and the remaining query parameters are sorted.
+func Canonical(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", err
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", ErrUnsupportedURL
+	}
+
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	u.Host = host
+	if port != "" {
+		u.Host = host + ":" + port
+	}
+
+	u.User = nil
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = ""
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	u.RawQuery = canonicalQuery(u.Query())
+	u.ForceQuery = false
+
+	return u.String(), nil
+}
+
+// Resolve resolves ref against base before canonicalising it, so relative item
+// links found in a feed become absolute.
+func Resolve(base, ref string) (string, error) {
+	b, err := url.Parse(strings.TrimSpace(base))
+	if err != nil {
+		return "", err
+	}
+
+	r, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return "", err
+	}
+
+	return Canonical(b.ResolveReference(r).String())
+}
+
+func canonicalQuery(query url.Values) string {
+	for key := range query {
+		if isTrackingParam(key) {
+			query.Del(key)
+		}
+	}
+
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, key := range keys {
+		values := query[key]
+		sort.Strings(values)
+		for _, value := range values {
+			if b.Len() > 0 {
+				b.WriteByte('&')
+			}
+			b.WriteString(url.QueryEscape(key))
+			b.WriteByte('=')
+			b.WriteString(url.QueryEscape(value))
+		}
+	}
+
+	return b.String()
+}
+
+func isTrackingParam(key string) bool {
+	key = strings.ToLower(key)
+	return strings.HasPrefix(key, "utm_") || trackingParams[key]
+}
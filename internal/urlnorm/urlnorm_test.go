@@ -0,0 +1,99 @@
+package urlnorm
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCanonical(t *testing.T) {
+	tests := map[string]struct {
+		input       string
+		expectedURL string
+		expectError bool
+	}{
+		"Already canonical": {
+			input:       "https://example.com/posts/1",
+			expectedURL: "https://example.com/posts/1",
+		},
+		"Scheme and host case": {
+			input:       "HTTPS://Example.COM/Posts/1",
+			expectedURL: "https://example.com/Posts/1",
+		},
+		"Trailing slash": {
+			input:       "https://example.com/posts/1/",
+			expectedURL: "https://example.com/posts/1",
+		},
+		"Root path": {
+			input:       "https://example.com",
+			expectedURL: "https://example.com/",
+		},
+		"Default port": {
+			input:       "http://example.com:80/feed.xml",
+			expectedURL: "http://example.com/feed.xml",
+		},
+		"Non-default port": {
+			input:       "https://example.com:8443/feed.xml",
+			expectedURL: "https://example.com:8443/feed.xml",
+		},
+		"Fragment": {
+			input:       "https://example.com/posts/1#comments",
+			expectedURL: "https://example.com/posts/1",
+		},
+		"Tracking parameters": {
+			input:       "https://example.com/posts/1?utm_source=rss&utm_medium=feed&fbclid=abc&id=7",
+			expectedURL: "https://example.com/posts/1?id=7",
+		},
+		"Sorted query": {
+			input:       "https://example.com/search?q=go&a=1",
+			expectedURL: "https://example.com/search?a=1&q=go",
+		},
+		"Only tracking parameters": {
+			input:       "https://example.com/posts/1/?utm_campaign=spring",
+			expectedURL: "https://example.com/posts/1",
+		},
+		"Surrounding whitespace": {
+			input:       "  https://example.com/posts/1\n",
+			expectedURL: "https://example.com/posts/1",
+		},
+		"Relative URL": {
+			input:       "/posts/1",
+			expectError: true,
+		},
+		"Unsupported scheme": {
+			input:       "ftp://example.com/feed.xml",
+			expectError: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := Canonical(tc.input)
+
+			if tc.expectError {
+				if err == nil {
+					t.Fatalf("expected an error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.expectedURL, got); diff != "" {
+				t.Errorf("URL mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	got, err := Resolve("https://example.com/blog/feed.xml", "posts/1/?utm_source=rss")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if diff := cmp.Diff("https://example.com/blog/posts/1", got); diff != "" {
+		t.Errorf("URL mismatch (-want +got):\n%s", diff)
+	}
+}
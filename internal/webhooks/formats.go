@@ -0,0 +1,250 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Formats a webhook's requests can be rendered in. FormatJSON sends each
+// Payload as it is; the others are for chat tools, which get one message per
+// batch of posts.
+const (
+	FormatJSON    = "json"
+	FormatSlack   = "slack"
+	FormatDiscord = "discord"
+	FormatMatrix  = "matrix"
+)
+
+var Formats = []string{FormatJSON, FormatSlack, FormatDiscord, FormatMatrix}
+
+const (
+	// BatchWindow is how long a chat delivery waits for other posts to be
+	// sent in the same message.
+	BatchWindow = 30 * time.Second
+
+	// MaxBatch is the most posts sent in one chat message. Discord allows at
+	// most 10 embeds per message.
+	MaxBatch = 10
+
+	maxSnippetLength = 280
+	maxTitleLength   = 256
+
+	testMessage = "Test event from go-blog-aggregator: this webhook is working."
+)
+
+// render returns the body of a message announcing every payload in a batch,
+// in the webhook's format. Payloads without a post are test events.
+func render(format string, payloads []Payload) ([]byte, error) {
+	switch format {
+	case FormatSlack:
+		return json.Marshal(renderSlack(payloads))
+	case FormatDiscord:
+		return json.Marshal(renderDiscord(payloads))
+	case FormatMatrix:
+		return json.Marshal(renderMatrix(payloads))
+	default:
+		return nil, fmt.Errorf("unknown webhook format %q", format)
+	}
+}
+
+// summary is the line introducing a batch of more than one post.
+func summary(payloads []Payload) string {
+	return fmt.Sprintf("%d new posts", len(payloads))
+}
+
+var tags = regexp.MustCompile(`<[^>]*>`)
+
+// snippet returns the start of a post's description as plain text.
+func snippet(post *Post) string {
+	if post.Description == nil {
+		return ""
+	}
+
+	text := html.UnescapeString(tags.ReplaceAllString(*post.Description, " "))
+	return truncate(strings.Join(strings.Fields(text), " "), maxSnippetLength)
+}
+
+func truncate(s string, length int) string {
+	if utf8.RuneCountInString(s) <= length {
+		return s
+	}
+
+	runes := []rune(s)
+	return strings.TrimSpace(string(runes[:length-1])) + "…"
+}
+
+func feedName(payload Payload) string {
+	if payload.Feed == nil {
+		return ""
+	}
+	return payload.Feed.Name
+}
+
+type slackMessage struct {
+	Text        string       `json:"text"`
+	Blocks      []slackBlock `json:"blocks"`
+	UnfurlLinks bool         `json:"unfurl_links"`
+}
+
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text,omitempty"`
+	Elements []slackText `json:"elements,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// slackEscape escapes the characters Slack's mrkdwn treats as markup.
+var slackEscape = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// renderSlack renders a Block Kit message with a section per post, linking
+// its title above its snippet, and the feed's name underneath.
+func renderSlack(payloads []Payload) slackMessage {
+	msg := slackMessage{}
+	if len(payloads) > 1 {
+		msg.Text = summary(payloads)
+		msg.Blocks = append(msg.Blocks, slackBlock{Type: "header", Text: &slackText{Type: "plain_text", Text: msg.Text}})
+	}
+
+	for _, payload := range payloads {
+		if payload.Post == nil {
+			msg.Blocks = append(msg.Blocks, slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: testMessage}})
+			continue
+		}
+
+		// Slack link text ends at the first |, so titles can't contain one.
+		title := strings.ReplaceAll(truncate(payload.Post.Title, maxTitleLength), "|", "¦")
+		text := fmt.Sprintf("*<%s|%s>*", payload.Post.Url, slackEscape.Replace(title))
+		if s := snippet(payload.Post); s != "" {
+			text += "\n" + slackEscape.Replace(s)
+		}
+		msg.Blocks = append(msg.Blocks, slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: text}})
+
+		if name := feedName(payload); name != "" {
+			msg.Blocks = append(msg.Blocks, slackBlock{Type: "context", Elements: []slackText{{Type: "mrkdwn", Text: slackEscape.Replace(name)}}})
+		}
+	}
+
+	// The text is what notifications show, so a single post is announced by
+	// its title.
+	if msg.Text == "" && len(payloads) == 1 {
+		msg.Text = testMessage
+		if payloads[0].Post != nil {
+			msg.Text = payloads[0].Post.Title
+		}
+	}
+
+	return msg
+}
+
+type discordMessage struct {
+	Content         string          `json:"content,omitempty"`
+	Embeds          []discordEmbed  `json:"embeds,omitempty"`
+	AllowedMentions discordMentions `json:"allowed_mentions"`
+}
+
+type discordEmbed struct {
+	Title       string         `json:"title"`
+	Url         string         `json:"url"`
+	Description string         `json:"description,omitempty"`
+	Timestamp   *time.Time     `json:"timestamp,omitempty"`
+	Footer      *discordFooter `json:"footer,omitempty"`
+}
+
+type discordFooter struct {
+	Text string `json:"text"`
+}
+
+// discordMentions stops text in posts from pinging anyone.
+type discordMentions struct {
+	Parse []string `json:"parse"`
+}
+
+// renderDiscord renders a message with an embed per post, titled and linked
+// by the post, with its snippet and the feed's name in the footer.
+func renderDiscord(payloads []Payload) discordMessage {
+	msg := discordMessage{AllowedMentions: discordMentions{Parse: []string{}}}
+	if len(payloads) > 1 {
+		msg.Content = summary(payloads)
+	}
+
+	for _, payload := range payloads {
+		if payload.Post == nil {
+			msg.Content = strings.TrimSpace(msg.Content + "\n" + testMessage)
+			continue
+		}
+
+		embed := discordEmbed{
+			Title:       truncate(payload.Post.Title, maxTitleLength),
+			Url:         payload.Post.Url,
+			Description: snippet(payload.Post),
+			Timestamp:   payload.Post.PublishedAt,
+		}
+		if name := feedName(payload); name != "" {
+			embed.Footer = &discordFooter{Text: name}
+		}
+		msg.Embeds = append(msg.Embeds, embed)
+	}
+
+	return msg
+}
+
+// matrixMessage is the content of an m.room.message event. Notices are the
+// msgtype meant for bots, which other bots don't reply to.
+type matrixMessage struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format"`
+	FormattedBody string `json:"formatted_body"`
+}
+
+// renderMatrix renders a notice listing each post's linked title, feed name
+// and snippet, as plain text and HTML.
+func renderMatrix(payloads []Payload) matrixMessage {
+	var (
+		body          []string
+		formattedBody []string
+	)
+	if len(payloads) > 1 {
+		body = append(body, summary(payloads))
+		formattedBody = append(formattedBody, fmt.Sprintf("<p><strong>%s</strong></p>", summary(payloads)))
+	}
+
+	for _, payload := range payloads {
+		if payload.Post == nil {
+			body = append(body, testMessage)
+			formattedBody = append(formattedBody, "<p>"+html.EscapeString(testMessage)+"</p>")
+			continue
+		}
+
+		title := truncate(payload.Post.Title, maxTitleLength)
+		text := fmt.Sprintf("%s\n%s", title, payload.Post.Url)
+		formatted := fmt.Sprintf(`<a href="%s"><strong>%s</strong></a>`, html.EscapeString(payload.Post.Url), html.EscapeString(title))
+		if name := feedName(payload); name != "" {
+			text += "\n" + name
+			formatted += "<br><em>" + html.EscapeString(name) + "</em>"
+		}
+		if s := snippet(payload.Post); s != "" {
+			text += "\n" + s
+			formatted += "<br>" + html.EscapeString(s)
+		}
+
+		body = append(body, text)
+		formattedBody = append(formattedBody, "<p>"+formatted+"</p>")
+	}
+
+	return matrixMessage{
+		MsgType:       "m.notice",
+		Body:          strings.Join(body, "\n\n"),
+		Format:        "org.matrix.custom.html",
+		FormattedBody: strings.Join(formattedBody, ""),
+	}
+}
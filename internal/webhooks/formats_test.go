@@ -0,0 +1,87 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	description := "<p>Go 1.23 adds <b>range over functions</b> &amp; more.</p>"
+	publishedAt := time.Date(2024, 8, 13, 0, 0, 0, 0, time.UTC)
+	post := Payload{
+		Event: EventPostCreated,
+		Feed:  &Feed{Name: "Go Blog"},
+		Post: &Post{
+			Title:       "Go 1.23 <is> released",
+			Url:         "https://go.dev/blog/go1.23",
+			Description: &description,
+			PublishedAt: &publishedAt,
+		},
+	}
+	test := Payload{Event: EventTest}
+
+	t.Run("Slack", func(t *testing.T) {
+		msg := renderSlack([]Payload{post})
+		assert.Equal(t, "Go 1.23 <is> released", msg.Text)
+		require.Len(t, msg.Blocks, 2)
+		assert.Equal(t, "*<https://go.dev/blog/go1.23|Go 1.23 &lt;is&gt; released>*\nGo 1.23 adds range over functions &amp; more.", msg.Blocks[0].Text.Text)
+		assert.Equal(t, "Go Blog", msg.Blocks[1].Elements[0].Text)
+
+		msg = renderSlack([]Payload{post, post})
+		assert.Equal(t, "2 new posts", msg.Text)
+		assert.Equal(t, "header", msg.Blocks[0].Type)
+		assert.Len(t, msg.Blocks, 5)
+
+		msg = renderSlack([]Payload{test})
+		assert.Equal(t, testMessage, msg.Text)
+	})
+
+	t.Run("Discord", func(t *testing.T) {
+		msg := renderDiscord([]Payload{post})
+		assert.Empty(t, msg.Content)
+		require.Len(t, msg.Embeds, 1)
+		assert.Equal(t, "Go 1.23 <is> released", msg.Embeds[0].Title)
+		assert.Equal(t, "https://go.dev/blog/go1.23", msg.Embeds[0].Url)
+		assert.Equal(t, "Go 1.23 adds range over functions & more.", msg.Embeds[0].Description)
+		assert.Equal(t, "Go Blog", msg.Embeds[0].Footer.Text)
+		assert.Equal(t, &publishedAt, msg.Embeds[0].Timestamp)
+
+		js, err := json.Marshal(msg)
+		require.NoError(t, err)
+		assert.Contains(t, string(js), `"allowed_mentions":{"parse":[]}`)
+
+		msg = renderDiscord([]Payload{test})
+		assert.Equal(t, testMessage, msg.Content)
+		assert.Empty(t, msg.Embeds)
+	})
+
+	t.Run("Matrix", func(t *testing.T) {
+		msg := renderMatrix([]Payload{post})
+		assert.Equal(t, "m.notice", msg.MsgType)
+		assert.Equal(t, "Go 1.23 <is> released\nhttps://go.dev/blog/go1.23\nGo Blog\nGo 1.23 adds range over functions & more.", msg.Body)
+		assert.Equal(t, "org.matrix.custom.html", msg.Format)
+		assert.Equal(t, `<p><a href="https://go.dev/blog/go1.23"><strong>Go 1.23 &lt;is&gt; released</strong></a><br><em>Go Blog</em><br>Go 1.23 adds range over functions &amp; more.</p>`, msg.FormattedBody)
+
+		msg = renderMatrix([]Payload{post, test})
+		assert.True(t, strings.HasPrefix(msg.Body, "2 new posts\n\n"))
+	})
+
+	t.Run("Unknown formats", func(t *testing.T) {
+		_, err := render(FormatJSON, []Payload{post})
+		assert.Error(t, err)
+	})
+}
+
+func TestSnippet(t *testing.T) {
+	long := strings.Repeat("word ", 100)
+	s := snippet(&Post{Description: &long})
+	assert.LessOrEqual(t, len([]rune(s)), maxSnippetLength)
+	assert.True(t, strings.HasSuffix(s, "…"))
+
+	assert.Empty(t, snippet(&Post{}))
+}
@@ -0,0 +1,492 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/netguard"
+	"github.com/google/uuid"
+)
+
+const (
+	EventPostCreated = "post.created"
+	EventTest        = "test"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+const (
+	// MaxAttempts is how many times a delivery is tried before it's given up
+	// on.
+	MaxAttempts = 8
+
+	// Lease is how long a claimed delivery is reserved for the instance that
+	// claimed it. It must be longer than it takes to send everything claimed
+	// at once, and longer than BatchWindow.
+	Lease = 5 * time.Minute
+
+	requestTimeout = 10 * time.Second
+	firstRetry     = 30 * time.Second
+	maxRetry       = 6 * time.Hour
+
+	maxDrainLength = 1 << 20
+)
+
+// Store is the subset of database.Queries webhooks are queued and delivered
+// through.
+type Store interface {
+	GetWebhooksForFeed(ctx context.Context, feedID uuid.UUID) ([]database.Webhook, error)
+	CreateWebhookDelivery(ctx context.Context, arg database.CreateWebhookDeliveryParams) (database.WebhookDelivery, error)
+	GetWebhookBatchDue(ctx context.Context, arg database.GetWebhookBatchDueParams) (time.Time, error)
+	ClaimWebhookDeliveries(ctx context.Context, arg database.ClaimWebhookDeliveriesParams) ([]database.ClaimWebhookDeliveriesRow, error)
+	RecordWebhookDeliveryAttempt(ctx context.Context, arg database.RecordWebhookDeliveryAttemptParams) (database.WebhookDelivery, error)
+	DeleteWebhookDeliveriesBefore(ctx context.Context, createdAt time.Time) (int64, error)
+}
+
+// Payload is the body POSTed to a webhook.
+type Payload struct {
+	Event     string    `json:"event"`
+	WebhookID uuid.UUID `json:"webhook_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Feed      *Feed     `json:"feed,omitempty"`
+	Post      *Post     `json:"post,omitempty"`
+}
+
+type Feed struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+	Url  string    `json:"url"`
+}
+
+type Post struct {
+	ID          uuid.UUID  `json:"id"`
+	Title       string     `json:"title"`
+	Url         string     `json:"url"`
+	Description *string    `json:"description"`
+	Author      *string    `json:"author"`
+	PublishedAt *time.Time `json:"published_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// Dispatcher queues a delivery for each webhook a new post matches, and
+// sends queued deliveries until they succeed or run out of attempts.
+type Dispatcher struct {
+	store  Store
+	client *http.Client
+}
+
+// New returns a Dispatcher that only sends webhooks to public addresses,
+// unless allowPrivate is set. Webhooks aren't sent on redirects.
+func New(store Store, allowPrivate bool) *Dispatcher {
+	return &Dispatcher{
+		store: store,
+		client: &http.Client{
+			Transport: netguard.Transport(allowPrivate, requestTimeout),
+			Timeout:   requestTimeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Matches reports whether a post has any of the keywords in its title or
+// description, ignoring case. Every post matches when there are none.
+func Matches(keywords []string, post database.Post) bool {
+	if len(keywords) == 0 {
+		return true
+	}
+
+	text := strings.ToLower(post.Title + "\n" + post.Description.String)
+	for _, keyword := range keywords {
+		if strings.Contains(text, strings.ToLower(keyword)) {
+			return true
+		}
+	}
+	return false
+}
+
+// PostCreated queues a delivery of a newly collected post to every webhook
+// whose filters it matches. It has the signature of a scraper hook.
+func (d *Dispatcher) PostCreated(ctx context.Context, feed database.Feed, post database.Post) {
+	hooks, err := d.store.GetWebhooksForFeed(ctx, feed.ID)
+	if err != nil {
+		log.Printf("Couldn't get webhooks for feed %s: %v", feed.Name, err)
+		return
+	}
+
+	for _, hook := range hooks {
+		if !Matches(hook.Keywords, post) {
+			continue
+		}
+
+		_, err := d.Queue(ctx, hook, EventPostCreated, &feed, &post)
+		if err != nil {
+			log.Printf("Couldn't queue post %s for webhook %s: %v", post.ID, hook.ID, err)
+		}
+	}
+}
+
+// Queue adds a delivery of an event to a webhook. Deliveries in a chat
+// format wait up to BatchWindow, joining the batch of any other delivery
+// already waiting, so a burst of posts is sent as one message. Others are due
+// straight away. Each post is only queued once per webhook, so queuing it
+// again returns sql.ErrNoRows.
+func (d *Dispatcher) Queue(ctx context.Context, hook database.Webhook, event string, feed *database.Feed, post *database.Post) (database.WebhookDelivery, error) {
+	now := time.Now().UTC()
+	if hook.Format == FormatJSON {
+		return d.queue(ctx, hook, event, feed, post, now)
+	}
+
+	due, err := d.store.GetWebhookBatchDue(ctx, database.GetWebhookBatchDueParams{
+		WebhookID:  hook.ID,
+		Now:        now,
+		BatchUntil: now.Add(BatchWindow),
+	})
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		due = now.Add(BatchWindow)
+	case err != nil:
+		return database.WebhookDelivery{}, err
+	}
+
+	return d.queue(ctx, hook, event, feed, post, due)
+}
+
+func (d *Dispatcher) queue(ctx context.Context, hook database.Webhook, event string, feed *database.Feed, post *database.Post, due time.Time) (database.WebhookDelivery, error) {
+	now := time.Now().UTC()
+	payload := Payload{
+		Event:     event,
+		WebhookID: hook.ID,
+		CreatedAt: now,
+	}
+
+	var postID uuid.NullUUID
+	if feed != nil {
+		payload.Feed = &Feed{ID: feed.ID, Name: feed.Name, Url: feed.Url}
+	}
+	if post != nil {
+		postID = uuid.NullUUID{UUID: post.ID, Valid: true}
+		payload.Post = &Post{
+			ID:          post.ID,
+			Title:       post.Title,
+			Url:         post.Url,
+			Description: nullString(post.Description),
+			Author:      nullString(post.Author),
+			PublishedAt: nullTime(post.PublishedAt),
+			CreatedAt:   post.CreatedAt,
+		}
+	}
+
+	js, err := json.Marshal(payload)
+	if err != nil {
+		return database.WebhookDelivery{}, err
+	}
+
+	return d.store.CreateWebhookDelivery(ctx, database.CreateWebhookDeliveryParams{
+		ID:            uuid.New(),
+		CreatedAt:     now,
+		WebhookID:     hook.ID,
+		PostID:        postID,
+		Event:         event,
+		Payload:       string(js),
+		NextAttemptAt: due,
+	})
+}
+
+// SendTest queues a test event for a webhook and delivers it straight away,
+// returning the delivery as it was recorded.
+func (d *Dispatcher) SendTest(ctx context.Context, hook database.Webhook) (database.WebhookDelivery, error) {
+	// The delivery is leased from the start, so workers leave it alone.
+	delivery, err := d.queue(ctx, hook, EventTest, nil, nil, time.Now().UTC().Add(Lease))
+	if err != nil {
+		return database.WebhookDelivery{}, err
+	}
+
+	return d.Deliver(ctx, database.ClaimWebhookDeliveriesRow{
+		ID:        delivery.ID,
+		CreatedAt: delivery.CreatedAt,
+		WebhookID: delivery.WebhookID,
+		Event:     delivery.Event,
+		Payload:   delivery.Payload,
+		Attempts:  delivery.Attempts,
+		Url:       hook.Url,
+		Secret:    hook.Secret,
+		Format:    hook.Format,
+	})
+}
+
+// Start delivers due deliveries every interval, concurrency at a time. Sent
+// and failed deliveries are logged for logRetention.
+func (d *Dispatcher) Start(concurrency int, interval, logRetention time.Duration) {
+	log.Printf("Delivering webhooks on %v goroutines every %s...", concurrency, interval)
+	ticker := time.NewTicker(interval)
+
+	for ; ; <-ticker.C {
+		// Batches are claimed until the queue is drained, so a backlog
+		// doesn't wait an interval per batch.
+		for {
+			delivered, err := d.DeliverOnce(context.Background(), concurrency)
+			if err != nil {
+				log.Println("Couldn't deliver webhooks", err)
+			}
+			if err != nil || delivered < concurrency*MaxBatch {
+				break
+			}
+		}
+
+		_, err := d.store.DeleteWebhookDeliveriesBefore(context.Background(), time.Now().UTC().Add(-logRetention))
+		if err != nil {
+			log.Println("Couldn't delete old webhook deliveries", err)
+		}
+	}
+}
+
+// DeliverOnce claims the deliveries that are due, up to MaxBatch for each of
+// concurrency requests, and sends them concurrency requests at a time. It
+// returns how many deliveries it attempted.
+func (d *Dispatcher) DeliverOnce(ctx context.Context, concurrency int) (int, error) {
+	now := time.Now().UTC()
+	deliveries, err := d.store.ClaimWebhookDeliveries(ctx, database.ClaimWebhookDeliveriesParams{
+		LeaseUntil: now.Add(Lease),
+		Now:        now,
+		Lim:        int32(concurrency * MaxBatch), //#nosec G115
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	wg := &sync.WaitGroup{}
+	requests := make(chan struct{}, concurrency)
+	for _, batch := range group(deliveries) {
+		wg.Add(1)
+		requests <- struct{}{}
+		go func(batch []database.ClaimWebhookDeliveriesRow) {
+			defer wg.Done()
+			defer func() { <-requests }()
+
+			_, err := d.DeliverBatch(ctx, batch)
+			if err != nil {
+				log.Printf("Couldn't record webhook delivery %s: %v", batch[0].ID, err)
+			}
+		}(batch)
+	}
+	wg.Wait()
+
+	return len(deliveries), nil
+}
+
+// group splits claimed deliveries into the requests they're sent in. Chat
+// deliveries to the same webhook are sent together, oldest first and up to
+// MaxBatch at a time, and the rest are sent alone.
+func group(deliveries []database.ClaimWebhookDeliveriesRow) [][]database.ClaimWebhookDeliveriesRow {
+	slices.SortStableFunc(deliveries, func(a, b database.ClaimWebhookDeliveriesRow) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	var batches [][]database.ClaimWebhookDeliveriesRow
+	filling := make(map[uuid.UUID]int)
+	for _, delivery := range deliveries {
+		i, ok := filling[delivery.WebhookID]
+		if delivery.Format != FormatJSON && ok && len(batches[i]) < MaxBatch {
+			batches[i] = append(batches[i], delivery)
+			continue
+		}
+
+		if delivery.Format != FormatJSON {
+			filling[delivery.WebhookID] = len(batches)
+		}
+		batches = append(batches, []database.ClaimWebhookDeliveriesRow{delivery})
+	}
+	return batches
+}
+
+// Deliver sends a claimed delivery and records the attempt. Failed attempts
+// are retried with backoff until MaxAttempts is reached. The error is only
+// about recording the attempt: how the request went is in the delivery.
+func (d *Dispatcher) Deliver(ctx context.Context, delivery database.ClaimWebhookDeliveriesRow) (database.WebhookDelivery, error) {
+	recorded, err := d.DeliverBatch(ctx, []database.ClaimWebhookDeliveriesRow{delivery})
+	if err != nil {
+		return database.WebhookDelivery{}, err
+	}
+	return recorded[0], nil
+}
+
+// DeliverBatch is Deliver for deliveries to the same webhook sent in one
+// request, which is only possible for chat formats. Every delivery in the
+// batch is recorded as having had the same response.
+func (d *Dispatcher) DeliverBatch(ctx context.Context, batch []database.ClaimWebhookDeliveriesRow) ([]database.WebhookDelivery, error) {
+	statusCode, sendErr := d.send(ctx, batch)
+
+	now := time.Now().UTC()
+	recorded := make([]database.WebhookDelivery, len(batch))
+	for i, delivery := range batch {
+		attempts := int(delivery.Attempts) + 1
+		arg := database.RecordWebhookDeliveryAttemptParams{
+			Status:        StatusSucceeded,
+			AttemptedAt:   now,
+			NextAttemptAt: now,
+			ID:            delivery.ID,
+		}
+		if statusCode != 0 {
+			arg.ResponseStatus = sql.NullInt32{Int32: int32(statusCode), Valid: true} //#nosec G115
+		}
+		if sendErr != nil {
+			arg.LastError = sql.NullString{String: sendErr.Error(), Valid: true}
+			arg.Status = StatusPending
+			arg.NextAttemptAt = now.Add(Backoff(attempts))
+			if attempts >= MaxAttempts {
+				arg.Status = StatusFailed
+			}
+		}
+
+		var err error
+		recorded[i], err = d.store.RecordWebhookDeliveryAttempt(ctx, arg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return recorded, nil
+}
+
+// requestBody returns the body of the request sending a batch, in its
+// webhook's format.
+func requestBody(batch []database.ClaimWebhookDeliveriesRow) ([]byte, error) {
+	if batch[0].Format == FormatJSON {
+		return []byte(batch[0].Payload), nil
+	}
+
+	payloads := make([]Payload, len(batch))
+	for i, delivery := range batch {
+		err := json.Unmarshal([]byte(delivery.Payload), &payloads[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return render(batch[0].Format, payloads)
+}
+
+// transactionID identifies a batch sent to Matrix, which drops events sent
+// again with a transaction ID it has already seen. Retrying a batch reuses
+// its ID, so a response lost on the way back doesn't post the batch twice.
+func transactionID(batch []database.ClaimWebhookDeliveriesRow) string {
+	h := sha256.New()
+	for _, delivery := range batch {
+		h.Write(delivery.ID[:])
+	}
+	return hex.EncodeToString(h.Sum(nil))[:32]
+}
+
+// send POSTs a batch to its webhook and returns the response's status code,
+// if there was one. Responses other than 2xx are errors. Matrix webhooks are
+// the URL of a room's send endpoint, which takes a PUT with the transaction
+// ID appended, and their secret is the access token of the user to post as.
+func (d *Dispatcher) send(ctx context.Context, batch []database.ClaimWebhookDeliveriesRow) (int, error) {
+	first := batch[0]
+	body, err := requestBody(batch)
+	if err != nil {
+		return 0, err
+	}
+
+	method, target := http.MethodPost, first.Url
+	if first.Format == FormatMatrix {
+		method, target = http.MethodPut, strings.TrimSuffix(first.Url, "/")+"/"+transactionID(batch)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-blog-aggregator-webhooks")
+	if first.Format == FormatMatrix {
+		req.Header.Set("Authorization", "Bearer "+first.Secret)
+	} else {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set("X-Webhook-Event", first.Event)
+		req.Header.Set("X-Webhook-Delivery", first.ID.String())
+		req.Header.Set("X-Webhook-Timestamp", timestamp)
+		// Slack and Discord webhooks may have no secret to sign with.
+		if first.Secret != "" {
+			req.Header.Set("X-Webhook-Signature", Sign(first.Secret, timestamp, body))
+		}
+	}
+
+	// Errors are recorded where the webhook's owner can read them, so they
+	// say no more than whether the request got a response: anything else
+	// would let webhooks probe what's reachable from the server.
+	resp, err := d.client.Do(req)
+	if err != nil {
+		log.Printf("Couldn't send webhook delivery %s: %v", first.ID, err)
+		return 0, netguard.Redact(err)
+	}
+	defer resp.Body.Close()
+
+	// Draining the body lets the connection be reused.
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainLength))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// Sign returns the X-Webhook-Signature of a body sent at timestamp: the hex
+// HMAC-SHA256 of the timestamp, a dot and the body, keyed by the secret.
+// Receivers should compute the same and compare them in constant time.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff returns how long to wait before retrying after the given number of
+// failed attempts. It doubles each time from 30 seconds, up to six hours.
+func Backoff(attempts int) time.Duration {
+	wait := firstRetry
+	for i := 1; i < attempts; i++ {
+		wait *= 2
+		if wait >= maxRetry {
+			return maxRetry
+		}
+	}
+	return wait
+}
+
+func nullString(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
@@ -0,0 +1,395 @@
+package webhooks
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/database"
+	"github.com/DomenicoDicosimo/go-blog-aggregator/internal/netguard"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStore struct {
+	mu       sync.Mutex
+	hooks    []database.Webhook
+	batchDue time.Time
+	queued   []database.CreateWebhookDeliveryParams
+	claimed  []database.ClaimWebhookDeliveriesRow
+	attempts []database.RecordWebhookDeliveryAttemptParams
+}
+
+func (f *fakeStore) GetWebhooksForFeed(ctx context.Context, feedID uuid.UUID) ([]database.Webhook, error) {
+	return f.hooks, nil
+}
+
+func (f *fakeStore) CreateWebhookDelivery(ctx context.Context, arg database.CreateWebhookDeliveryParams) (database.WebhookDelivery, error) {
+	f.queued = append(f.queued, arg)
+	return database.WebhookDelivery{
+		ID:            arg.ID,
+		WebhookID:     arg.WebhookID,
+		Event:         arg.Event,
+		Payload:       arg.Payload,
+		NextAttemptAt: arg.NextAttemptAt,
+	}, nil
+}
+
+func (f *fakeStore) GetWebhookBatchDue(ctx context.Context, arg database.GetWebhookBatchDueParams) (time.Time, error) {
+	if f.batchDue.IsZero() {
+		return time.Time{}, sql.ErrNoRows
+	}
+	return f.batchDue, nil
+}
+
+func (f *fakeStore) ClaimWebhookDeliveries(ctx context.Context, arg database.ClaimWebhookDeliveriesParams) ([]database.ClaimWebhookDeliveriesRow, error) {
+	claimed := f.claimed
+	f.claimed = nil
+	return claimed, nil
+}
+
+func (f *fakeStore) RecordWebhookDeliveryAttempt(ctx context.Context, arg database.RecordWebhookDeliveryAttemptParams) (database.WebhookDelivery, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.attempts = append(f.attempts, arg)
+	return database.WebhookDelivery{ID: arg.ID, Status: arg.Status}, nil
+}
+
+func (f *fakeStore) DeleteWebhookDeliveriesBefore(ctx context.Context, createdAt time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestMatches(t *testing.T) {
+	post := database.Post{
+		Title:       "Go 1.23 is released",
+		Description: sql.NullString{String: "Range over functions", Valid: true},
+	}
+
+	assert.True(t, Matches(nil, post))
+	assert.True(t, Matches([]string{"rust", "GO 1.23"}, post))
+	assert.True(t, Matches([]string{"functions"}, post))
+	assert.False(t, Matches([]string{"rust"}, post))
+}
+
+func TestPostCreated(t *testing.T) {
+	feed := database.Feed{ID: uuid.New(), Name: "Go Blog", Url: "https://go.dev/blog/feed.atom"}
+	post := database.Post{ID: uuid.New(), FeedID: feed.ID, Title: "Go 1.23 is released", Url: "https://go.dev/blog/go1.23"}
+
+	all := database.Webhook{ID: uuid.New(), Format: FormatJSON}
+	matching := database.Webhook{ID: uuid.New(), Format: FormatJSON, Keywords: []string{"release"}}
+	other := database.Webhook{ID: uuid.New(), Format: FormatJSON, Keywords: []string{"rust"}}
+
+	store := &fakeStore{hooks: []database.Webhook{all, matching, other}}
+	New(store, true).PostCreated(context.Background(), feed, post)
+
+	require.Len(t, store.queued, 2)
+	assert.Equal(t, all.ID, store.queued[0].WebhookID)
+	assert.Equal(t, matching.ID, store.queued[1].WebhookID)
+
+	queued := store.queued[0]
+	assert.Equal(t, EventPostCreated, queued.Event)
+	assert.Equal(t, uuid.NullUUID{UUID: post.ID, Valid: true}, queued.PostID)
+
+	var payload Payload
+	require.NoError(t, json.Unmarshal([]byte(queued.Payload), &payload))
+	assert.Equal(t, EventPostCreated, payload.Event)
+	assert.Equal(t, all.ID, payload.WebhookID)
+	require.NotNil(t, payload.Feed)
+	assert.Equal(t, "Go Blog", payload.Feed.Name)
+	require.NotNil(t, payload.Post)
+	assert.Equal(t, post.ID, payload.Post.ID)
+	assert.Equal(t, post.Url, payload.Post.Url)
+	assert.Nil(t, payload.Post.Description)
+}
+
+func TestDeliver(t *testing.T) {
+	t.Run("Signs the payload", func(t *testing.T) {
+		var (
+			header http.Header
+			body   []byte
+		)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header = r.Header
+			body, _ = io.ReadAll(r.Body)
+		}))
+		defer server.Close()
+
+		store := &fakeStore{}
+		delivery := database.ClaimWebhookDeliveriesRow{
+			ID:      uuid.New(),
+			Event:   EventPostCreated,
+			Format:  FormatJSON,
+			Payload: `{"event":"post.created"}`,
+			Url:     server.URL,
+			Secret:  "a-very-secret-secret",
+		}
+
+		recorded, err := New(store, true).Deliver(context.Background(), delivery)
+		require.NoError(t, err)
+		assert.Equal(t, StatusSucceeded, recorded.Status)
+
+		assert.Equal(t, delivery.Payload, string(body))
+		assert.Equal(t, EventPostCreated, header.Get("X-Webhook-Event"))
+		assert.Equal(t, delivery.ID.String(), header.Get("X-Webhook-Delivery"))
+		timestamp := header.Get("X-Webhook-Timestamp")
+		assert.Equal(t, Sign(delivery.Secret, timestamp, body), header.Get("X-Webhook-Signature"))
+
+		require.Len(t, store.attempts, 1)
+		assert.Equal(t, sql.NullInt32{Int32: http.StatusOK, Valid: true}, store.attempts[0].ResponseStatus)
+		assert.False(t, store.attempts[0].LastError.Valid)
+	})
+
+	t.Run("Retries failures with backoff", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "try again later", http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		store := &fakeStore{}
+		delivery := database.ClaimWebhookDeliveriesRow{ID: uuid.New(), Format: FormatJSON, Payload: "{}", Url: server.URL, Attempts: 2}
+
+		recorded, err := New(store, true).Deliver(context.Background(), delivery)
+		require.NoError(t, err)
+		assert.Equal(t, StatusPending, recorded.Status)
+
+		attempt := store.attempts[0]
+		assert.Equal(t, int32(http.StatusServiceUnavailable), attempt.ResponseStatus.Int32)
+		// Response bodies aren't recorded, as webhooks' owners can read them.
+		assert.Equal(t, "unexpected status 503", attempt.LastError.String)
+		assert.WithinDuration(t, attempt.AttemptedAt.Add(2*time.Minute), attempt.NextAttemptAt, time.Second)
+	})
+
+	t.Run("Gives up after the last attempt", func(t *testing.T) {
+		store := &fakeStore{}
+		delivery := database.ClaimWebhookDeliveriesRow{ID: uuid.New(), Format: FormatJSON, Payload: "{}", Url: "http://127.0.0.1:1", Attempts: MaxAttempts - 1}
+
+		recorded, err := New(store, true).Deliver(context.Background(), delivery)
+		require.NoError(t, err)
+		assert.Equal(t, StatusFailed, recorded.Status)
+		assert.False(t, store.attempts[0].ResponseStatus.Valid)
+		assert.True(t, store.attempts[0].LastError.Valid)
+	})
+}
+
+func TestDeliverPrivateAddresses(t *testing.T) {
+	t.Run("Refuses private addresses", func(t *testing.T) {
+		called := false
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+		}))
+		defer server.Close()
+
+		store := &fakeStore{}
+		delivery := database.ClaimWebhookDeliveriesRow{ID: uuid.New(), Format: FormatJSON, Payload: "{}", Url: server.URL}
+
+		recorded, err := New(store, false).Deliver(context.Background(), delivery)
+		require.NoError(t, err)
+		assert.Equal(t, StatusPending, recorded.Status)
+		assert.False(t, called)
+		assert.Equal(t, netguard.ErrPrivateAddress.Error(), store.attempts[0].LastError.String)
+	})
+
+	t.Run("Doesn't follow redirects", func(t *testing.T) {
+		called := false
+		target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+		}))
+		defer target.Close()
+		server := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+		defer server.Close()
+
+		store := &fakeStore{}
+		delivery := database.ClaimWebhookDeliveriesRow{ID: uuid.New(), Format: FormatJSON, Payload: "{}", Url: server.URL}
+
+		recorded, err := New(store, true).Deliver(context.Background(), delivery)
+		require.NoError(t, err)
+		assert.Equal(t, StatusPending, recorded.Status)
+		assert.False(t, called)
+		assert.Equal(t, int32(http.StatusTemporaryRedirect), store.attempts[0].ResponseStatus.Int32)
+	})
+}
+
+func TestDeliverOnce(t *testing.T) {
+	requests := make(chan string, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r.Header.Get("X-Webhook-Delivery")
+	}))
+	defer server.Close()
+
+	store := &fakeStore{claimed: []database.ClaimWebhookDeliveriesRow{
+		{ID: uuid.New(), Format: FormatJSON, Payload: "{}", Url: server.URL},
+		{ID: uuid.New(), Format: FormatJSON, Payload: "{}", Url: server.URL},
+	}}
+
+	delivered, err := New(store, true).DeliverOnce(context.Background(), 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, delivered)
+	assert.Len(t, requests, 2)
+	assert.Len(t, store.attempts, 2)
+}
+
+func TestSendTest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	store := &fakeStore{}
+	hook := database.Webhook{ID: uuid.New(), Url: server.URL, Secret: "a-very-secret-secret", Format: FormatJSON}
+
+	recorded, err := New(store, true).SendTest(context.Background(), hook)
+	require.NoError(t, err)
+	assert.Equal(t, StatusSucceeded, recorded.Status)
+
+	require.Len(t, store.queued, 1)
+	assert.Equal(t, EventTest, store.queued[0].Event)
+	assert.False(t, store.queued[0].PostID.Valid)
+	assert.True(t, store.queued[0].NextAttemptAt.After(time.Now()), "test deliveries are leased")
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, Backoff(1))
+	assert.Equal(t, time.Minute, Backoff(2))
+	assert.Equal(t, 2*time.Minute, Backoff(3))
+	assert.Equal(t, 6*time.Hour, Backoff(20))
+}
+
+func TestQueue(t *testing.T) {
+	feed := database.Feed{ID: uuid.New(), Name: "Go Blog"}
+	post := database.Post{ID: uuid.New(), Title: "Go 1.23 is released"}
+
+	t.Run("JSON deliveries are due straight away", func(t *testing.T) {
+		store := &fakeStore{batchDue: time.Now().Add(time.Minute)}
+		_, err := New(store, true).Queue(context.Background(), database.Webhook{ID: uuid.New(), Format: FormatJSON}, EventPostCreated, &feed, &post)
+		require.NoError(t, err)
+
+		assert.WithinDuration(t, time.Now(), store.queued[0].NextAttemptAt, time.Second)
+	})
+
+	t.Run("Chat deliveries wait for a batch", func(t *testing.T) {
+		store := &fakeStore{}
+		_, err := New(store, true).Queue(context.Background(), database.Webhook{ID: uuid.New(), Format: FormatSlack}, EventPostCreated, &feed, &post)
+		require.NoError(t, err)
+
+		assert.WithinDuration(t, time.Now().Add(BatchWindow), store.queued[0].NextAttemptAt, time.Second)
+	})
+
+	t.Run("Chat deliveries join the waiting batch", func(t *testing.T) {
+		due := time.Now().UTC().Add(10 * time.Second)
+		store := &fakeStore{batchDue: due}
+		_, err := New(store, true).Queue(context.Background(), database.Webhook{ID: uuid.New(), Format: FormatDiscord}, EventPostCreated, &feed, &post)
+		require.NoError(t, err)
+
+		assert.Equal(t, due, store.queued[0].NextAttemptAt)
+	})
+}
+
+func TestGroup(t *testing.T) {
+	chat := uuid.New()
+	other := uuid.New()
+	start := time.Now()
+
+	var deliveries []database.ClaimWebhookDeliveriesRow
+	for i := 0; i < MaxBatch+2; i++ {
+		deliveries = append(deliveries, database.ClaimWebhookDeliveriesRow{
+			ID:        uuid.New(),
+			CreatedAt: start.Add(-time.Duration(i) * time.Second),
+			WebhookID: chat,
+			Format:    FormatSlack,
+		})
+	}
+	deliveries = append(deliveries,
+		database.ClaimWebhookDeliveriesRow{ID: uuid.New(), CreatedAt: start, WebhookID: other, Format: FormatJSON},
+		database.ClaimWebhookDeliveriesRow{ID: uuid.New(), CreatedAt: start, WebhookID: other, Format: FormatJSON},
+	)
+
+	batches := group(deliveries)
+	require.Len(t, batches, 4)
+	assert.Len(t, batches[0], MaxBatch)
+	assert.Len(t, batches[1], 2)
+	assert.Len(t, batches[2], 1)
+	assert.Len(t, batches[3], 1)
+
+	for _, batch := range batches[:2] {
+		for i := 1; i < len(batch); i++ {
+			assert.False(t, batch[i].CreatedAt.Before(batch[i-1].CreatedAt), "batches are sent oldest first")
+		}
+	}
+}
+
+func TestDeliverBatch(t *testing.T) {
+	payload := func(title string) string {
+		js, err := json.Marshal(Payload{
+			Event: EventPostCreated,
+			Feed:  &Feed{Name: "Go Blog"},
+			Post:  &Post{ID: uuid.New(), Title: title, Url: "https://go.dev/blog/" + title},
+		})
+		require.NoError(t, err)
+		return string(js)
+	}
+
+	t.Run("Chat deliveries are sent in one message", func(t *testing.T) {
+		bodies := make(chan []byte, 2)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			bodies <- body
+		}))
+		defer server.Close()
+
+		webhookID := uuid.New()
+		store := &fakeStore{claimed: []database.ClaimWebhookDeliveriesRow{
+			{ID: uuid.New(), WebhookID: webhookID, Format: FormatDiscord, Payload: payload("first"), Url: server.URL},
+			{ID: uuid.New(), WebhookID: webhookID, Format: FormatDiscord, Payload: payload("second"), Url: server.URL},
+		}}
+
+		delivered, err := New(store, true).DeliverOnce(context.Background(), 10)
+		require.NoError(t, err)
+		assert.Equal(t, 2, delivered)
+		require.Len(t, bodies, 1)
+		assert.Len(t, store.attempts, 2)
+
+		var msg discordMessage
+		require.NoError(t, json.Unmarshal(<-bodies, &msg))
+		assert.Equal(t, "2 new posts", msg.Content)
+		require.Len(t, msg.Embeds, 2)
+		assert.Equal(t, "first", msg.Embeds[0].Title)
+		assert.Equal(t, "second", msg.Embeds[1].Title)
+	})
+
+	t.Run("Matrix batches are PUT with a stable transaction ID", func(t *testing.T) {
+		var paths []string
+		var authorization string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPut, r.Method)
+			paths = append(paths, r.URL.Path)
+			authorization = r.Header.Get("Authorization")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		batch := []database.ClaimWebhookDeliveriesRow{
+			{ID: uuid.New(), Format: FormatMatrix, Payload: payload("first"), Url: server.URL + "/_matrix/client/v3/rooms/!room:example.org/send/m.room.message", Secret: "syt_access_token"},
+			{ID: uuid.New(), Format: FormatMatrix, Payload: payload("second"), Url: server.URL + "/_matrix/client/v3/rooms/!room:example.org/send/m.room.message", Secret: "syt_access_token"},
+		}
+
+		store := &fakeStore{}
+		recorded, err := New(store, true).DeliverBatch(context.Background(), batch)
+		require.NoError(t, err)
+		require.Len(t, recorded, 2)
+		assert.Equal(t, StatusPending, recorded[0].Status)
+
+		_, err = New(store, true).DeliverBatch(context.Background(), batch)
+		require.NoError(t, err)
+
+		require.Len(t, paths, 2)
+		assert.Equal(t, paths[0], paths[1])
+		assert.Contains(t, paths[0], "/send/m.room.message/")
+		assert.Equal(t, "Bearer syt_access_token", authorization)
+	})
+}
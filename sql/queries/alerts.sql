@@ -0,0 +1,87 @@
+-- name: CreateAlert :one
+INSERT INTO alerts (id, created_at, updated_at, user_id, phrase, enabled)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetAlerts :many
+SELECT * FROM alerts
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: UpdateAlert :one
+UPDATE alerts
+SET phrase = $3, enabled = $4, updated_at = $5
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteAlert :execrows
+DELETE FROM alerts
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetAlertsForFeed :many
+SELECT alerts.* FROM alerts
+WHERE alerts.enabled
+  AND EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.user_id = alerts.user_id
+      AND feed_follows.feed_id = @feed_id::uuid
+      AND NOT feed_follows.muted
+      AND feed_follows.notify
+  );
+
+-- name: QueueAlertMatch :exec
+WITH match AS (
+  INSERT INTO alert_matches (alert_id, post_id, created_at)
+  VALUES (@alert_id::uuid, @post_id::uuid, @now::timestamp)
+  ON CONFLICT DO NOTHING
+  RETURNING alert_id
+)
+UPDATE alerts
+-- An alert that isn't already due is sent straight away, unless it was sent
+-- within the window, when it waits for the window to end.
+SET next_send_at = coalesce(next_send_at, greatest(@now::timestamp, last_sent_at + make_interval(secs => @window_seconds::integer)))
+WHERE id IN (SELECT alert_id FROM match);
+
+-- name: ClaimDueAlerts :many
+UPDATE alerts
+-- Due alerts are leased to the caller until lease_until, so concurrent
+-- instances don't send them twice.
+SET next_send_at = @lease_until::timestamp
+FROM users
+WHERE users.id = alerts.user_id
+  AND alerts.id IN (
+    SELECT due.id FROM alerts AS due
+    WHERE due.enabled AND due.next_send_at <= @now::timestamp
+    ORDER BY due.next_send_at
+    LIMIT @lim::integer
+    FOR UPDATE SKIP LOCKED
+  )
+RETURNING alerts.*, users.email, users.name;
+
+-- name: GetAlertMatches :many
+SELECT count(*) OVER() AS count, sqlc.embed(posts), feeds.name AS feed_name
+FROM alert_matches
+JOIN posts ON posts.id = alert_matches.post_id
+JOIN feeds ON feeds.id = posts.feed_id
+WHERE alert_matches.alert_id = @alert_id::uuid
+  AND alert_matches.sent_at IS NULL
+  AND alert_matches.created_at <= @until::timestamp
+ORDER BY alert_matches.created_at, posts.id
+LIMIT @lim::integer;
+
+-- name: MarkAlertMatchesSent :exec
+UPDATE alert_matches
+SET sent_at = @sent_at::timestamp
+WHERE alert_id = @alert_id::uuid
+  AND sent_at IS NULL
+  AND created_at <= @until::timestamp;
+
+-- name: RecordAlertSent :exec
+UPDATE alerts
+SET last_sent_at = coalesce(sqlc.narg('last_sent_at')::timestamp, last_sent_at),
+  -- Matches that arrived while the alert was being sent are sent next.
+  next_send_at = CASE
+    WHEN EXISTS (SELECT 1 FROM alert_matches WHERE alert_id = alerts.id AND sent_at IS NULL)
+    THEN @retry_at::timestamp
+  END
+WHERE id = @id::uuid;
@@ -0,0 +1,78 @@
+-- name: CreateDigest :one
+INSERT INTO digests (id, created_at, updated_at, user_id, schedule, hour, weekday, timezone, feed_ids, folder_ids, enabled, unsubscribe_token, next_send_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+RETURNING *;
+
+-- name: GetDigests :many
+SELECT * FROM digests
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: UpdateDigest :one
+UPDATE digests
+SET schedule = $3, hour = $4, weekday = $5, timezone = $6, feed_ids = $7, folder_ids = $8, enabled = $9, next_send_at = $10, updated_at = $11
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteDigest :execrows
+DELETE FROM digests
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetDigestByUnsubscribeToken :one
+SELECT * FROM digests
+WHERE unsubscribe_token = $1;
+
+-- name: UnsubscribeDigest :execrows
+UPDATE digests
+SET enabled = FALSE, updated_at = $2
+WHERE unsubscribe_token = $1;
+
+-- name: ClaimDueDigests :many
+UPDATE digests
+-- Due digests are leased to the caller until lease_until, so concurrent
+-- instances don't send them twice.
+SET next_send_at = @lease_until::timestamp
+FROM users
+WHERE users.id = digests.user_id
+  AND digests.id IN (
+    SELECT due.id FROM digests AS due
+    WHERE due.enabled AND due.next_send_at <= @now::timestamp
+    ORDER BY due.next_send_at
+    LIMIT @lim::integer
+    FOR UPDATE SKIP LOCKED
+  )
+RETURNING digests.*, users.email, users.name;
+
+-- name: RecordDigestSent :exec
+UPDATE digests
+SET last_sent_at = coalesce(sqlc.narg('last_sent_at')::timestamp, last_sent_at),
+  next_send_at = @next_send_at::timestamp
+WHERE id = @id::uuid;
+
+-- name: GetDigestPosts :many
+SELECT count(*) OVER() AS count, sqlc.embed(posts), feeds.name AS feed_name
+FROM posts
+JOIN feeds ON feeds.id = posts.feed_id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = @user_id::uuid
+WHERE EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.user_id = @user_id::uuid
+      AND feed_follows.feed_id = posts.feed_id
+      -- Muted feeds are only included when chosen by ID.
+      AND (NOT feed_follows.muted OR feed_follows.feed_id = ANY(@feed_ids::uuid[]))
+      -- Digests without feeds or folders cover every followed feed.
+      AND (
+        (cardinality(@feed_ids::uuid[]) = 0 AND cardinality(@folder_ids::uuid[]) = 0)
+        OR feed_follows.feed_id = ANY(@feed_ids::uuid[])
+        OR EXISTS (
+          SELECT 1 FROM feed_follow_folders
+          WHERE feed_follow_folders.feed_follow_id = feed_follows.id
+            AND feed_follow_folders.folder_id = ANY(@folder_ids::uuid[])
+        )
+      )
+  )
+  AND post_states.read_at IS NULL
+  AND post_states.hidden_at IS NULL
+  AND posts.created_at >= @since::timestamp
+ORDER BY posts.created_at DESC, posts.id DESC
+LIMIT @lim::integer;
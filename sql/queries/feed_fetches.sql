@@ -0,0 +1,20 @@
+-- name: CreateFeedFetch :one
+INSERT INTO feed_fetches (id, feed_id, started_at, finished_at, status_code, bytes, items_seen, items_new, error)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING *;
+
+-- name: GetFeedFetches :many
+SELECT count(*) OVER(), id, feed_id, started_at, finished_at, status_code, bytes, items_seen, items_new, error
+FROM feed_fetches
+WHERE feed_id = @feed_id::uuid
+ORDER BY
+  CASE
+    WHEN @sort = 'started_at' THEN started_at END ASC,
+    CASE
+    WHEN @sort = '-started_at' THEN started_at END DESC,
+  id ASC
+  LIMIT @lim::integer OFFSET @off::integer;
+
+-- name: DeleteFeedFetchesBefore :execrows
+DELETE FROM feed_fetches
+WHERE started_at < $1;
@@ -7,6 +7,44 @@ RETURNING *;
 DELETE FROM feed_follows
 WHERE feed_id = $1;
 
+-- name: DeleteFeedFollowsForUser :execrows
+DELETE FROM feed_follows
+WHERE feed_id = $1 AND user_id = $2;
+
 -- name: GetFeedFollows :many
+SELECT sqlc.embed(feed_follows),
+  ARRAY(
+    SELECT feed_follow_folders.folder_id
+    FROM feed_follow_folders
+    WHERE feed_follow_folders.feed_follow_id = feed_follows.id
+    ORDER BY feed_follow_folders.folder_id
+  )::uuid[] AS folder_ids
+FROM feed_follows
+WHERE user_id = $1
+ORDER BY feed_follows.priority DESC, feed_follows.created_at;
+
+-- name: UpdateFeedFollow :one
+UPDATE feed_follows
+SET title = $3, priority = $4, muted = $5, notify = $6, updated_at = $7
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: GetFeedFollowForUser :one
 SELECT * FROM feed_follows
-WHERE user_id = $1;
\ No newline at end of file
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetFeedFollowCounts :many
+SELECT follows.feed_id,
+  count(posts.id) FILTER (WHERE post_states.hidden_at IS NULL) AS total,
+  count(posts.id) FILTER (WHERE post_states.read_at IS NULL AND post_states.hidden_at IS NULL) AS unread
+FROM (
+  SELECT DISTINCT feed_follows.feed_id
+  FROM feed_follows
+  WHERE feed_follows.user_id = @user_id::uuid
+) AS follows
+LEFT JOIN posts ON posts.feed_id = follows.feed_id
+LEFT JOIN post_states ON post_states.post_id = posts.id
+  AND post_states.user_id = @user_id::uuid
+  AND (post_states.read_at IS NOT NULL OR post_states.hidden_at IS NOT NULL)
+GROUP BY follows.feed_id
+ORDER BY follows.feed_id;
@@ -14,4 +14,18 @@ LIMIT $1;
 -- name: MarkFeedFetched :exec
 UPDATE feeds
 SET last_fetched_at = NOW(), updated_at = NOW()
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: UpdateFeedRetention :one
+UPDATE feeds
+SET retention_days = $2, retention_max_posts = $3, updated_at = $4
+WHERE id = $1
+RETURNING *;
+
+-- name: GetFeedByID :one
+SELECT * FROM feeds
+WHERE id = $1;
+
+-- name: GetFeedByURL :one
+SELECT * FROM feeds
+WHERE url = $1;
@@ -0,0 +1,65 @@
+-- name: CreateFolder :one
+INSERT INTO folders (id, created_at, updated_at, user_id, name, position)
+VALUES ($1, $2, $3, $4, $5, (SELECT COALESCE(MAX(position) + 1, 0) FROM folders WHERE user_id = $4))
+RETURNING *;
+
+-- name: GetFolders :many
+SELECT * FROM folders
+WHERE user_id = $1
+ORDER BY position, name;
+
+-- name: GetFolder :one
+SELECT * FROM folders
+WHERE id = $1 AND user_id = $2;
+
+-- name: UpdateFolder :one
+UPDATE folders
+SET name = $3, updated_at = $4
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteFolder :execrows
+DELETE FROM folders
+WHERE id = $1 AND user_id = $2;
+
+-- name: ReorderFolders :execrows
+UPDATE folders
+SET position = ordered.position - 1, updated_at = @updated_at::timestamp
+FROM unnest(@folder_ids::uuid[]) WITH ORDINALITY AS ordered(id, position)
+WHERE folders.id = ordered.id AND folders.user_id = @user_id::uuid;
+
+-- name: SetFeedFollowFolders :exec
+WITH removed AS (
+  DELETE FROM feed_follow_folders
+  WHERE feed_follow_id = @feed_follow_id::uuid
+    AND NOT (folder_id = ANY(@folder_ids::uuid[]))
+)
+INSERT INTO feed_follow_folders (feed_follow_id, folder_id)
+SELECT @feed_follow_id::uuid, folders.id
+FROM folders
+WHERE folders.id = ANY(@folder_ids::uuid[]) AND folders.user_id = @user_id::uuid
+ON CONFLICT DO NOTHING;
+
+-- name: GetFeedFollowFolderIDs :many
+SELECT folder_id FROM feed_follow_folders
+WHERE feed_follow_id = $1
+ORDER BY folder_id;
+
+-- name: GetFolderCounts :many
+SELECT folders.id AS folder_id,
+  count(posts.id) FILTER (WHERE post_states.hidden_at IS NULL) AS total,
+  count(posts.id) FILTER (WHERE post_states.read_at IS NULL AND post_states.hidden_at IS NULL) AS unread
+FROM folders
+LEFT JOIN (
+  SELECT DISTINCT feed_follow_folders.folder_id, feed_follows.feed_id
+  FROM feed_follow_folders
+  JOIN feed_follows ON feed_follows.id = feed_follow_folders.feed_follow_id
+  WHERE feed_follows.user_id = @user_id::uuid
+) AS folder_feeds ON folder_feeds.folder_id = folders.id
+LEFT JOIN posts ON posts.feed_id = folder_feeds.feed_id
+LEFT JOIN post_states ON post_states.post_id = posts.id
+  AND post_states.user_id = @user_id::uuid
+  AND (post_states.read_at IS NOT NULL OR post_states.hidden_at IS NOT NULL)
+WHERE folders.user_id = @user_id::uuid
+GROUP BY folders.id
+ORDER BY folders.id;
@@ -0,0 +1,14 @@
+-- name: GetGReaderItems :many
+SELECT sqlc.embed(posts), post_states.read_at, post_states.starred_at
+FROM posts
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = @user_id::uuid
+WHERE posts.item_id = ANY(@item_ids::bigint[])
+  AND EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = posts.feed_id AND feed_follows.user_id = @user_id::uuid
+  );
+
+-- name: GetGReaderPostIDs :many
+SELECT posts.id
+FROM posts
+WHERE posts.item_id = ANY(@item_ids::bigint[]);
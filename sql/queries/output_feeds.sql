@@ -0,0 +1,35 @@
+-- name: CreateOutputFeed :one
+INSERT INTO output_feeds (id, created_at, updated_at, user_id, name, source, folder_id, tag, saved_search_id, token)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING *;
+
+-- name: GetOutputFeeds :many
+SELECT * FROM output_feeds
+WHERE user_id = $1
+ORDER BY name;
+
+-- name: GetOutputFeedByToken :one
+SELECT * FROM output_feeds
+WHERE token = $1;
+
+-- name: UpdateOutputFeed :one
+UPDATE output_feeds
+SET name = $3, source = $4, folder_id = $5, tag = $6, saved_search_id = $7, updated_at = $8
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: RotateOutputFeedToken :one
+UPDATE output_feeds
+SET token = $3, updated_at = $4
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteOutputFeed :execrows
+DELETE FROM output_feeds
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetFollowedFeedTitles :many
+SELECT feeds.id, coalesce(feed_follows.title, feeds.name)::text AS title, feeds.url
+FROM feed_follows
+JOIN feeds ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1;
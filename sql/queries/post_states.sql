@@ -0,0 +1,78 @@
+-- name: SetPostsReadState :execrows
+INSERT INTO post_states (user_id, post_id, read_at, updated_at)
+SELECT @user_id::uuid, posts.id, sqlc.narg('read_at')::timestamp, @updated_at::timestamp
+FROM posts
+WHERE posts.id = ANY(@post_ids::uuid[])
+  AND EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = posts.feed_id AND feed_follows.user_id = @user_id::uuid
+  )
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET read_at = CASE
+    WHEN EXCLUDED.read_at IS NULL THEN NULL
+    ELSE COALESCE(post_states.read_at, EXCLUDED.read_at)
+  END,
+  updated_at = EXCLUDED.updated_at;
+
+-- name: MarkAllPostsRead :execrows
+INSERT INTO post_states (user_id, post_id, read_at, updated_at)
+SELECT @user_id::uuid, posts.id, @read_at::timestamp, @read_at::timestamp
+FROM posts
+WHERE posts.created_at <= @before::timestamp
+  AND (posts.feed_id = @feed_id::uuid OR @feed_id::uuid = '00000000-0000-0000-0000-000000000000'::uuid)
+  AND EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = posts.feed_id AND feed_follows.user_id = @user_id::uuid
+  )
+  AND NOT EXISTS (
+    SELECT 1 FROM post_states
+    WHERE post_states.user_id = @user_id::uuid
+      AND post_states.post_id = posts.id
+      AND post_states.read_at IS NOT NULL
+  )
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET read_at = EXCLUDED.read_at, updated_at = EXCLUDED.updated_at;
+
+-- name: SetPostStarred :execrows
+INSERT INTO post_states (user_id, post_id, starred_at, updated_at)
+SELECT @user_id::uuid, posts.id, sqlc.narg('starred_at')::timestamp, @updated_at::timestamp
+FROM posts
+WHERE posts.id = @post_id::uuid
+  AND EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = posts.feed_id AND feed_follows.user_id = @user_id::uuid
+  )
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET starred_at = CASE
+    WHEN EXCLUDED.starred_at IS NULL THEN NULL
+    ELSE COALESCE(post_states.starred_at, EXCLUDED.starred_at)
+  END,
+  updated_at = EXCLUDED.updated_at;
+
+-- name: SetPostHidden :execrows
+INSERT INTO post_states (user_id, post_id, hidden_at, updated_at)
+SELECT @user_id::uuid, posts.id, sqlc.narg('hidden_at')::timestamp, @updated_at::timestamp
+FROM posts
+WHERE posts.id = @post_id::uuid
+  AND EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.feed_id = posts.feed_id AND feed_follows.user_id = @user_id::uuid
+  )
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET hidden_at = CASE
+    WHEN EXCLUDED.hidden_at IS NULL THEN NULL
+    ELSE COALESCE(post_states.hidden_at, EXCLUDED.hidden_at)
+  END,
+  updated_at = EXCLUDED.updated_at;
+
+-- name: GetPostStatesChangedForUser :many
+SELECT * FROM post_states
+WHERE user_id = @user_id::uuid
+  AND (changed_xid, change_seq) > (@after_xid::bigint, @after_seq::bigint)
+  -- Changes wait until every older transaction has ended, as those could
+  -- still commit changes that belong before them. A transaction's own
+  -- changes are final to it.
+  AND (changed_xid < pg_snapshot_xmin(pg_current_snapshot())::text::bigint
+    OR changed_xid = pg_current_xact_id_if_assigned()::text::bigint)
+ORDER BY changed_xid, change_seq
+LIMIT @lim::integer;
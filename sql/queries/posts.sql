@@ -1,29 +1,152 @@
 -- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, simhash, cluster_id, author, content, enclosure_url, enclosure_type, language)
+SELECT $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15
+WHERE NOT EXISTS (
+  SELECT 1 FROM pruned_posts
+  WHERE pruned_posts.feed_id = $8 AND pruned_posts.url = $5
+)
 RETURNING *;
 
+-- name: IsPostVisibleToUser :one
+SELECT EXISTS (
+  SELECT 1
+  FROM posts
+  JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+  WHERE posts.id = $1 AND feed_follows.user_id = $2
+);
+
+-- name: FindPostClusterByURL :one
+SELECT cluster_id
+FROM posts
+WHERE url = $1
+ORDER BY created_at ASC
+LIMIT 1;
+
+-- name: FindPostClusterBySimhash :one
+SELECT cluster_id
+FROM posts
+-- The bands narrow the posts down through posts_simhash_bands_idx, before
+-- the distance of each is checked.
+WHERE simhash_bands && simhash_bands(@simhash::bigint)
+  AND created_at > @since::timestamp
+  AND bit_count((simhash # @simhash::bigint)::bit(64)) <= @max_distance::integer
+ORDER BY created_at ASC
+LIMIT 1;
+
+-- name: CountPostsForUser :one
+SELECT CASE WHEN @collapse::boolean THEN count(DISTINCT posts.cluster_id) ELSE count(*) END
+FROM filter_posts(
+  @user_id::uuid, @search::text, @feed_ids::uuid[], @folder_id::uuid, @status::text, @starred::boolean,
+  @include_hidden::boolean, @tags::text[], @match_all_tags::boolean,
+  sqlc.narg('published_after')::timestamp, sqlc.narg('published_before')::timestamp, sqlc.narg('created_after')::timestamp,
+  @author::text, sqlc.narg('has_enclosure')::boolean, @language::text
+) AS posts;
+
 -- name: GetPostsForUser :many
-SELECT count(*) OVER(), posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.cluster_id, posts.author,
+  posts.enclosure_url, posts.enclosure_type, posts.language, posts.item_id,
+  (
+    SELECT count(*)
+    FROM posts AS duplicates
+    JOIN feed_follows AS duplicate_follows ON duplicate_follows.feed_id = duplicates.feed_id
+    WHERE duplicate_follows.user_id = @user_id::uuid
+      AND duplicates.cluster_id = posts.cluster_id
+      AND duplicates.id <> posts.id
+  ) AS duplicate_count,
+  post_states.read_at, post_states.starred_at, post_states.hidden_at,
+  ARRAY(
+    SELECT tags.name
+    FROM post_tags
+    JOIN tags ON tags.id = post_tags.tag_id
+    WHERE post_tags.post_id = posts.id AND tags.user_id = @user_id::uuid
+    ORDER BY tags.name
+  )::text[] AS tags,
+  CASE WHEN @search::text <> '' THEN ts_headline(
+    'english',
+    post_search_text(posts.description, posts.content),
+    websearch_to_tsquery('english', @search::text),
+    'MaxFragments=2, MaxWords=30, MinWords=10'
+  ) END AS snippet,
+  (CASE WHEN @search::text <> '' THEN ts_rank_cd(post_search_vector(posts.title, posts.description, posts.content), websearch_to_tsquery('english', @search::text)) ELSE 0 END)::real AS relevance
+FROM filter_posts(
+  @user_id::uuid, @search::text, @feed_ids::uuid[], @folder_id::uuid, @status::text, @starred::boolean,
+  @include_hidden::boolean, @tags::text[], @match_all_tags::boolean,
+  sqlc.narg('published_after')::timestamp, sqlc.narg('published_before')::timestamp, sqlc.narg('created_after')::timestamp,
+  @author::text, sqlc.narg('has_enclosure')::boolean, @language::text
+) AS posts
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = @user_id::uuid
+-- Collapsed listings show the earliest post of each story that matches.
+WHERE (NOT @collapse::boolean OR NOT EXISTS (
+    SELECT 1
+    FROM filter_posts(
+      @user_id::uuid, @search::text, @feed_ids::uuid[], @folder_id::uuid, @status::text, @starred::boolean,
+      @include_hidden::boolean, @tags::text[], @match_all_tags::boolean,
+      sqlc.narg('published_after')::timestamp, sqlc.narg('published_before')::timestamp, sqlc.narg('created_after')::timestamp,
+      @author::text, sqlc.narg('has_enclosure')::boolean, @language::text
+    ) AS duplicates
+    WHERE duplicates.cluster_id = posts.cluster_id
+      AND (duplicates.created_at, duplicates.id) < (posts.created_at, posts.id)
+  ))
+  -- Keyset pagination: only posts after the cursor in the sort order. Ties
+  -- are broken by ID in the same direction, so a cursor is a (key, id) pair.
+  AND (@cursor_id::uuid = '00000000-0000-0000-0000-000000000000'::uuid OR CASE
+    WHEN @sort_desc::boolean THEN CASE @sort_key::text
+      WHEN 'title' THEN (posts.title, posts.id) < (@cursor_text::text, @cursor_id::uuid)
+      WHEN 'published_at' THEN (coalesce(posts.published_at, posts.created_at), posts.id) < (@cursor_time::timestamp, @cursor_id::uuid)
+      WHEN 'relevance' THEN (ts_rank_cd(post_search_vector(posts.title, posts.description, posts.content), websearch_to_tsquery('english', @search::text)), posts.id) < (@cursor_rank::real, @cursor_id::uuid)
+      ELSE posts.id < @cursor_id::uuid
+    END
+    ELSE CASE @sort_key::text
+      WHEN 'title' THEN (posts.title, posts.id) > (@cursor_text::text, @cursor_id::uuid)
+      WHEN 'published_at' THEN (coalesce(posts.published_at, posts.created_at), posts.id) > (@cursor_time::timestamp, @cursor_id::uuid)
+      WHEN 'relevance' THEN (ts_rank_cd(post_search_vector(posts.title, posts.description, posts.content), websearch_to_tsquery('english', @search::text)), posts.id) > (@cursor_rank::real, @cursor_id::uuid)
+      ELSE posts.id > @cursor_id::uuid
+    END
+  END)
+ORDER BY
+  CASE WHEN @sort_key::text = 'title' AND NOT @sort_desc::boolean THEN posts.title END ASC,
+  CASE WHEN @sort_key::text = 'title' AND @sort_desc::boolean THEN posts.title END DESC,
+  CASE WHEN @sort_key::text = 'published_at' AND NOT @sort_desc::boolean THEN coalesce(posts.published_at, posts.created_at) END ASC,
+  CASE WHEN @sort_key::text = 'published_at' AND @sort_desc::boolean THEN coalesce(posts.published_at, posts.created_at) END DESC,
+  CASE WHEN @sort_key::text = 'relevance' AND NOT @sort_desc::boolean THEN ts_rank_cd(post_search_vector(posts.title, posts.description, posts.content), websearch_to_tsquery('english', @search::text)) END ASC,
+  CASE WHEN @sort_key::text = 'relevance' AND @sort_desc::boolean THEN ts_rank_cd(post_search_vector(posts.title, posts.description, posts.content), websearch_to_tsquery('english', @search::text)) END DESC,
+  CASE WHEN NOT @sort_desc::boolean THEN posts.id END ASC,
+  CASE WHEN @sort_desc::boolean THEN posts.id END DESC
+LIMIT @lim::integer OFFSET @off::integer;
+
+-- name: GetNewPostsForUser :many
+SELECT sqlc.embed(posts), post_states.read_at, post_states.starred_at,
+  ARRAY(
+    SELECT tags.name
+    FROM post_tags
+    JOIN tags ON tags.id = post_tags.tag_id
+    WHERE post_tags.post_id = posts.id AND tags.user_id = @user_id::uuid
+    ORDER BY tags.name
+  )::text[] AS tags
 FROM posts
-JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
-WHERE feed_follows.user_id = @user_id::uuid
-  AND (to_tsvector('simple', posts.title) @@ plainto_tsquery('simple', @title::text) OR @title::text = '')
-  AND (posts.feed_id = @feed_id::uuid OR @feed_id::uuid = '00000000-0000-0000-0000-000000000000'::uuid)
-ORDER BY 
-  CASE 
-    WHEN @sort = 'id' THEN posts.id END ASC,
-    CASE 
-    WHEN @sort = 'title' THEN posts.title END ASC,
-    CASE 
-    WHEN @sort = 'published_at' THEN posts.published_at END ASC,
-    CASE 
-    WHEN @sort = '-id' THEN posts.id END DESC,
-    CASE 
-    WHEN @sort = '-title' THEN posts.title END DESC,
-    CASE 
-    WHEN @sort = '-published_at' THEN posts.published_at END DESC,
-  posts.id ASC
-  LIMIT @lim::integer OFFSET @off::integer;
-
-    
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = @user_id::uuid
+WHERE EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.user_id = @user_id::uuid
+      AND feed_follows.feed_id = posts.feed_id
+      AND NOT feed_follows.muted
+  )
+  AND post_states.hidden_at IS NULL
+  AND (posts.created_xid, posts.stream_seq) > (@after_xid::bigint, @after_seq::bigint)
+  -- Posts wait until every older transaction has ended, as those could still
+  -- commit posts that belong before them. A transaction's own posts are
+  -- final to it.
+  AND (posts.created_xid < pg_snapshot_xmin(pg_current_snapshot())::text::bigint
+    OR posts.created_xid = pg_current_xact_id_if_assigned()::text::bigint)
+ORDER BY posts.created_xid, posts.stream_seq
+LIMIT @lim::integer;
+
+-- name: GetStreamHorizon :one
+SELECT pg_snapshot_xmin(pg_current_snapshot())::text::bigint AS xid;
+
+-- name: GetPostStreamOrder :one
+SELECT created_xid, stream_seq FROM posts
+WHERE id = $1;
+
+-- name: NotifyPostCreated :exec
+SELECT pg_notify('new_posts', '');
@@ -0,0 +1,42 @@
+-- name: PrunePosts :execrows
+WITH policies AS (
+  SELECT feeds.id AS feed_id,
+    COALESCE(feeds.retention_days, @default_days::integer) AS max_age_days,
+    COALESCE(feeds.retention_max_posts, @default_max_posts::integer) AS max_posts
+  FROM feeds
+),
+ranked AS (
+  SELECT posts.id, posts.created_at, policies.max_age_days, policies.max_posts,
+    row_number() OVER (PARTITION BY posts.feed_id ORDER BY posts.created_at DESC, posts.id DESC) AS position
+  FROM posts
+  JOIN policies ON policies.feed_id = posts.feed_id
+  WHERE (policies.max_age_days > 0 OR policies.max_posts > 0)
+    -- Posts someone has starred or tagged are kept and don't count towards
+    -- the limit.
+    AND NOT EXISTS (
+      SELECT 1 FROM post_states
+      WHERE post_states.post_id = posts.id AND post_states.starred_at IS NOT NULL
+    )
+    AND NOT EXISTS (
+      SELECT 1 FROM post_tags
+      WHERE post_tags.post_id = posts.id
+    )
+),
+pruned AS (
+  DELETE FROM posts
+  USING ranked
+  WHERE posts.id = ranked.id
+    AND (
+      (ranked.max_age_days > 0 AND ranked.created_at < NOW() - make_interval(days => ranked.max_age_days))
+      OR (ranked.max_posts > 0 AND ranked.position > ranked.max_posts)
+    )
+  RETURNING posts.feed_id, posts.url
+)
+INSERT INTO pruned_posts (feed_id, url, pruned_at)
+SELECT feed_id, url, NOW()
+FROM pruned
+ON CONFLICT (feed_id, url) DO UPDATE SET pruned_at = EXCLUDED.pruned_at;
+
+-- name: DeletePrunedPostsBefore :execrows
+DELETE FROM pruned_posts
+WHERE pruned_at < $1;
@@ -0,0 +1,66 @@
+-- name: CreateRule :one
+INSERT INTO rules (id, created_at, updated_at, user_id, name, feed_id, field, match_type, pattern, actions, tag, enabled)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+RETURNING *;
+
+-- name: GetRules :many
+SELECT * FROM rules
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: GetRule :one
+SELECT * FROM rules
+WHERE id = $1 AND user_id = $2;
+
+-- name: UpdateRule :one
+UPDATE rules
+SET name = $3, feed_id = $4, field = $5, match_type = $6, pattern = $7, actions = $8, tag = $9, enabled = $10, updated_at = $11
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteRule :execrows
+DELETE FROM rules
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetRulesForFeed :many
+SELECT sqlc.embed(rules), follows.title AS follow_title, follows.notify
+FROM rules
+JOIN LATERAL (
+  SELECT feed_follows.title, feed_follows.notify
+  FROM feed_follows
+  WHERE feed_follows.user_id = rules.user_id AND feed_follows.feed_id = @feed_id::uuid
+  ORDER BY feed_follows.created_at
+  LIMIT 1
+) AS follows ON TRUE
+WHERE rules.enabled
+  AND (rules.feed_id IS NULL OR rules.feed_id = @feed_id::uuid);
+
+-- name: GetPostsToMatch :many
+SELECT sqlc.embed(posts), feeds.name AS feed_name, follows.title AS follow_title, follows.notify
+FROM posts
+JOIN feeds ON feeds.id = posts.feed_id
+JOIN LATERAL (
+  SELECT feed_follows.title, feed_follows.notify
+  FROM feed_follows
+  WHERE feed_follows.user_id = @user_id::uuid AND feed_follows.feed_id = posts.feed_id
+  ORDER BY feed_follows.created_at
+  LIMIT 1
+) AS follows ON TRUE
+WHERE (sqlc.narg('feed_id')::uuid IS NULL OR posts.feed_id = sqlc.narg('feed_id')::uuid)
+  AND posts.id > @after::uuid
+ORDER BY posts.id
+LIMIT @lim::integer;
+
+-- name: CreateNotification :exec
+INSERT INTO notifications (id, created_at, user_id, post_id, rule_id)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (user_id, post_id, rule_id) DO NOTHING;
+
+-- name: GetNotifications :many
+SELECT count(*) OVER(), notifications.id, notifications.created_at, notifications.post_id, notifications.rule_id,
+  posts.title, posts.url, posts.feed_id
+FROM notifications
+JOIN posts ON posts.id = notifications.post_id
+WHERE notifications.user_id = @user_id::uuid
+ORDER BY notifications.created_at DESC, notifications.id
+LIMIT @lim::integer OFFSET @off::integer;
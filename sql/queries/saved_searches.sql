@@ -0,0 +1,42 @@
+-- name: CreateSavedSearch :one
+INSERT INTO saved_searches (id, created_at, updated_at, user_id, name, query, feed_id, folder_id, tags, match_all_tags)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING *;
+
+-- name: GetSavedSearches :many
+SELECT * FROM saved_searches
+WHERE user_id = $1
+ORDER BY name;
+
+-- name: GetSavedSearch :one
+SELECT * FROM saved_searches
+WHERE id = $1 AND user_id = $2;
+
+-- name: UpdateSavedSearch :one
+UPDATE saved_searches
+SET name = $3, query = $4, feed_id = $5, folder_id = $6, tags = $7, match_all_tags = $8, updated_at = $9
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteSavedSearch :execrows
+DELETE FROM saved_searches
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetSavedSearchCounts :many
+SELECT saved_searches.id AS saved_search_id, counts.total, counts.unread
+FROM saved_searches
+CROSS JOIN LATERAL (
+  SELECT count(*) AS total,
+    count(*) FILTER (WHERE post_states.read_at IS NULL) AS unread
+  -- The same posts as the search's listing with no other parameters.
+  FROM filter_posts(
+    saved_searches.user_id, saved_searches.query,
+    CASE WHEN saved_searches.feed_id IS NULL THEN '{}'::uuid[] ELSE ARRAY[saved_searches.feed_id] END,
+    coalesce(saved_searches.folder_id, '00000000-0000-0000-0000-000000000000'::uuid),
+    'all', false, false, saved_searches.tags, saved_searches.match_all_tags,
+    NULL, NULL, NULL, '', NULL, ''
+  ) AS posts
+  LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = saved_searches.user_id
+) AS counts
+WHERE saved_searches.user_id = @user_id::uuid
+ORDER BY saved_searches.id;
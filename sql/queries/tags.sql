@@ -0,0 +1,40 @@
+-- name: AddPostTags :execrows
+WITH new_tags AS (
+  INSERT INTO tags (created_at, user_id, name)
+  SELECT @created_at::timestamp, @user_id::uuid, unnest(@names::text[])
+  ON CONFLICT (user_id, name) DO NOTHING
+  RETURNING id
+),
+post_tag_ids AS (
+  SELECT id FROM new_tags
+  UNION
+  SELECT id FROM tags
+  WHERE tags.user_id = @user_id::uuid AND tags.name = ANY(@names::text[])
+)
+INSERT INTO post_tags (tag_id, post_id, created_at)
+SELECT post_tag_ids.id, @post_id::uuid, @created_at::timestamp
+FROM post_tag_ids
+ON CONFLICT DO NOTHING;
+
+-- name: RemovePostTag :execrows
+DELETE FROM post_tags
+USING tags
+WHERE post_tags.tag_id = tags.id
+  AND tags.user_id = @user_id::uuid
+  AND tags.name = @name::text
+  AND post_tags.post_id = @post_id::uuid;
+
+-- name: GetPostTags :many
+SELECT tags.name
+FROM post_tags
+JOIN tags ON tags.id = post_tags.tag_id
+WHERE tags.user_id = $1 AND post_tags.post_id = $2
+ORDER BY tags.name;
+
+-- name: GetTagsForUser :many
+SELECT tags.name, count(post_tags.post_id) AS count
+FROM tags
+LEFT JOIN post_tags ON post_tags.tag_id = tags.id
+WHERE tags.user_id = $1
+GROUP BY tags.id, tags.name
+ORDER BY tags.name;
@@ -0,0 +1,100 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks (id, created_at, updated_at, user_id, url, secret, feed_id, folder_id, keywords, enabled, format)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+RETURNING *;
+
+-- name: GetWebhooks :many
+SELECT * FROM webhooks
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: GetWebhook :one
+SELECT * FROM webhooks
+WHERE id = $1 AND user_id = $2;
+
+-- name: UpdateWebhook :one
+UPDATE webhooks
+SET url = $3, secret = $4, feed_id = $5, folder_id = $6, keywords = $7, enabled = $8, format = $9, updated_at = $10
+WHERE id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteWebhook :execrows
+DELETE FROM webhooks
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetWebhooksForFeed :many
+SELECT webhooks.* FROM webhooks
+WHERE webhooks.enabled
+  -- Only the feed's followers hear about its posts, through the webhooks
+  -- whose feed and folder filters let them through. Keywords are matched by
+  -- the caller.
+  AND EXISTS (
+    SELECT 1 FROM feed_follows
+    WHERE feed_follows.user_id = webhooks.user_id AND feed_follows.feed_id = @feed_id::uuid
+  )
+  AND (webhooks.feed_id IS NULL OR webhooks.feed_id = @feed_id::uuid)
+  AND (webhooks.folder_id IS NULL OR EXISTS (
+    SELECT 1 FROM feed_follow_folders
+    JOIN feed_follows ON feed_follows.id = feed_follow_folders.feed_follow_id
+    WHERE feed_follow_folders.folder_id = webhooks.folder_id
+      AND feed_follows.user_id = webhooks.user_id
+      AND feed_follows.feed_id = @feed_id::uuid
+  ));
+
+-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_deliveries (id, created_at, webhook_id, post_id, event, payload, next_attempt_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (webhook_id, post_id) DO NOTHING
+RETURNING *;
+
+-- name: ClaimWebhookDeliveries :many
+UPDATE webhook_deliveries
+-- Due deliveries are leased to the caller until lease_until, so concurrent
+-- instances don't send them twice.
+SET next_attempt_at = @lease_until::timestamp
+FROM webhooks
+WHERE webhooks.id = webhook_deliveries.webhook_id
+  AND webhook_deliveries.id IN (
+    SELECT due.id FROM webhook_deliveries AS due
+    WHERE due.status = 'pending' AND due.next_attempt_at <= @now::timestamp
+    ORDER BY due.next_attempt_at
+    LIMIT @lim::integer
+    FOR UPDATE SKIP LOCKED
+  )
+RETURNING webhook_deliveries.id, webhook_deliveries.created_at, webhook_deliveries.webhook_id, webhook_deliveries.event,
+  webhook_deliveries.payload, webhook_deliveries.attempts, webhooks.url, webhooks.secret, webhooks.format;
+
+-- name: GetWebhookBatchDue :one
+SELECT next_attempt_at FROM webhook_deliveries
+WHERE webhook_id = @webhook_id::uuid
+  AND status = 'pending'
+  AND attempts = 0
+  -- Claimed deliveries are leased past the batch window, so only deliveries
+  -- still waiting for their batch are found.
+  AND next_attempt_at > @now::timestamp
+  AND next_attempt_at <= @batch_until::timestamp
+ORDER BY next_attempt_at
+LIMIT 1;
+
+-- name: RecordWebhookDeliveryAttempt :one
+UPDATE webhook_deliveries
+SET status = @status::text,
+  attempts = attempts + 1,
+  last_attempt_at = @attempted_at::timestamp,
+  next_attempt_at = @next_attempt_at::timestamp,
+  response_status = sqlc.narg('response_status')::integer,
+  last_error = sqlc.narg('last_error')::text
+WHERE id = @id::uuid
+RETURNING *;
+
+-- name: GetWebhookDeliveries :many
+SELECT count(*) OVER() AS count, sqlc.embed(webhook_deliveries)
+FROM webhook_deliveries
+JOIN webhooks ON webhooks.id = webhook_deliveries.webhook_id
+WHERE webhook_deliveries.webhook_id = @webhook_id::uuid AND webhooks.user_id = @user_id::uuid
+ORDER BY webhook_deliveries.created_at DESC, webhook_deliveries.id
+LIMIT @lim::integer OFFSET @off::integer;
+
+-- name: DeleteWebhookDeliveriesBefore :execrows
+DELETE FROM webhook_deliveries
+WHERE created_at < @created_at::timestamp AND status <> 'pending';
@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE feed_fetches (
+id              UUID        NOT NULL PRIMARY KEY,
+feed_id         UUID        NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+started_at      TIMESTAMP   NOT NULL,
+finished_at     TIMESTAMP   NOT NULL,
+status_code     INTEGER,
+bytes           BIGINT      NOT NULL DEFAULT 0,
+items_seen      INTEGER     NOT NULL DEFAULT 0,
+items_new       INTEGER     NOT NULL DEFAULT 0,
+error           TEXT
+);
+
+CREATE INDEX IF NOT EXISTS feed_fetches_feed_id_started_at_idx ON feed_fetches (feed_id, started_at DESC);
+CREATE INDEX IF NOT EXISTS feed_fetches_started_at_idx ON feed_fetches (started_at);
+
+INSERT INTO permissions (code)
+VALUES
+    ('feeds:admin');
+
+-- +goose Down
+DELETE FROM permissions WHERE code = 'feeds:admin';
+DROP TABLE IF EXISTS feed_fetches;
@@ -0,0 +1,23 @@
+-- +goose Up
+-- Feed and post URLs are kept canonical from here on. URLs saved before are
+-- canonicalised by running `go run ./cmd/canonicalize` once after migrating,
+-- as canonical forms are worked out in Go.
+ALTER TABLE posts DROP CONSTRAINT IF EXISTS posts_url_key;
+ALTER TABLE posts ADD CONSTRAINT posts_feed_id_url_key UNIQUE (feed_id, url);
+
+CREATE INDEX IF NOT EXISTS posts_url_idx ON posts (url);
+
+-- +goose Down
+DROP INDEX IF EXISTS posts_url_idx;
+
+DELETE FROM posts
+WHERE id IN (
+    SELECT id FROM (
+        SELECT id, row_number() OVER (PARTITION BY url ORDER BY created_at, id) AS n
+        FROM posts
+    ) duplicates
+    WHERE n > 1
+);
+
+ALTER TABLE posts DROP CONSTRAINT IF EXISTS posts_feed_id_url_key;
+ALTER TABLE posts ADD CONSTRAINT posts_url_key UNIQUE (url);
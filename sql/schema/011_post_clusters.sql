@@ -0,0 +1,28 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN simhash BIGINT,
+ADD COLUMN cluster_id UUID;
+
+-- Existing copies of the same URL start out in the same cluster
+UPDATE posts
+SET cluster_id = (
+    SELECT first_copy.id
+    FROM posts AS first_copy
+    WHERE first_copy.url = posts.url
+    ORDER BY first_copy.created_at, first_copy.id
+    LIMIT 1
+);
+
+ALTER TABLE posts
+ALTER COLUMN cluster_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS posts_cluster_id_idx ON posts (cluster_id);
+CREATE INDEX IF NOT EXISTS posts_created_at_idx ON posts (created_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS posts_created_at_idx;
+DROP INDEX IF EXISTS posts_cluster_id_idx;
+
+ALTER TABLE posts
+DROP COLUMN cluster_id,
+DROP COLUMN simhash;
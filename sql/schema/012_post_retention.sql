@@ -0,0 +1,24 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN retention_days       INTEGER,
+ADD COLUMN retention_max_posts  INTEGER;
+
+-- Posts removed by retention are remembered so the scraper doesn't collect
+-- them again while they're still listed in the feed.
+CREATE TABLE pruned_posts (
+feed_id     UUID        NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+url         TEXT        NOT NULL,
+pruned_at   TIMESTAMP   NOT NULL,
+PRIMARY KEY (feed_id, url)
+);
+
+CREATE INDEX IF NOT EXISTS pruned_posts_pruned_at_idx ON pruned_posts (pruned_at);
+CREATE INDEX IF NOT EXISTS posts_feed_id_created_at_idx ON posts (feed_id, created_at DESC);
+
+-- +goose Down
+DROP INDEX IF EXISTS posts_feed_id_created_at_idx;
+DROP TABLE IF EXISTS pruned_posts;
+
+ALTER TABLE feeds
+DROP COLUMN retention_max_posts,
+DROP COLUMN retention_days;
@@ -0,0 +1,25 @@
+-- +goose Up
+CREATE TABLE post_states (
+user_id     UUID        NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+post_id     UUID        NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+read_at     TIMESTAMP,
+updated_at  TIMESTAMP   NOT NULL,
+PRIMARY KEY (user_id, post_id)
+);
+
+CREATE INDEX IF NOT EXISTS post_states_post_id_idx ON post_states (post_id);
+
+INSERT INTO permissions (code)
+VALUES
+    ('posts:write');
+
+-- Everyone who can read posts can keep track of what they've read.
+INSERT INTO users_permissions (user_id, permissions_id)
+SELECT users_permissions.user_id, (SELECT id FROM permissions WHERE code = 'posts:write')
+FROM users_permissions
+JOIN permissions ON permissions.id = users_permissions.permissions_id
+WHERE permissions.code = 'posts:read';
+
+-- +goose Down
+DELETE FROM permissions WHERE code = 'posts:write';
+DROP TABLE IF EXISTS post_states;
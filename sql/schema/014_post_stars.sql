@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE post_states
+ADD COLUMN starred_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS post_states_starred_idx ON post_states (post_id) WHERE starred_at IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS post_states_starred_idx;
+
+ALTER TABLE post_states
+DROP COLUMN starred_at;
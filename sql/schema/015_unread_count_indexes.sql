@@ -0,0 +1,7 @@
+-- +goose Up
+CREATE INDEX IF NOT EXISTS feed_follows_user_id_feed_id_idx ON feed_follows (user_id, feed_id);
+CREATE INDEX IF NOT EXISTS post_states_user_id_read_idx ON post_states (user_id, post_id) WHERE read_at IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS post_states_user_id_read_idx;
+DROP INDEX IF EXISTS feed_follows_user_id_feed_id_idx;
@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE folders (
+id          UUID        NOT NULL PRIMARY KEY,
+created_at  TIMESTAMP   NOT NULL,
+updated_at  TIMESTAMP   NOT NULL,
+user_id     UUID        NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+name        TEXT        NOT NULL,
+position    INTEGER     NOT NULL DEFAULT 0,
+CONSTRAINT folders_user_id_name_key UNIQUE (user_id, name)
+);
+
+CREATE TABLE feed_follow_folders (
+feed_follow_id  UUID    NOT NULL REFERENCES feed_follows(id) ON DELETE CASCADE,
+folder_id       UUID    NOT NULL REFERENCES folders(id) ON DELETE CASCADE,
+PRIMARY KEY (feed_follow_id, folder_id)
+);
+
+CREATE INDEX IF NOT EXISTS feed_follow_folders_folder_id_idx ON feed_follow_folders (folder_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS feed_follow_folders;
+DROP TABLE IF EXISTS folders;
@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE feed_follows
+ADD COLUMN title     TEXT,
+ADD COLUMN priority  INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN muted     BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN notify    BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- +goose Down
+ALTER TABLE feed_follows
+DROP COLUMN notify,
+DROP COLUMN muted,
+DROP COLUMN priority,
+DROP COLUMN title;
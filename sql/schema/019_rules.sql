@@ -0,0 +1,44 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN author TEXT;
+
+ALTER TABLE post_states
+ADD COLUMN hidden_at TIMESTAMP;
+
+CREATE TABLE rules (
+id          UUID        NOT NULL PRIMARY KEY,
+created_at  TIMESTAMP   NOT NULL,
+updated_at  TIMESTAMP   NOT NULL,
+user_id     UUID        NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+name        TEXT        NOT NULL,
+feed_id     UUID        REFERENCES feeds(id) ON DELETE CASCADE,
+field       TEXT        NOT NULL,
+match_type  TEXT        NOT NULL,
+pattern     TEXT        NOT NULL,
+actions     TEXT[]      NOT NULL,
+tag         TEXT,
+enabled     BOOLEAN     NOT NULL DEFAULT TRUE
+);
+
+CREATE INDEX IF NOT EXISTS rules_user_id_idx ON rules (user_id);
+
+CREATE TABLE notifications (
+id          UUID        NOT NULL PRIMARY KEY,
+created_at  TIMESTAMP   NOT NULL,
+user_id     UUID        NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+post_id     UUID        NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+rule_id     UUID        NOT NULL REFERENCES rules(id) ON DELETE CASCADE,
+CONSTRAINT notifications_user_id_post_id_rule_id_key UNIQUE (user_id, post_id, rule_id)
+);
+
+CREATE INDEX IF NOT EXISTS notifications_user_id_created_at_idx ON notifications (user_id, created_at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS rules;
+
+ALTER TABLE post_states
+DROP COLUMN hidden_at;
+
+ALTER TABLE posts
+DROP COLUMN author;
@@ -0,0 +1,37 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN content TEXT;
+
+-- post_search_vector weights a post's title above its description, and its
+-- description above its content, with markup stripped from both.
+-- +goose StatementBegin
+CREATE FUNCTION post_search_vector(title TEXT, description TEXT, content TEXT) RETURNS tsvector
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+  SELECT setweight(to_tsvector('english', coalesce(title, '')), 'A')
+    || setweight(to_tsvector('english', regexp_replace(coalesce(description, ''), '<[^>]*>', ' ', 'g')), 'B')
+    || setweight(to_tsvector('english', regexp_replace(coalesce(content, ''), '<[^>]*>', ' ', 'g')), 'C')
+$$;
+-- +goose StatementEnd
+
+-- post_search_text is the plain text search snippets are taken from.
+-- +goose StatementBegin
+CREATE FUNCTION post_search_text(description TEXT, content TEXT) RETURNS TEXT
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+  SELECT regexp_replace(coalesce(description, '') || ' ' || coalesce(content, ''), '<[^>]*>', ' ', 'g')
+$$;
+-- +goose StatementEnd
+
+CREATE INDEX IF NOT EXISTS posts_search_idx ON posts USING GIN (post_search_vector(title, description, content));
+
+DROP INDEX IF EXISTS movies_title_idx;
+
+-- +goose Down
+CREATE INDEX IF NOT EXISTS movies_title_idx ON posts USING GIN (to_tsvector('simple', title));
+
+DROP INDEX IF EXISTS posts_search_idx;
+
+DROP FUNCTION IF EXISTS post_search_text;
+DROP FUNCTION IF EXISTS post_search_vector;
+
+ALTER TABLE posts
+DROP COLUMN content;
@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE saved_searches (
+id              UUID        NOT NULL PRIMARY KEY,
+created_at      TIMESTAMP   NOT NULL,
+updated_at      TIMESTAMP   NOT NULL,
+user_id         UUID        NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+name            TEXT        NOT NULL,
+query           TEXT        NOT NULL DEFAULT '',
+feed_id         UUID        REFERENCES feeds(id) ON DELETE CASCADE,
+folder_id       UUID        REFERENCES folders(id) ON DELETE CASCADE,
+tags            TEXT[]      NOT NULL DEFAULT '{}',
+match_all_tags  BOOLEAN     NOT NULL DEFAULT FALSE,
+CONSTRAINT saved_searches_user_id_name_key UNIQUE (user_id, name)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS saved_searches;
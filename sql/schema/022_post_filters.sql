@@ -0,0 +1,21 @@
+-- +goose Up
+ALTER TABLE posts
+ADD COLUMN enclosure_url   TEXT,
+ADD COLUMN enclosure_type  TEXT,
+ADD COLUMN language        TEXT;
+
+CREATE INDEX IF NOT EXISTS posts_published_at_idx ON posts (published_at);
+CREATE INDEX IF NOT EXISTS posts_author_idx ON posts (lower(author));
+CREATE INDEX IF NOT EXISTS posts_language_idx ON posts (language text_pattern_ops);
+CREATE INDEX IF NOT EXISTS posts_enclosure_idx ON posts (feed_id) WHERE enclosure_url IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS posts_enclosure_idx;
+DROP INDEX IF EXISTS posts_language_idx;
+DROP INDEX IF EXISTS posts_author_idx;
+DROP INDEX IF EXISTS posts_published_at_idx;
+
+ALTER TABLE posts
+DROP COLUMN language,
+DROP COLUMN enclosure_type,
+DROP COLUMN enclosure_url;
@@ -0,0 +1,24 @@
+-- +goose Up
+CREATE INDEX IF NOT EXISTS post_states_user_id_updated_at_idx ON post_states (user_id, updated_at, post_id);
+
+-- notify_post_state_changed announces which user's post states changed, so
+-- live connections on every instance can sync them. Notifications with the
+-- same payload are sent once per transaction, however many rows change.
+-- +goose StatementBegin
+CREATE FUNCTION notify_post_state_changed() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+  PERFORM pg_notify('post_states', NEW.user_id::text);
+  RETURN NULL;
+END
+$$;
+-- +goose StatementEnd
+
+CREATE TRIGGER post_states_changed
+AFTER INSERT OR UPDATE ON post_states
+FOR EACH ROW EXECUTE FUNCTION notify_post_state_changed();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS post_states_changed ON post_states;
+DROP FUNCTION IF EXISTS notify_post_state_changed();
+DROP INDEX IF EXISTS post_states_user_id_updated_at_idx;
@@ -0,0 +1,39 @@
+-- +goose Up
+CREATE TABLE webhooks (
+id          UUID        NOT NULL PRIMARY KEY,
+created_at  TIMESTAMP   NOT NULL,
+updated_at  TIMESTAMP   NOT NULL,
+user_id     UUID        NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+url         TEXT        NOT NULL,
+secret      TEXT        NOT NULL,
+feed_id     UUID        REFERENCES feeds(id) ON DELETE CASCADE,
+folder_id   UUID        REFERENCES folders(id) ON DELETE CASCADE,
+keywords    TEXT[]      NOT NULL DEFAULT '{}',
+enabled     BOOLEAN     NOT NULL DEFAULT TRUE
+);
+
+CREATE INDEX IF NOT EXISTS webhooks_user_id_idx ON webhooks (user_id);
+
+CREATE TABLE webhook_deliveries (
+id               UUID        NOT NULL PRIMARY KEY,
+created_at       TIMESTAMP   NOT NULL,
+webhook_id       UUID        NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+post_id          UUID        REFERENCES posts(id) ON DELETE SET NULL,
+event            TEXT        NOT NULL,
+payload          TEXT        NOT NULL,
+status           TEXT        NOT NULL DEFAULT 'pending',
+attempts         INTEGER     NOT NULL DEFAULT 0,
+next_attempt_at  TIMESTAMP   NOT NULL,
+last_attempt_at  TIMESTAMP,
+response_status  INTEGER,
+last_error       TEXT
+);
+
+-- A post is only ever queued once per webhook.
+CREATE UNIQUE INDEX IF NOT EXISTS webhook_deliveries_webhook_id_post_id_idx ON webhook_deliveries (webhook_id, post_id);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_id_created_at_idx ON webhook_deliveries (webhook_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+
+-- +goose Down
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
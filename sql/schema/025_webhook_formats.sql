@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE webhooks
+ADD COLUMN format TEXT NOT NULL DEFAULT 'json';
+
+-- +goose Down
+ALTER TABLE webhooks
+DROP COLUMN format;
@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE digests (
+id                 UUID        NOT NULL PRIMARY KEY,
+created_at         TIMESTAMP   NOT NULL,
+updated_at         TIMESTAMP   NOT NULL,
+user_id            UUID        NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+schedule           TEXT        NOT NULL,
+hour               INTEGER     NOT NULL,
+weekday            INTEGER     NOT NULL DEFAULT 0,
+timezone           TEXT        NOT NULL,
+feed_ids           UUID[]      NOT NULL DEFAULT '{}',
+folder_ids         UUID[]      NOT NULL DEFAULT '{}',
+enabled            BOOLEAN     NOT NULL DEFAULT TRUE,
+unsubscribe_token  TEXT        NOT NULL UNIQUE,
+next_send_at       TIMESTAMP   NOT NULL,
+last_sent_at       TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS digests_user_id_idx ON digests (user_id);
+CREATE INDEX IF NOT EXISTS digests_due_idx ON digests (next_send_at) WHERE enabled;
+
+-- +goose Down
+DROP TABLE IF EXISTS digests;
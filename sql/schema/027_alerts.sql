@@ -0,0 +1,28 @@
+-- +goose Up
+CREATE TABLE alerts (
+id            UUID        NOT NULL PRIMARY KEY,
+created_at    TIMESTAMP   NOT NULL,
+updated_at    TIMESTAMP   NOT NULL,
+user_id       UUID        NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+phrase        TEXT        NOT NULL,
+enabled       BOOLEAN     NOT NULL DEFAULT TRUE,
+next_send_at  TIMESTAMP,
+last_sent_at  TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS alerts_user_id_idx ON alerts (user_id);
+CREATE INDEX IF NOT EXISTS alerts_due_idx ON alerts (next_send_at) WHERE enabled AND next_send_at IS NOT NULL;
+
+CREATE TABLE alert_matches (
+alert_id    UUID        NOT NULL REFERENCES alerts(id) ON DELETE CASCADE,
+post_id     UUID        NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+created_at  TIMESTAMP   NOT NULL,
+sent_at     TIMESTAMP,
+PRIMARY KEY (alert_id, post_id)
+);
+
+CREATE INDEX IF NOT EXISTS alert_matches_unsent_idx ON alert_matches (alert_id, created_at) WHERE sent_at IS NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS alert_matches;
+DROP TABLE IF EXISTS alerts;
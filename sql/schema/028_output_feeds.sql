@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE output_feeds (
+id               UUID        NOT NULL PRIMARY KEY,
+created_at       TIMESTAMP   NOT NULL,
+updated_at       TIMESTAMP   NOT NULL,
+user_id          UUID        NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+name             TEXT        NOT NULL,
+source           TEXT        NOT NULL,
+folder_id        UUID        REFERENCES folders(id) ON DELETE CASCADE,
+tag              TEXT,
+saved_search_id  UUID        REFERENCES saved_searches(id) ON DELETE CASCADE,
+token            TEXT        NOT NULL UNIQUE
+);
+
+CREATE INDEX IF NOT EXISTS output_feeds_user_id_idx ON output_feeds (user_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS output_feeds;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- Google Reader clients identify items by 64-bit integers.
+ALTER TABLE posts
+ADD COLUMN item_id  BIGINT GENERATED ALWAYS AS IDENTITY;
+
+CREATE UNIQUE INDEX IF NOT EXISTS posts_item_id_key ON posts (item_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS posts_item_id_key;
+
+ALTER TABLE posts
+DROP COLUMN item_id;
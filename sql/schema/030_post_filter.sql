@@ -0,0 +1,64 @@
+-- +goose Up
+-- filter_posts returns the posts a user's listing filters match. Listings,
+-- their counts and the duplicates they collapse all filter through it, so
+-- they can't disagree about which posts match. It's a single query, which
+-- Postgres inlines into the queries calling it. Arguments are qualified with
+-- the function's name where they share a column's name.
+-- +goose StatementBegin
+CREATE FUNCTION filter_posts(
+  user_id          UUID,
+  search           TEXT,
+  feed_ids         UUID[],
+  folder_id        UUID,
+  status           TEXT,
+  starred          BOOLEAN,
+  include_hidden   BOOLEAN,
+  tags             TEXT[],
+  match_all_tags   BOOLEAN,
+  published_after  TIMESTAMP,
+  published_before TIMESTAMP,
+  created_after    TIMESTAMP,
+  author           TEXT,
+  has_enclosure    BOOLEAN,
+  language         TEXT
+) RETURNS SETOF posts
+LANGUAGE sql STABLE AS $$
+  SELECT posts.*
+  FROM posts
+  JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+  LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = filter_posts.user_id
+  WHERE feed_follows.user_id = filter_posts.user_id
+    -- Muted feeds only show up when asked for by ID.
+    AND (NOT feed_follows.muted OR posts.feed_id = ANY(filter_posts.feed_ids))
+    AND (filter_posts.search = '' OR post_search_vector(posts.title, posts.description, posts.content) @@ websearch_to_tsquery('english', filter_posts.search))
+    AND (cardinality(filter_posts.feed_ids) = 0 OR posts.feed_id = ANY(filter_posts.feed_ids))
+    AND (filter_posts.folder_id = '00000000-0000-0000-0000-000000000000'::uuid OR EXISTS (
+      SELECT 1 FROM feed_follow_folders
+      WHERE feed_follow_folders.feed_follow_id = feed_follows.id
+        AND feed_follow_folders.folder_id = filter_posts.folder_id
+    ))
+    AND (filter_posts.status = 'all'
+      OR (filter_posts.status = 'read' AND post_states.read_at IS NOT NULL)
+      OR (filter_posts.status = 'unread' AND post_states.read_at IS NULL))
+    AND (NOT filter_posts.starred OR post_states.starred_at IS NOT NULL)
+    AND (filter_posts.include_hidden OR post_states.hidden_at IS NULL)
+    AND (cardinality(filter_posts.tags) = 0 OR (
+      SELECT count(*)
+      FROM post_tags
+      JOIN tags ON tags.id = post_tags.tag_id
+      WHERE post_tags.post_id = posts.id
+        AND tags.user_id = filter_posts.user_id
+        AND tags.name = ANY(filter_posts.tags)
+    ) >= CASE WHEN filter_posts.match_all_tags THEN cardinality(filter_posts.tags) ELSE 1 END)
+    AND (filter_posts.published_after IS NULL OR posts.published_at >= filter_posts.published_after)
+    AND (filter_posts.published_before IS NULL OR posts.published_at < filter_posts.published_before)
+    AND (filter_posts.created_after IS NULL OR posts.created_at >= filter_posts.created_after)
+    AND (filter_posts.author = '' OR lower(posts.author) = lower(filter_posts.author))
+    AND (filter_posts.has_enclosure IS NULL OR (posts.enclosure_url IS NOT NULL) = filter_posts.has_enclosure)
+    -- A language matches itself and its regional variants, so en matches en-gb.
+    AND (filter_posts.language = '' OR posts.language = filter_posts.language OR posts.language LIKE filter_posts.language || '-%')
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP FUNCTION IF EXISTS filter_posts;
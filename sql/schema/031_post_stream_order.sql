@@ -0,0 +1,17 @@
+-- +goose Up
+-- Streams send posts in the order of the transactions that collected them,
+-- then of stream_seq within each transaction, and only once every older
+-- transaction has ended, so a post whose transaction commits late can't be
+-- skipped.
+ALTER TABLE posts
+ADD COLUMN created_xid BIGINT NOT NULL DEFAULT pg_current_xact_id()::text::bigint,
+ADD COLUMN stream_seq  BIGINT GENERATED ALWAYS AS IDENTITY;
+
+CREATE INDEX IF NOT EXISTS posts_stream_order_idx ON posts (created_xid, stream_seq);
+
+-- +goose Down
+DROP INDEX IF EXISTS posts_stream_order_idx;
+
+ALTER TABLE posts
+DROP COLUMN stream_seq,
+DROP COLUMN created_xid;
@@ -0,0 +1,60 @@
+-- +goose Up
+-- Live connections sync post states in the order of the transactions that
+-- changed them, then of change_seq within each transaction, and only once
+-- every older transaction has ended. Both are set by the database as each
+-- row changes, so unlike updated_at they don't depend on the clock of the
+-- instance making the change, and a change committed late can't be skipped.
+CREATE SEQUENCE IF NOT EXISTS post_states_change_seq;
+
+ALTER TABLE post_states
+ADD COLUMN changed_xid BIGINT NOT NULL DEFAULT pg_current_xact_id()::text::bigint,
+ADD COLUMN change_seq  BIGINT NOT NULL DEFAULT nextval('post_states_change_seq');
+
+ALTER SEQUENCE post_states_change_seq OWNED BY post_states.change_seq;
+
+CREATE INDEX IF NOT EXISTS post_states_user_id_change_idx ON post_states (user_id, changed_xid, change_seq);
+DROP INDEX IF EXISTS post_states_user_id_updated_at_idx;
+
+-- notify_post_state_changed now runs before each change, so it can mark the
+-- row with its place in the order changes are synced in.
+DROP TRIGGER IF EXISTS post_states_changed ON post_states;
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION notify_post_state_changed() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+  NEW.changed_xid := pg_current_xact_id()::text::bigint;
+  NEW.change_seq := nextval('post_states_change_seq');
+  PERFORM pg_notify('post_states', NEW.user_id::text);
+  RETURN NEW;
+END
+$$;
+-- +goose StatementEnd
+
+CREATE TRIGGER post_states_changed
+BEFORE INSERT OR UPDATE ON post_states
+FOR EACH ROW EXECUTE FUNCTION notify_post_state_changed();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS post_states_changed ON post_states;
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION notify_post_state_changed() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+  PERFORM pg_notify('post_states', NEW.user_id::text);
+  RETURN NULL;
+END
+$$;
+-- +goose StatementEnd
+
+CREATE TRIGGER post_states_changed
+AFTER INSERT OR UPDATE ON post_states
+FOR EACH ROW EXECUTE FUNCTION notify_post_state_changed();
+
+CREATE INDEX IF NOT EXISTS post_states_user_id_updated_at_idx ON post_states (user_id, updated_at, post_id);
+DROP INDEX IF EXISTS post_states_user_id_change_idx;
+
+ALTER TABLE post_states
+DROP COLUMN change_seq,
+DROP COLUMN changed_xid;
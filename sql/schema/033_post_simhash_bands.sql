@@ -0,0 +1,29 @@
+-- +goose Up
+-- simhash_bands splits a fingerprint into eight 8-bit bands, numbered so the
+-- same bits in different bands don't match. Fingerprints within
+-- simhash.NearDuplicateDistance (7) bits of each other differ in at most seven
+-- bands, so they always share one, and only posts sharing a band need their
+-- distance checked.
+-- +goose StatementBegin
+CREATE FUNCTION simhash_bands(simhash BIGINT) RETURNS INTEGER[]
+LANGUAGE sql IMMUTABLE STRICT AS $$
+  SELECT ARRAY(
+    SELECT band * 256 + ((simhash >> (band * 8)) & 255)::integer
+    FROM generate_series(0, 7) AS band
+  )
+$$;
+-- +goose StatementEnd
+
+-- Adding the column computes the bands of existing posts.
+ALTER TABLE posts
+ADD COLUMN simhash_bands INTEGER[] GENERATED ALWAYS AS (simhash_bands(simhash)) STORED;
+
+CREATE INDEX IF NOT EXISTS posts_simhash_bands_idx ON posts USING gin (simhash_bands);
+
+-- +goose Down
+DROP INDEX IF EXISTS posts_simhash_bands_idx;
+
+ALTER TABLE posts
+DROP COLUMN simhash_bands;
+
+DROP FUNCTION IF EXISTS simhash_bands;